@@ -21,6 +21,7 @@ import (
 
 var (
 	sanitize = flag.String("sanitize", "", "Dump cleaned up replay to this file (no chat, sane colors)")
+	anonym   = flag.Bool("anonymize", false, "Rename players, remove trigger chat and skins when sanitizing")
 	header   = flag.Bool("header", false, "Decode header only")
 	stream   = flag.Bool("stream", false, "Stream game to LAN")
 	jsonout  = flag.Bool("json", false, "Print machine readable format")
@@ -30,14 +31,28 @@ var logOut = log.New(os.Stdout, "", 0)
 var logErr = log.New(os.Stderr, "", 0)
 
 func print(v interface{}) {
-	var str = fmt.Sprintf("%+v", v)[1:]
-	if *jsonout {
-		if json, err := json.Marshal(v); err == nil {
-			str = string(json)
-		}
+	var name = reflect.TypeOf(v).String()[5:]
+	if !*jsonout {
+		logOut.Printf("%-14v %v\n", name, fmt.Sprintf("%+v", v)[1:])
+		return
+	}
+
+	// One w3g.RecordJSON document per line, headers use the same form
+	var b []byte
+	var err error
+	if r, ok := v.(w3g.Record); ok {
+		b, err = json.Marshal(w3g.RecordJSON{Record: r})
+	} else {
+		b, err = json.Marshal(&struct {
+			Type   string      `json:"type"`
+			Record interface{} `json:"record"`
+		}{name, v})
+	}
+	if err != nil {
+		logErr.Fatal("Marshal error: ", err)
 	}
 
-	logOut.Printf("%-14v %v\n", reflect.TypeOf(v).String()[5:], str)
+	logOut.Println(string(b))
 }
 
 func main() {
@@ -59,9 +74,13 @@ func main() {
 
 	// Find header, nwg files have their own header prepended
 	var b = bufio.NewReaderSize(f, 8192)
-	if _, err := w3g.FindHeader(b); err != nil {
+	nwg, err := w3g.FindNWGHeader(b)
+	if err != nil {
 		logErr.Fatal("Cannot find header: ", err)
 	}
+	if nwg != nil {
+		print(nwg)
+	}
 
 	hdr, data, _, err := w3g.DecodeHeader(b, w3g.NewFactoryCache(w3g.DefaultFactory))
 	if err != nil {
@@ -83,35 +102,24 @@ func main() {
 		enc.Header = *hdr
 	}
 
-	var skip = false
-	var maxp uint8 = 24
-	if hdr.GameVersion.Version < 29 {
-		maxp = 12
+	var san = w3g.Sanitizer{
+		SanitizeRules: w3g.SanitizeRules{
+			RemoveChat:        true,
+			RemoveTriggerChat: *anonym,
+			RemoveSkins:       *anonym,
+			RenamePlayers:     *anonym,
+			FixColors:         true,
+		},
+		Encoding: hdr.Encoding(),
 	}
 
+	var skip = false
+
 	print(hdr)
 	if err := data.ForEach(func(r w3g.Record) error {
-		if enc != nil {
-			var write = true
-
-			switch v := r.(type) {
-			case *w3g.ChatMessage:
-				write = false
-			case *w3g.SlotInfo:
-				var c = uint8(0)
-				for i := range v.Slots {
-					if v.Slots[i].Team >= maxp {
-						continue
-					}
-					v.Slots[i].Color = c
-					c++
-				}
-			}
-
-			if write {
-				if _, err := enc.WriteRecord(r); err != nil {
-					return err
-				}
+		if enc != nil && san.Sanitize(r) {
+			if _, err := enc.WriteRecord(r); err != nil {
+				return err
 			}
 		}
 		if !skip && *header {
@@ -137,5 +145,8 @@ func main() {
 		if err := enc.Close(); err != nil {
 			logErr.Fatal("Save error: ", err)
 		}
+		if san.Unchecked > 0 {
+			logErr.Printf("Sanitize warning: %d action blocks with unknown actions were kept as is and may contain trigger chat\n", san.Unchecked)
+		}
 	}
 }
@@ -12,7 +12,6 @@ import (
 	"net"
 	"strconv"
 	"strings"
-	"sync/atomic"
 	"time"
 
 	"github.com/nielsAD/gowarcraft3/file/fs"
@@ -20,6 +19,7 @@ import (
 	"github.com/nielsAD/gowarcraft3/file/w3g"
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/network/playback"
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
@@ -45,13 +45,6 @@ func mapCRC(name string) (uint32, uint32) {
 	return 1, 1
 }
 
-func speedString(s int64) string {
-	if s < 0 {
-		return fmt.Sprintf("1/%dx", -s+1)
-	}
-	return fmt.Sprintf("%dx", s+1)
-}
-
 func cast(name string) error {
 	replay, err := w3g.Open(name)
 	if err != nil {
@@ -211,8 +204,8 @@ func cast(name string) error {
 		break
 	}
 
-	var msec int64
-	var speed int64
+	var pb = playback.NewPlayback(replay, hostID)
+	pb.AddSink(conn)
 
 	var say = func(s string) {
 		if _, err := conn.Send(&w3gs.MessageRelay{Message: w3gs.Message{
@@ -230,6 +223,7 @@ func cast(name string) error {
 	events.On(&w3gs.Leave{}, func(_ *network.Event) {
 		conn.Send(&w3gs.LeaveAck{})
 		conn.Close()
+		pb.Close()
 	})
 	events.On(&w3gs.Message{}, func(ev *network.Event) {
 		var msg = ev.Arg.(*w3gs.Message)
@@ -240,25 +234,40 @@ func cast(name string) error {
 		var cmd = strings.Fields(msg.Content)
 		switch strings.ToLower(cmd[0]) {
 		case ".time":
-			var t = (time.Duration)(atomic.LoadInt64(&msec)) * time.Millisecond
-			say("Time: " + t.String())
+			say("Time: " + pb.Time().String())
 		case ".speed":
-			var s = atomic.LoadInt64(&speed)
-
 			if len(cmd) > 1 {
+				var s float64
 				if strings.HasPrefix(cmd[1], "1/") {
-					if i, err := strconv.ParseInt(cmd[1][2:], 0, 64); err == nil {
-						s = -(i - 1)
-					}
-				} else {
-					if i, err := strconv.ParseInt(cmd[1], 0, 64); err == nil {
-						s = i - 1
+					if f, err := strconv.ParseFloat(cmd[1][2:], 64); err == nil {
+						s = 1 / f
 					}
+				} else if f, err := strconv.ParseFloat(strings.TrimSuffix(cmd[1], "x"), 64); err == nil {
+					s = f
+				}
+				if err := pb.SetSpeed(s); err != nil {
+					say("Invalid speed: " + cmd[1])
 				}
-				atomic.StoreInt64(&speed, s)
 			}
-
-			say("Replay speed: " + speedString(s))
+			say(fmt.Sprintf("Replay speed: %gx", pb.Speed()))
+		case ".pause":
+			pb.Pause()
+			say("Replay paused")
+		case ".resume":
+			pb.Resume()
+			say("Replay resumed")
+		case ".seek":
+			if len(cmd) < 2 {
+				say("Usage: .seek <time>")
+				return
+			}
+			t, err := time.ParseDuration(cmd[1])
+			if err != nil || t < pb.Time() {
+				say("Cannot seek to " + cmd[1])
+				return
+			}
+			pb.Seek(t)
+			say("Seeking to " + t.String())
 		}
 	})
 
@@ -268,6 +277,7 @@ func cast(name string) error {
 			logErr.Println("Connection error: ", err)
 			conn.Close()
 		}
+		pb.Close()
 	}()
 
 	if _, err := conn.Send(&w3gs.PlayerLoaded{
@@ -276,39 +286,17 @@ func cast(name string) error {
 		return err
 	}
 
-	for _, rec := range replay.Records {
-		var pkt w3gs.Packet
-		switch v := rec.(type) {
-		case *w3g.PlayerLeft:
-			if v.PlayerID == hostID {
-				continue
-			}
-			pkt = &w3gs.PlayerLeft{
-				PlayerID: v.PlayerID,
-				Reason:   v.Reason,
-			}
-		case *w3g.TimeSlot:
-			var s = atomic.LoadInt64(&speed)
-			if s >= 0 {
-				time.Sleep(time.Duration(v.TimeIncrementMS) * time.Millisecond / (time.Duration)(s+1))
-			} else {
-				time.Sleep(time.Duration(v.TimeIncrementMS) * time.Millisecond * (time.Duration)(-s+1))
-			}
-			atomic.AddInt64(&msec, int64(v.TimeIncrementMS))
-
-			pkt = &v.TimeSlot
-		case *w3g.Desync:
-			pkt = &v.Desync
-		case *w3g.ChatMessage:
-			pkt = &w3gs.MessageRelay{Message: v.Message}
-		default:
-			continue
+	var sendErr error
+	pb.On(&playback.SinkError{}, func(ev *network.Event) {
+		if err := ev.Arg.(*playback.SinkError).Err; !network.IsCloseError(err) {
+			sendErr = err
 		}
+		pb.Close()
+	})
 
-		if _, err := conn.Send(pkt); err != nil {
-			return err
-		}
+	if err := pb.Run(); err != nil {
+		return err
 	}
 
-	return nil
+	return sendErr
 }
@@ -0,0 +1,133 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// w3gedit is a tool that cuts, trims, repairs and re-encodes w3g files.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+var (
+	from = flag.Duration("from", 0, "Fast-forward until this game time")
+	to   = flag.Duration("to", 0, "End replay at this game time")
+	trim = flag.Bool("trim", false, "Trim end after the second-to-last player left")
+	fix  = flag.Bool("repair", false, "Salvage records from corrupted input")
+)
+
+var logErr = log.New(os.Stderr, "", 0)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 2 {
+		logErr.Fatal("Usage: w3gedit [options] input output")
+	}
+
+	var rep *w3g.Replay
+	var nwg *w3g.NWGHeader
+	var err error
+	switch {
+	case *fix:
+		rep, err = repair(flag.Arg(0))
+	case hasExt(flag.Arg(0), ".json"):
+		rep, err = openJSON(flag.Arg(0))
+	case hasExt(flag.Arg(0), ".yaml"), hasExt(flag.Arg(0), ".yml"):
+		rep, err = openYAML(flag.Arg(0))
+	default:
+		rep, nwg, err = w3g.OpenNWG(flag.Arg(0))
+	}
+	if err != nil {
+		logErr.Fatal("Open error: ", err)
+	}
+
+	if *trim {
+		rep = rep.TrimEnd()
+	}
+
+	if *from > 0 || *to > 0 {
+		var end = uint32(math.MaxUint32)
+		if *to > 0 {
+			end = uint32(*to / time.Millisecond)
+		}
+		rep = rep.Cut(uint32(*from/time.Millisecond), end)
+	}
+
+	switch {
+	case hasExt(flag.Arg(1), ".json"):
+		err = saveJSON(rep, flag.Arg(1))
+	case hasExt(flag.Arg(1), ".yaml"), hasExt(flag.Arg(1), ".yml"):
+		err = saveYAML(rep, flag.Arg(1))
+	case hasExt(flag.Arg(1), ".nwg"):
+		err = rep.SaveNWG(flag.Arg(1), nwg)
+	default:
+		err = rep.Save(flag.Arg(1))
+	}
+	if err != nil {
+		logErr.Fatal("Save error: ", err)
+	}
+}
+
+func hasExt(name string, ext string) bool {
+	return strings.EqualFold(filepath.Ext(name), ext)
+}
+
+func openJSON(name string) (*w3g.Replay, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var rep w3g.Replay
+	if err := json.Unmarshal(b, &rep); err != nil {
+		return nil, err
+	}
+
+	return &rep, nil
+}
+
+func saveJSON(rep *w3g.Replay, name string) error {
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, append(b, '\n'), 0644)
+}
+
+func repair(name string) (*w3g.Replay, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b = bufio.NewReaderSize(f, 8192)
+	if _, err := w3g.FindHeader(b); err != nil {
+		return nil, w3g.ErrBadFormat
+	}
+
+	var buf bytes.Buffer
+	rep, err := w3g.Repair(b, &buf)
+	if rep != nil {
+		for _, p := range rep.Problems {
+			logErr.Println("Repair:", p)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return w3g.Decode(&buf)
+}
@@ -0,0 +1,87 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+// YAML documents follow the JSON schema of w3g.Replay, they are converted to and from JSON
+
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+func marshalYAML(rep *w3g.Replay) ([]byte, error) {
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, decode into a node tree to keep the key order
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	blockStyle(&doc)
+
+	var buf bytes.Buffer
+	var enc = yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func unmarshalYAML(b []byte) (*w3g.Replay, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var rep w3g.Replay
+	if err := json.Unmarshal(j, &rep); err != nil {
+		return nil, err
+	}
+
+	return &rep, nil
+}
+
+func openYAML(name string) (*w3g.Replay, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalYAML(b)
+}
+
+func saveYAML(rep *w3g.Replay, name string) error {
+	b, err := marshalYAML(rep)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, b, 0644)
+}
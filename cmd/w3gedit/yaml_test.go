@@ -0,0 +1,57 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestYAML(t *testing.T) {
+	for _, file := range []string{"test_102.w3g", "test_130.w3g", "test_132.w3g"} {
+		rep, err := w3g.Open("../../file/w3g/" + file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		// Strings that would resolve to another type if left unquoted
+		for _, s := range []string{"yes", "123", "null", "0x10", "- gl", ""} {
+			rep.Records = append(rep.Records, &w3g.ChatMessage{Message: w3gs.Message{SenderID: rep.HostPlayer.ID, Type: w3gs.MsgChat, Content: s}})
+		}
+
+		b, err := marshalYAML(rep)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if !strings.HasPrefix(string(b), "schema: 1\n") {
+			t.Fatal(file, "Expected block style YAML")
+		}
+
+		res, err := unmarshalYAML(b)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var buf1 bytes.Buffer
+		var buf2 bytes.Buffer
+		if err := rep.Encode(&buf1); err != nil {
+			t.Fatal(file, err)
+		}
+		if err := res.Encode(&buf2); err != nil {
+			t.Fatal(file, err)
+		}
+		if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+			t.Fatal(file, "Encoded replay mismatch")
+		}
+	}
+
+	if _, err := unmarshalYAML([]byte("schema: 0\n")); err != w3g.ErrBadFormat {
+		t.Fatal("ErrBadFormat expected, got", err)
+	}
+}
@@ -0,0 +1,221 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+var errSetupDone = errors.New("w3gindex: Setup done")
+
+// IndexFile decodes the replay metadata in name, winner and chat are only extracted if full is set
+func IndexFile(name string, full bool) (*Entry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var b = bufio.NewReaderSize(f, 8192)
+	if _, err := w3g.FindHeader(b); err != nil {
+		return nil, w3g.ErrBadFormat
+	}
+
+	hdr, data, _, err := w3g.DecodeHeader(b, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var version = hdr.GameVersion.Version
+	if version >= 10000 {
+		version -= 10000
+	}
+
+	var e = Entry{
+		Path:       name,
+		Size:       stat.Size(),
+		ModTime:    stat.ModTime(),
+		Full:       full,
+		Version:    fmt.Sprintf("%s 1.%02d", hdr.GameVersion.Product, version),
+		Build:      hdr.BuildNumber,
+		DurationMS: hdr.DurationMS,
+	}
+
+	var slots []w3gs.SlotData
+	var leaves []*w3g.PlayerLeft
+	var time uint32
+
+	err = data.ForEach(func(r w3g.Record) error {
+		switch v := r.(type) {
+		case *w3g.GameInfo:
+			e.GameName = v.GameName
+			e.Host = v.HostPlayer.Name
+			e.Map = v.GameSettings.MapPath
+			e.Players = append(e.Players, Player{ID: v.HostPlayer.ID, Name: v.HostPlayer.Name})
+		case *w3g.PlayerInfo:
+			e.Players = append(e.Players, Player{ID: v.ID, Name: v.Name})
+		case *w3g.SlotInfo:
+			slots = v.Slots
+		case *w3g.CountDownStart, *w3g.GameStart:
+			if !full {
+				return errSetupDone
+			}
+		case *w3g.TimeSlot:
+			time += uint32(v.TimeIncrementMS)
+		case *w3g.ChatMessage:
+			if v.Type != w3gs.MsgChatExtra {
+				break
+			}
+			var c = Chat{TimeMS: time, Message: v.Content}
+			if p := e.player(v.SenderID); p != nil {
+				c.Player = p.Name
+			}
+			e.Chat = append(e.Chat, c)
+		case *w3g.PlayerLeft:
+			leaves = append(leaves, v)
+		}
+		return nil
+	})
+	if err != nil && err != errSetupDone {
+		return nil, err
+	}
+
+	var observer uint8 = 24
+	if version > 0 && version < 29 {
+		observer = 12
+	}
+
+	var observers = map[uint8]bool{}
+	for _, s := range slots {
+		if s.SlotStatus != w3gs.SlotOccupied || s.Computer {
+			continue
+		}
+		var p = e.player(s.PlayerID)
+		if p == nil {
+			continue
+		}
+		p.Race = (s.Race & w3gs.RaceMask).String()
+		p.Team = s.Team
+		observers[p.ID] = s.Team >= observer
+	}
+
+	for _, id := range winners(leaves) {
+		if p := e.player(id); p != nil && !observers[id] {
+			p.Winner = true
+		}
+	}
+
+	return &e, nil
+}
+
+// winners returns the IDs of players that won according to their leave records
+//
+// A local leave record describes the result of the replay saver (the player of the last
+// leave record) rather than the player that left, see LeaveGame in w3g_format.txt.
+func winners(leaves []*w3g.PlayerLeft) []uint8 {
+	if len(leaves) == 0 {
+		return nil
+	}
+
+	var saver = leaves[len(leaves)-1].PlayerID
+	var res []uint8
+	for _, l := range leaves {
+		if l.Reason != w3gs.LeaveWon {
+			continue
+		}
+		if l.Local {
+			res = append(res, saver)
+		} else {
+			res = append(res, l.PlayerID)
+		}
+	}
+
+	return res
+}
+
+// IndexDirs adds all replays (.w3g and .nwg) in dirs to s using workers goroutines,
+// files that did not change since they were last indexed are skipped.
+func IndexDirs(s *Store, dirs []string, full bool, workers int, onError func(path string, err error)) error {
+	var paths = make(chan string)
+	var mut sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				e, err := IndexFile(path, full)
+
+				mut.Lock()
+				if err != nil {
+					onError(path, err)
+				} else {
+					s.Entries[path] = e
+				}
+				mut.Unlock()
+			}
+		}()
+	}
+
+	var err error
+	for _, dir := range dirs {
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".w3g", ".nwg":
+			default:
+				return nil
+			}
+
+			path, err = filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			mut.Lock()
+			var old = s.Entries[path]
+			mut.Unlock()
+			if old != nil && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) && (old.Full || !full) {
+				return nil
+			}
+
+			paths <- path
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	close(paths)
+	wg.Wait()
+
+	return err
+}
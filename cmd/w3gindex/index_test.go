@@ -0,0 +1,58 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"testing"
+)
+
+func TestIndexFile(t *testing.T) {
+	var files = []struct {
+		file      string
+		observers int
+		winners   []string
+	}{
+		{"test_102.w3g", 10, []string{"IN.DoomGuard"}},
+		{"test_126.w3g", 0, nil},
+		{"test_132.w3g", 1, nil},
+	}
+
+	for _, f := range files {
+		e, err := IndexFile("../../file/w3g/"+f.file, true)
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+
+		var obs = 0
+		var win []string
+		for _, p := range e.Players {
+			if p.Team == 12 || p.Team == 24 {
+				obs++
+				if p.Winner {
+					t.Fatal(f.file, "Observer marked as winner", p)
+				}
+			}
+			if p.Winner {
+				win = append(win, p.Name)
+			}
+		}
+		if obs != f.observers {
+			t.Fatalf("%v: expected %d observers, got %d", f.file, f.observers, obs)
+		}
+		if len(win) != len(f.winners) || (len(win) > 0 && win[0] != f.winners[0]) {
+			t.Fatalf("%v: expected winners %v, got %v", f.file, f.winners, win)
+		}
+	}
+
+	e, err := IndexFile("../../file/w3g/test_102.w3g", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range e.Players {
+		if p.Winner {
+			t.Fatal("Winner set without full decode", p)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// w3gindex is a tool that indexes directories of w3g files and queries the index.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var (
+	db      = flag.String("db", "w3gindex.db", "Index file")
+	full    = flag.Bool("full", false, "Decode entire replays to extract winner and chat (slow)")
+	prune   = flag.Bool("prune", false, "Remove entries of files that no longer exist")
+	workers = flag.Int("workers", runtime.GOMAXPROCS(0), "Number of files to index concurrently")
+
+	player = flag.String("player", "", "Only list games with this player")
+	mapp   = flag.String("map", "", "Only list games on maps matching this substring")
+	winner = flag.String("winner", "", "Only list games won by this player (requires -full)")
+	chat   = flag.String("chat", "", "Only list games with chat matching this substring (requires -full)")
+	minDur = flag.Duration("min", 0, "Only list games longer than this")
+	maxDur = flag.Duration("max", 0, "Only list games shorter than this")
+	jsonf  = flag.Bool("json", false, "Print machine readable format")
+)
+
+var logOut = log.New(os.Stdout, "", 0)
+var logErr = log.New(os.Stderr, "", 0)
+
+func main() {
+	flag.Parse()
+
+	if *workers < 1 {
+		*workers = 1
+	}
+
+	s, err := LoadStore(*db)
+	if err != nil {
+		logErr.Fatal("Load error: ", err)
+	}
+
+	var save = *prune
+	if *prune {
+		logErr.Printf("Pruned %d entries\n", s.Prune())
+	}
+
+	if flag.NArg() > 0 {
+		save = true
+		if err := IndexDirs(s, flag.Args(), *full, *workers, func(path string, err error) {
+			logErr.Printf("Index error %s: %v\n", path, err)
+		}); err != nil {
+			logErr.Fatal("Index error: ", err)
+		}
+	}
+
+	if save {
+		if err := s.Save(*db); err != nil {
+			logErr.Fatal("Save error: ", err)
+		}
+		if !query() {
+			logErr.Printf("Indexed %d replays\n", len(s.Entries))
+			return
+		}
+	}
+
+	for _, e := range s.Sorted() {
+		if !match(e) {
+			continue
+		}
+		if *jsonf {
+			b, err := json.Marshal(e)
+			if err != nil {
+				logErr.Fatal("Marshal error: ", err)
+			}
+			logOut.Println(string(b))
+		} else {
+			printEntry(e)
+		}
+	}
+}
+
+// query returns true if any query flag is set
+func query() bool {
+	return *player != "" || *mapp != "" || *winner != "" || *chat != "" || *minDur > 0 || *maxDur > 0
+}
+
+func contains(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func match(e *Entry) bool {
+	var d = time.Duration(e.DurationMS) * time.Millisecond
+	if *minDur > 0 && d < *minDur {
+		return false
+	}
+	if *maxDur > 0 && d > *maxDur {
+		return false
+	}
+	if *mapp != "" && !contains(e.Map, *mapp) {
+		return false
+	}
+
+	if *player != "" || *winner != "" {
+		var hasPlayer = *player == ""
+		var hasWinner = *winner == ""
+		for _, p := range e.Players {
+			hasPlayer = hasPlayer || strings.EqualFold(p.Name, *player)
+			hasWinner = hasWinner || (p.Winner && strings.EqualFold(p.Name, *winner))
+		}
+		if !hasPlayer || !hasWinner {
+			return false
+		}
+	}
+
+	if *chat != "" {
+		var hasChat = false
+		for _, c := range e.Chat {
+			if contains(c.Message, *chat) {
+				hasChat = true
+				break
+			}
+		}
+		if !hasChat {
+			return false
+		}
+	}
+
+	return true
+}
+
+func printEntry(e *Entry) {
+	var players = make([]string, len(e.Players))
+	for i, p := range e.Players {
+		var s = p.Name
+		if p.Race != "" {
+			s += fmt.Sprintf(" (%s)", p.Race)
+		}
+		if p.Winner {
+			s += " [W]"
+		}
+		players[i] = s
+	}
+
+	var d = time.Duration(e.DurationMS) * time.Millisecond
+	logOut.Printf("%s\n  %-9v %-10v %s\n  %s\n", e.Path, e.Version, d.Round(time.Second), e.Map, strings.Join(players, ", "))
+}
@@ -0,0 +1,139 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Player in an indexed replay
+type Player struct {
+	ID     uint8  `json:"id"`
+	Name   string `json:"name"`
+	Race   string `json:"race,omitempty"`
+	Team   uint8  `json:"team"`
+	Winner bool   `json:"winner,omitempty"`
+}
+
+// Chat message in an indexed replay
+type Chat struct {
+	TimeMS  uint32 `json:"time"`
+	Player  string `json:"player"`
+	Message string `json:"message"`
+}
+
+// Entry in the index, keyed by Path
+type Entry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modtime"`
+
+	// Full is set if the entire replay was decoded (Winner and Chat are only available if set)
+	Full bool `json:"full,omitempty"`
+
+	Version    string   `json:"version"`
+	Build      uint16   `json:"build"`
+	DurationMS uint32   `json:"duration"`
+	GameName   string   `json:"game"`
+	Host       string   `json:"host"`
+	Map        string   `json:"map"`
+	Players    []Player `json:"players"`
+	Chat       []Chat   `json:"chat,omitempty"`
+}
+
+// player returns the player with id, or nil if not found
+func (e *Entry) player(id uint8) *Player {
+	for i := range e.Players {
+		if e.Players[i].ID == id {
+			return &e.Players[i]
+		}
+	}
+	return nil
+}
+
+// Store is an on-disk index, encoded as one JSON entry per line
+type Store struct {
+	Entries map[string]*Entry
+}
+
+// LoadStore from file, returns an empty store if the file does not exist
+func LoadStore(name string) (*Store, error) {
+	var s = Store{Entries: map[string]*Entry{}}
+
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return &s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var dec = json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			return nil, err
+		}
+		s.Entries[e.Path] = &e
+	}
+
+	return &s, nil
+}
+
+// Save store to file, replacing it atomically
+func (s *Store) Save(name string) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	var w = bufio.NewWriter(f)
+	var enc = json.NewEncoder(w)
+	for _, e := range s.Sorted() {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// Sorted returns all entries ordered by path
+func (s *Store) Sorted() []*Entry {
+	var res = make([]*Entry, 0, len(s.Entries))
+	for _, e := range s.Entries {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res
+}
+
+// Prune removes entries of files that no longer exist
+func (s *Store) Prune() int {
+	var n = 0
+	for path := range s.Entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(s.Entries, path)
+			n++
+		}
+	}
+	return n
+}
@@ -22,6 +22,7 @@ import (
 var (
 	binpath = flag.String("b", dir.InstallDir(), "Path to game binaries")
 	preview = flag.String("preview", "", "Dump preview image to this file")
+	imports = flag.String("imports", "", "Extract imported files to this directory")
 	jsonout = flag.Bool("json", false, "Print machine readable format")
 )
 
@@ -50,12 +51,21 @@ func main() {
 		logErr.Fatal("Checksum error: ", err)
 	}
 
+	imp, err := m.Imports()
+	if err == os.ErrNotExist {
+		imp = &w3m.Imports{}
+	} else if err != nil {
+		logErr.Fatal("Imports error: ", err)
+	}
+
 	var print = struct {
 		Info     w3m.Info
 		Checksum w3m.Hash
+		Imports  []w3m.Import
 	}{
 		*info,
 		*hash,
+		imp.Imports,
 	}
 
 	var str = fmt.Sprintf("%+v", print)
@@ -67,11 +77,24 @@ func main() {
 
 	logOut.Println(str)
 
+	if *imports != "" {
+		_, missing, err := m.ExtractImports(*imports)
+		for _, p := range missing {
+			logErr.Println("ExtractImports: missing from archive:", p)
+		}
+		if err != nil {
+			logErr.Fatal("ExtractImports error: ", err)
+		}
+	}
+
 	if *preview != "" {
 		img, err := m.Preview()
 		if err == os.ErrNotExist {
 			img, err = m.MenuMinimap()
 		}
+		if err == os.ErrNotExist {
+			img, err = m.RenderMinimap()
+		}
 		if err != nil {
 			logErr.Fatal("Preview error: ", err)
 		}
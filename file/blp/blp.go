@@ -2,12 +2,13 @@
 // Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
 // License: Mozilla Public License, v2.0
 
-// Package blp is a BLIzzard Picture image format decoder.
+// Package blp is a BLIzzard Picture image format decoder and encoder.
 package blp
 
 import (
 	"errors"
 	"image"
+	"image/color"
 	"image/draw"
 	"image/jpeg"
 	"io"
@@ -25,94 +26,365 @@ var (
 // Header constant for BLP files
 var Header = protocol.DString("BLP1")
 
-// Decode a BLP image. Only take the first image if it's a mipmap.
-func Decode(r io.Reader) (image.Image, error) {
-	var b protocol.Buffer
-	if _, err := io.Copy(&b, r); err != nil {
-		return nil, err
+// Header2 constant for BLP2 files
+var Header2 = protocol.DString("BLP2")
+
+func init() {
+	image.RegisterFormat("blp", Header.String(), Decode, DecodeConfig)
+	image.RegisterFormat("blp", Header2.String(), Decode, DecodeConfig)
+}
+
+// Compression enum
+type Compression uint32
+
+// Compression types
+const (
+	CompressionJPEG    Compression = 0x00
+	CompressionPalette Compression = 0x01
+	CompressionDXT     Compression = 0x02
+	CompressionARGB    Compression = 0x03
+)
+
+// DXT alpha encoding (BLP2)
+const (
+	alphaTypeDXT1 = 0x00
+	alphaTypeDXT3 = 0x01
+	alphaTypeDXT5 = 0x07
+)
+
+const maxMipmaps = 16
+
+type header struct {
+	Magic       protocol.DWordString
+	Compression Compression
+	AlphaBits   uint32
+	AlphaType   uint8
+	Mipmaps     bool
+	Width       uint32
+	Height      uint32
+	MMOffset    [maxMipmaps]uint32
+	MMSize      [maxMipmaps]uint32
+	JPEGHeader  []byte
+	Palette     []color.NRGBA
+}
+
+func readPalette(b *protocol.Buffer) []color.NRGBA {
+	var pal = make([]color.NRGBA, 256)
+	for i := range pal {
+		pal[i] = color.NRGBA{
+			B: b.ReadUInt8(),
+			G: b.ReadUInt8(),
+			R: b.ReadUInt8(),
+			A: b.ReadUInt8(),
+		}
 	}
+	return pal
+}
 
-	var size = b.Size()
-	if size < 156 {
+func decodeInfo(b *protocol.Buffer) (*header, error) {
+	if b.Size() < 20 {
 		return nil, ErrBadFormat
 	}
 
-	if b.ReadLEDString() != Header {
-		return nil, ErrBadFormat
+	var h = header{
+		Magic: b.ReadLEDString(),
 	}
 
-	var compression = b.ReadUInt32() //compression
-	var alphaBits = b.ReadUInt32()   //alpha
+	switch h.Magic {
+	case Header:
+		h.Compression = Compression(b.ReadUInt32())
+		h.AlphaBits = b.ReadUInt32()
+
+		switch h.Compression {
+		case CompressionJPEG, CompressionPalette:
+		default:
+			return nil, ErrInvalidCompression
+		}
+
+	case Header2:
+		if b.ReadUInt32() != 1 {
+			// JPEG encoded BLP2 files do not exist in the wild
+			return nil, ErrInvalidCompression
+		}
+		h.Compression = Compression(b.ReadUInt8())
+		h.AlphaBits = uint32(b.ReadUInt8())
+		h.AlphaType = b.ReadUInt8()
+		h.Mipmaps = b.ReadUInt8() != 0
+
+		switch h.Compression {
+		case CompressionPalette, CompressionARGB:
+		case CompressionDXT:
+			switch h.AlphaType {
+			case alphaTypeDXT1, alphaTypeDXT3, alphaTypeDXT5:
+			default:
+				return nil, ErrInvalidCompression
+			}
+		default:
+			return nil, ErrInvalidCompression
+		}
 
-	switch alphaBits {
-	case 0, 8:
 	default:
 		return nil, ErrBadFormat
 	}
 
-	b.ReadUInt32() //width
-	b.ReadUInt32() //height
-	b.ReadUInt32() //flags
-	b.ReadUInt32() //hasMipmap
+	switch h.AlphaBits {
+	case 0, 1, 4, 8:
+	default:
+		return nil, ErrBadFormat
+	}
 
-	var mmOffset [16]uint32
-	for i := 0; i < len(mmOffset); i++ {
-		mmOffset[i] = b.ReadUInt32()
+	h.Width = b.ReadUInt32()
+	h.Height = b.ReadUInt32()
+	if h.Width == 0 || h.Height == 0 || h.Width > 65535 || h.Height > 65535 {
+		return nil, ErrBadFormat
 	}
 
-	var mmSize [16]uint32
-	for i := 0; i < len(mmOffset); i++ {
-		mmSize[i] = b.ReadUInt32()
+	return &h, nil
+}
+
+func decodeHeader(b *protocol.Buffer) (*header, error) {
+	h, err := decodeInfo(b)
+	if err != nil {
+		return nil, err
 	}
 
-	switch compression {
-	// JPEG
-	case 0x00:
-		var hSize = b.ReadUInt32()
-		if b.Size() < int(hSize) || mmOffset[0] == 0 || mmSize[0] == 0 {
+	if h.Magic == Header {
+		if b.Size() < 8 {
 			return nil, ErrBadFormat
 		}
+		b.ReadUInt32() //flags
+		h.Mipmaps = b.ReadUInt32() != 0
+	}
+
+	if b.Size() < 128 {
+		return nil, ErrBadFormat
+	}
 
-		var imgBuf = make([]byte, 0, hSize+mmSize[0])
-		imgBuf = append(imgBuf, b.ReadBlob(int(hSize))...)
+	for i := 0; i < len(h.MMOffset); i++ {
+		h.MMOffset[i] = b.ReadUInt32()
+	}
+	for i := 0; i < len(h.MMSize); i++ {
+		h.MMSize[i] = b.ReadUInt32()
+	}
 
-		var offset = int(mmOffset[0]) - size + b.Size()
-		if offset < 0 || offset+int(mmSize[0]) > b.Size() {
+	if h.Magic == Header && h.Compression == CompressionJPEG {
+		if b.Size() < 4 {
 			return nil, ErrBadFormat
 		}
+		var hSize = b.ReadUInt32()
+		if b.Size() < int(hSize) {
+			return nil, ErrBadFormat
+		}
+		h.JPEGHeader = b.ReadBlob(int(hSize))
+	} else {
+		// BLP2 always stores a palette, regardless of compression
+		if b.Size() < 1024 {
+			return nil, ErrBadFormat
+		}
+		h.Palette = readPalette(b)
+	}
+
+	return h, nil
+}
 
-		b.Skip(offset)
-		imgBuf = append(imgBuf, b.ReadBlob(int(mmSize[0]))...)
+func (h *header) numMipmaps() int {
+	if !h.Mipmaps {
+		return 1
+	}
 
-		jpg, err := jpeg.Decode(&protocol.Buffer{Bytes: imgBuf})
+	var n = 0
+	for n < maxMipmaps && h.MMOffset[n] != 0 && h.MMSize[n] != 0 && (h.Width|h.Height)>>uint(n) != 0 {
+		n++
+	}
+	return n
+}
 
-		// Workaround for CMYK image without APP14 marker
-		if err != nil && strings.Contains(err.Error(), "Adobe APP14") {
-			imgBuf = append([]byte{
-				0xFF, 0xD8, //SOIMAGE
-				0xFF, 0xEE, 0x00, 0x0E, //App14Marker
-				'A', 'd', 'o', 'b', 'e',
-				0, 0, 0, 0, 0, 0, 0,
-			}, imgBuf[2:]...)
-			jpg, err = jpeg.Decode(&protocol.Buffer{Bytes: imgBuf})
-		}
+func (h *header) mipmapBounds(level int) image.Rectangle {
+	var w = int(h.Width >> uint(level))
+	var ht = int(h.Height >> uint(level))
+	if w < 1 {
+		w = 1
+	}
+	if ht < 1 {
+		ht = 1
+	}
+	return image.Rect(0, 0, w, ht)
+}
 
-		if err != nil {
-			return nil, err
+func (h *header) decodeMipmap(raw []byte, level int) (image.Image, error) {
+	var off = int(h.MMOffset[level])
+	var size = int(h.MMSize[level])
+	if off == 0 || size == 0 || off+size > len(raw) {
+		return nil, ErrBadFormat
+	}
+
+	var data = raw[off : off+size]
+	var rect = h.mipmapBounds(level)
+
+	switch h.Compression {
+	case CompressionJPEG:
+		return decodeJPEG(h.JPEGHeader, data)
+	case CompressionPalette:
+		return decodePalette(rect, data, h.Palette, h.AlphaBits)
+	case CompressionARGB:
+		return decodeARGB(rect, data, h.AlphaBits)
+	case CompressionDXT:
+		return decodeDXT(rect, data, h.AlphaType, h.AlphaBits)
+	default:
+		return nil, ErrInvalidCompression
+	}
+}
+
+func decodeJPEG(hdr []byte, data []byte) (image.Image, error) {
+	var imgBuf = make([]byte, 0, len(hdr)+len(data))
+	imgBuf = append(imgBuf, hdr...)
+	imgBuf = append(imgBuf, data...)
+
+	jpg, err := jpeg.Decode(&protocol.Buffer{Bytes: imgBuf})
+
+	// Workaround for CMYK image without APP14 marker
+	if err != nil && strings.Contains(err.Error(), "Adobe APP14") {
+		imgBuf = append([]byte{
+			0xFF, 0xD8, //SOIMAGE
+			0xFF, 0xEE, 0x00, 0x0E, //App14Marker
+			'A', 'd', 'o', 'b', 'e',
+			0, 0, 0, 0, 0, 0, 0,
+		}, imgBuf[2:]...)
+		jpg, err = jpeg.Decode(&protocol.Buffer{Bytes: imgBuf})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// BGR to RGB
+	var img = image.NewRGBA(jpg.Bounds())
+	draw.Draw(img, img.Rect, jpg, img.Rect.Min, draw.Src)
+
+	for i, m := 0, img.Rect.Dx()*img.Rect.Dy(); i < m; i++ {
+		img.Pix[i*4+0], img.Pix[i*4+2] = img.Pix[i*4+2], img.Pix[i*4+0]
+	}
+
+	return img, nil
+}
+
+func alphaValue(alpha []byte, alphaBits uint32, i int) uint8 {
+	switch alphaBits {
+	case 1:
+		if alpha[i/8]&(1<<uint(i%8)) != 0 {
+			return 0xFF
 		}
+		return 0x00
+	case 4:
+		var v = (alpha[i/2] >> (uint(i%2) * 4)) & 0x0F
+		return v<<4 | v
+	case 8:
+		return alpha[i]
+	default:
+		return 0xFF
+	}
+}
+
+func decodePalette(rect image.Rectangle, data []byte, pal []color.NRGBA, alphaBits uint32) (image.Image, error) {
+	var num = rect.Dx() * rect.Dy()
+	if len(data) < num+(num*int(alphaBits)+7)/8 {
+		return nil, ErrBadFormat
+	}
+
+	var img = image.NewNRGBA(rect)
+	var alpha = data[num:]
+
+	for i := 0; i < num; i++ {
+		var c = pal[data[i]]
+		img.Pix[i*4+0] = c.R
+		img.Pix[i*4+1] = c.G
+		img.Pix[i*4+2] = c.B
+		img.Pix[i*4+3] = alphaValue(alpha, alphaBits, i)
+	}
+
+	return img, nil
+}
 
-		// BGR to RGB
-		var img = image.NewRGBA(jpg.Bounds())
-		draw.Draw(img, img.Rect, jpg, img.Rect.Min, draw.Src)
+func decodeARGB(rect image.Rectangle, data []byte, alphaBits uint32) (image.Image, error) {
+	var num = rect.Dx() * rect.Dy()
+	if len(data) < num*4 {
+		return nil, ErrBadFormat
+	}
 
-		for i, m := 0, img.Rect.Dx()*img.Rect.Dy(); i < m; i++ {
-			img.Pix[i*4+0], img.Pix[i*4+2] = img.Pix[i*4+2], img.Pix[i*4+0]
+	var img = image.NewNRGBA(rect)
+	for i := 0; i < num; i++ {
+		img.Pix[i*4+0] = data[i*4+2]
+		img.Pix[i*4+1] = data[i*4+1]
+		img.Pix[i*4+2] = data[i*4+0]
+		if alphaBits > 0 {
+			img.Pix[i*4+3] = data[i*4+3]
+		} else {
+			img.Pix[i*4+3] = 0xFF
 		}
+	}
 
-		return img, nil
+	return img, nil
+}
 
-	// case 0x01: PALETTE
-	default:
-		return nil, ErrInvalidCompression
+// DecodeConfig returns the color model and dimensions of a BLP image without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	var b protocol.Buffer
+	if _, err := b.ReadSizeFrom(r, 20); err != nil {
+		return image.Config{}, err
 	}
+
+	h, err := decodeInfo(&b)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	var cfg = image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      int(h.Width),
+		Height:     int(h.Height),
+	}
+	if h.Compression == CompressionJPEG {
+		cfg.ColorModel = color.RGBAModel
+	}
+
+	return cfg, nil
+}
+
+// Decode a BLP image. Only take the first image if it's a mipmap.
+func Decode(r io.Reader) (image.Image, error) {
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, err
+	}
+
+	var raw = b.Bytes
+	h, err := decodeHeader(&b)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.decodeMipmap(raw, 0)
+}
+
+// DecodeAll decodes a BLP image and returns all of its mipmap levels, starting with the full size image.
+func DecodeAll(r io.Reader) ([]image.Image, error) {
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, err
+	}
+
+	var raw = b.Bytes
+	h, err := decodeHeader(&b)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = make([]image.Image, h.numMipmaps())
+	for i := range res {
+		if res[i], err = h.decodeMipmap(raw, i); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
 }
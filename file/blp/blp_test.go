@@ -8,12 +8,16 @@ import (
 	"bytes"
 	"crypto/sha512"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"image"
+	"image/color"
 	"image/png"
 	"os"
 	"testing"
 
 	"github.com/nielsAD/gowarcraft3/file/blp"
+	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
 func Example() {
@@ -61,3 +65,330 @@ func TestJPEG(t *testing.T) {
 		t.Fatal("Sha512 mismatch")
 	}
 }
+
+func makeBLP2(compression byte, alphaBits byte, alphaType byte, w int, h int, pal []color.NRGBA, mipmaps ...[]byte) []byte {
+	var b protocol.Buffer
+	b.WriteLEDString(blp.Header2)
+	b.WriteUInt32(1)
+	b.WriteUInt8(compression)
+	b.WriteUInt8(alphaBits)
+	b.WriteUInt8(alphaType)
+	if len(mipmaps) > 1 {
+		b.WriteUInt8(1)
+	} else {
+		b.WriteUInt8(0)
+	}
+	b.WriteUInt32(uint32(w))
+	b.WriteUInt32(uint32(h))
+
+	var offset = 148 + 1024
+	for i := 0; i < 16; i++ {
+		if i < len(mipmaps) {
+			b.WriteUInt32(uint32(offset))
+			offset += len(mipmaps[i])
+		} else {
+			b.WriteUInt32(0)
+		}
+	}
+	for i := 0; i < 16; i++ {
+		if i < len(mipmaps) {
+			b.WriteUInt32(uint32(len(mipmaps[i])))
+		} else {
+			b.WriteUInt32(0)
+		}
+	}
+
+	for i := 0; i < 256; i++ {
+		var c color.NRGBA
+		if i < len(pal) {
+			c = pal[i]
+		}
+		b.WriteUInt8(c.B)
+		b.WriteUInt8(c.G)
+		b.WriteUInt8(c.R)
+		b.WriteUInt8(c.A)
+	}
+
+	for _, m := range mipmaps {
+		b.WriteBlob(m)
+	}
+
+	return b.Bytes
+}
+
+func TestBLP2(t *testing.T) {
+	var red = color.NRGBA{0xFF, 0x00, 0x00, 0xFF}
+	var blue = color.NRGBA{0x00, 0x00, 0xFF, 0xFF}
+
+	// Block with c0=red, c1=blue, all indices 0 (red)
+	var dxt1 = []byte{0x00, 0xF8, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x00}
+	// Block with c0=blue, c1=red (3-color mode), all indices 3 (transparent)
+	var dxt1a = []byte{0x1F, 0x00, 0x00, 0xF8, 0xFF, 0xFF, 0xFF, 0xFF}
+	// Explicit alpha 0x8 for all pixels
+	var dxt3 = append([]byte{0x88, 0x88, 0x88, 0x88, 0x88, 0x88, 0x88, 0x88}, dxt1...)
+	// Interpolated alpha, all indices 1 (a1=0x40)
+	var dxt5 = append([]byte{0xFF, 0x40, 0x49, 0x92, 0x24, 0x49, 0x92, 0x24}, dxt1...)
+
+	var files = []struct {
+		name    string
+		data    []byte
+		mipmaps int
+		bounds  image.Rectangle
+		px      color.NRGBA
+	}{
+		{
+			"DXT1",
+			makeBLP2(2, 0, 0, 8, 4, nil, bytes.Repeat(dxt1, 2), dxt1, dxt1, dxt1),
+			4,
+			image.Rect(0, 0, 8, 4),
+			red,
+		},
+		{
+			"DXT1 (opaque)",
+			makeBLP2(2, 0, 0, 4, 4, nil, dxt1a),
+			1,
+			image.Rect(0, 0, 4, 4),
+			color.NRGBA{A: 0xFF},
+		},
+		{
+			"DXT1 (alpha)",
+			makeBLP2(2, 1, 0, 4, 4, nil, dxt1a),
+			1,
+			image.Rect(0, 0, 4, 4),
+			color.NRGBA{},
+		},
+		{
+			"DXT3",
+			makeBLP2(2, 8, 1, 4, 4, nil, dxt3, dxt3, dxt3),
+			3,
+			image.Rect(0, 0, 4, 4),
+			color.NRGBA{0xFF, 0x00, 0x00, 0x88},
+		},
+		{
+			"DXT5",
+			makeBLP2(2, 8, 7, 2, 2, nil, dxt5, dxt5),
+			2,
+			image.Rect(0, 0, 2, 2),
+			color.NRGBA{0xFF, 0x00, 0x00, 0x40},
+		},
+		{
+			"Palette",
+			makeBLP2(1, 8, 0, 2, 2, []color.NRGBA{red, blue}, []byte{1, 1, 1, 1, 0x80, 0x80, 0x80, 0x80}, []byte{1, 0x80}),
+			2,
+			image.Rect(0, 0, 2, 2),
+			color.NRGBA{0x00, 0x00, 0xFF, 0x80},
+		},
+		{
+			"Palette (1-bit alpha)",
+			makeBLP2(1, 1, 0, 2, 2, []color.NRGBA{red, blue}, []byte{0, 0, 0, 0, 0x0F}),
+			1,
+			image.Rect(0, 0, 2, 2),
+			red,
+		},
+		{
+			"ARGB",
+			makeBLP2(3, 8, 0, 1, 2, nil, []byte{0xFF, 0x00, 0x00, 0x20, 0xFF, 0x00, 0x00, 0x20}),
+			1,
+			image.Rect(0, 0, 1, 2),
+			color.NRGBA{0x00, 0x00, 0xFF, 0x20},
+		},
+	}
+
+	for _, f := range files {
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(f.data))
+		if err != nil {
+			t.Fatal(f.name, err)
+		}
+		if format != "blp" || cfg.Width != f.bounds.Dx() || cfg.Height != f.bounds.Dy() {
+			t.Fatalf("%v: config mismatch %v %+v\n", f.name, format, cfg)
+		}
+
+		img, err := blp.DecodeAll(bytes.NewReader(f.data))
+		if err != nil {
+			t.Fatal(f.name, err)
+		}
+		if len(img) != f.mipmaps {
+			t.Fatalf("%v: expected %v mipmaps, got %v\n", f.name, f.mipmaps, len(img))
+		}
+
+		for i, m := range img {
+			var w, h = f.bounds.Dx() >> uint(i), f.bounds.Dy() >> uint(i)
+			if w < 1 {
+				w = 1
+			}
+			if h < 1 {
+				h = 1
+			}
+			if m.Bounds() != image.Rect(0, 0, w, h) {
+				t.Fatalf("%v: mipmap %v bounds mismatch %v\n", f.name, i, m.Bounds())
+			}
+		}
+
+		if c := color.NRGBAModel.Convert(img[0].At(0, 0)).(color.NRGBA); c != f.px {
+			t.Fatalf("%v: pixel mismatch %v != %v\n", f.name, c, f.px)
+		}
+
+		if _, err := blp.DecodeAll(bytes.NewReader(f.data[:len(f.data)-1])); err != blp.ErrBadFormat {
+			t.Fatalf("%v: expected ErrBadFormat for truncated input, got %v\n", f.name, err)
+		}
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	f, err := os.Open("./test.blp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "blp" || cfg.Width != 256 || cfg.Height != 256 {
+		t.Fatalf("Config mismatch %v %+v\n", format, cfg)
+	}
+}
+
+func testImage(w int, h int, colors int) *image.NRGBA {
+	var img = image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var c = (x + y*w) % colors
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(c * 7),
+				G: uint8(c * 13),
+				B: uint8(255 - c),
+				A: uint8(255 - (c%4)*64),
+			})
+		}
+	}
+	return img
+}
+
+func TestEncode(t *testing.T) {
+	var files = []struct {
+		name    string
+		img     *image.NRGBA
+		opt     *blp.Options
+		mipmaps int
+		maxDiff int
+	}{
+		{"JPEG", testImage(64, 32, 2), nil, 7, 24},
+		{"JPEG (no mipmaps)", testImage(64, 32, 2), &blp.Options{Quality: 100, NoMipmaps: true}, 1, 8},
+		{"JPEG (partial blocks)", testImage(13, 7, 3), &blp.Options{Quality: 100}, 4, 8},
+		{"Palette", testImage(32, 32, 200), &blp.Options{Compression: blp.CompressionPalette, AlphaBits: 8}, 6, 0},
+		{"Palette (1-bit alpha)", testImage(3, 5, 4), &blp.Options{Compression: blp.CompressionPalette, AlphaBits: 1}, 3, 0},
+		{"Palette (4-bit alpha)", testImage(4, 4, 4), &blp.Options{Compression: blp.CompressionPalette, AlphaBits: 4}, 3, 0},
+		{"Palette (quantized)", testImage(64, 64, 4096), &blp.Options{Compression: blp.CompressionPalette}, 7, 12},
+	}
+
+	for _, f := range files {
+		var buf bytes.Buffer
+		if err := blp.Encode(&buf, f.img, f.opt); err != nil {
+			t.Fatal(f.name, err)
+		}
+
+		img, err := blp.DecodeAll(&buf)
+		if err != nil {
+			t.Fatal(f.name, err)
+		}
+		if len(img) != f.mipmaps {
+			t.Fatalf("%v: expected %v mipmaps, got %v\n", f.name, f.mipmaps, len(img))
+		}
+		if img[0].Bounds() != f.img.Rect {
+			t.Fatalf("%v: bounds mismatch %v\n", f.name, img[0].Bounds())
+		}
+		if b := img[len(img)-1].Bounds(); len(img) > 1 && (b.Dx() != 1 || b.Dy() != 1) {
+			t.Fatalf("%v: expected smallest mipmap to be 1x1, got %v\n", f.name, b)
+		}
+
+		var alphaBits uint32 = 8
+		if f.opt != nil {
+			alphaBits = f.opt.AlphaBits
+		}
+
+		for y := 0; y < f.img.Rect.Dy(); y++ {
+			for x := 0; x < f.img.Rect.Dx(); x++ {
+				var a = f.img.NRGBAAt(x, y)
+				var b = color.NRGBAModel.Convert(img[0].At(x, y)).(color.NRGBA)
+
+				var diff = 0
+				for _, d := range []int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B)} {
+					if d < 0 {
+						d = -d
+					}
+					if d > diff {
+						diff = d
+					}
+				}
+				if diff > f.maxDiff {
+					t.Fatalf("%v: pixel (%v,%v) mismatch %v != %v\n", f.name, x, y, a, b)
+				}
+
+				var alpha = a.A
+				switch {
+				case f.opt == nil || f.opt.Compression == blp.CompressionJPEG || alphaBits == 0:
+					alpha = 0xFF
+				case alphaBits == 1:
+					alpha = (a.A >> 7) * 0xFF
+				case alphaBits == 4:
+					alpha = (a.A >> 4) * 0x11
+				}
+				if b.A != alpha {
+					t.Fatalf("%v: alpha (%v,%v) mismatch %v != %v\n", f.name, x, y, alpha, b.A)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeFormat(t *testing.T) {
+	var img = testImage(16, 16, 4)
+
+	var buf bytes.Buffer
+	if err := blp.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// JPEG header followed by the first mipmap should be a 4-component baseline JPEG without APP14
+	var b = buf.Bytes()
+	var offset = binary.LittleEndian.Uint32(b[28:])
+	var size = binary.LittleEndian.Uint32(b[92:])
+	var hdrSize = binary.LittleEndian.Uint32(b[156:])
+	var jpg = append(append([]byte{}, b[160:160+hdrSize]...), b[offset:offset+size]...)
+
+	var sof = bytes.Index(jpg, []byte{0xFF, 0xC0})
+	if sof < 0 || jpg[sof+9] != 4 {
+		t.Fatal("Expected 4-component SOF0")
+	}
+	if bytes.Contains(jpg, []byte{0xFF, 0xEE}) {
+		t.Fatal("Unexpected APP14 marker")
+	}
+
+	buf.Reset()
+	if err := blp.Encode(&buf, img, &blp.Options{Compression: blp.CompressionPalette}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Palette entries are BGRA with opaque alpha
+	var pal = buf.Bytes()[156 : 156+256*4]
+	for i := 0; i < 256; i++ {
+		if pal[i*4+3] != 0xFF {
+			t.Fatalf("Palette entry %v alpha %v != 255\n", i, pal[i*4+3])
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	var img = testImage(4, 4, 4)
+	if err := blp.Encode(&bytes.Buffer{}, img, &blp.Options{Compression: blp.CompressionDXT}); err != blp.ErrInvalidCompression {
+		t.Fatal("Expected ErrInvalidCompression, got", err)
+	}
+	if err := blp.Encode(&bytes.Buffer{}, img, &blp.Options{Compression: blp.CompressionPalette, AlphaBits: 3}); err != blp.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+	if err := blp.Encode(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, 0, 0)), nil); err != blp.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+}
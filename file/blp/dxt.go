@@ -0,0 +1,60 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package blp
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/nielsAD/gowarcraft3/file/internal/bc"
+)
+
+func decodeDXT(rect image.Rectangle, data []byte, alphaType uint8, alphaBits uint32) (image.Image, error) {
+	var blockSize = 16
+	if alphaType == alphaTypeDXT1 {
+		blockSize = 8
+	}
+
+	var bw = (rect.Dx() + 3) / 4
+	var bh = (rect.Dy() + 3) / 4
+	if len(data) < bw*bh*blockSize {
+		return nil, ErrBadFormat
+	}
+
+	var decode func(blk []byte, res *[16]color.NRGBA)
+	switch alphaType {
+	case alphaTypeDXT1:
+		decode = func(blk []byte, res *[16]color.NRGBA) {
+			bc.DecodeColorBlock(blk, true, res)
+			if alphaBits == 0 {
+				for i := range res {
+					res[i].A = 0xFF
+				}
+			}
+		}
+	case alphaTypeDXT3:
+		decode = func(blk []byte, res *[16]color.NRGBA) {
+			var a [16]uint8
+			bc.DecodeExplicitBlock(blk, &a)
+			for i := range res {
+				res[i].A = a[i]
+			}
+			bc.DecodeColorBlock(blk[8:], false, res)
+		}
+	case alphaTypeDXT5:
+		decode = func(blk []byte, res *[16]color.NRGBA) {
+			var a [16]uint8
+			bc.DecodeInterpolatedBlock(blk, &a)
+			for i := range res {
+				res[i].A = a[i]
+			}
+			bc.DecodeColorBlock(blk[8:], false, res)
+		}
+	default:
+		return nil, ErrInvalidCompression
+	}
+
+	return bc.DecodeImage(rect, data, blockSize, decode), nil
+}
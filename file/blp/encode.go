@@ -0,0 +1,251 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package blp
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// DefaultQuality is the default JPEG quality used by Encode
+const DefaultQuality = 90
+
+// Maximum size of the shared JPEG header supported by the game
+const maxJPEGHeader = 624
+
+// Options are the encoding parameters
+type Options struct {
+	// Compression is either CompressionJPEG or CompressionPalette
+	Compression Compression
+
+	// Quality ranges from 1 to 100 inclusive, higher is better (JPEG only)
+	Quality int
+
+	// AlphaBits stores 0, 1, 4 or 8 bits of alpha per pixel (palette only)
+	AlphaBits uint32
+
+	// NoMipmaps disables the automatic generation of mipmaps
+	NoMipmaps bool
+}
+
+// Encode writes the image m to w in BLP1 format with the given options.
+// Default parameters are used if a nil *Options is passed.
+func Encode(w io.Writer, m image.Image, o *Options) error {
+	var opt = Options{Quality: DefaultQuality}
+	if o != nil {
+		opt = *o
+	}
+
+	var rect = m.Bounds()
+	if rect.Empty() || rect.Dx() > 65535 || rect.Dy() > 65535 {
+		return ErrBadFormat
+	}
+
+	var flags uint32
+	switch opt.Compression {
+	case CompressionJPEG:
+		if opt.AlphaBits != 0 {
+			return ErrBadFormat
+		}
+		if opt.Quality < 1 || opt.Quality > 100 {
+			opt.Quality = DefaultQuality
+		}
+		flags = 3
+	case CompressionPalette:
+		switch opt.AlphaBits {
+		case 0:
+			flags = 5
+		case 1, 4, 8:
+			flags = 4
+		default:
+			return ErrBadFormat
+		}
+	default:
+		return ErrInvalidCompression
+	}
+
+	var mipmaps = []*image.NRGBA{toNRGBA(m)}
+	if !opt.NoMipmaps {
+		for len(mipmaps) < maxMipmaps {
+			var last = mipmaps[len(mipmaps)-1]
+			if last.Rect.Dx() == 1 && last.Rect.Dy() == 1 {
+				break
+			}
+			mipmaps = append(mipmaps, downsample(last))
+		}
+	}
+
+	var extra []byte
+	var data = make([][]byte, len(mipmaps))
+
+	switch opt.Compression {
+	case CompressionJPEG:
+		for i, mm := range mipmaps {
+			data[i] = encodeJPEG(mm, opt.Quality)
+		}
+
+		// Share the common prefix (tables and frame header) between mipmaps
+		var hSize = commonPrefix(data)
+		if hSize > maxJPEGHeader {
+			hSize = maxJPEGHeader
+		}
+
+		var hdr protocol.Buffer
+		hdr.WriteUInt32(uint32(hSize))
+		hdr.WriteBlob(data[0][:hSize])
+		extra = hdr.Bytes
+
+		for i := range data {
+			data[i] = data[i][hSize:]
+		}
+
+	case CompressionPalette:
+		var pal = quantize(mipmaps[0], 256)
+		var idx = newPaletteIndex(pal)
+
+		// Alpha is taken from the per-pixel alpha list, palette entries are written opaque
+		var hdr protocol.Buffer
+		for i := 0; i < 256; i++ {
+			var c color.NRGBA
+			if i < len(pal) {
+				c = pal[i].(color.NRGBA)
+			}
+			hdr.WriteUInt8(c.B)
+			hdr.WriteUInt8(c.G)
+			hdr.WriteUInt8(c.R)
+			hdr.WriteUInt8(0xFF)
+		}
+		extra = hdr.Bytes
+
+		for i, mm := range mipmaps {
+			data[i] = encodePalette(mm, idx, opt.AlphaBits)
+		}
+	}
+
+	var b protocol.Buffer
+	b.WriteLEDString(Header)
+	b.WriteUInt32(uint32(opt.Compression))
+	b.WriteUInt32(opt.AlphaBits)
+	b.WriteUInt32(uint32(rect.Dx()))
+	b.WriteUInt32(uint32(rect.Dy()))
+	b.WriteUInt32(flags)
+	b.WriteBool32(len(mipmaps) > 1)
+
+	var offset = 156 + len(extra)
+	for i := 0; i < maxMipmaps; i++ {
+		if i < len(data) {
+			b.WriteUInt32(uint32(offset))
+			offset += len(data[i])
+		} else {
+			b.WriteUInt32(0)
+		}
+	}
+	for i := 0; i < maxMipmaps; i++ {
+		if i < len(data) {
+			b.WriteUInt32(uint32(len(data[i])))
+		} else {
+			b.WriteUInt32(0)
+		}
+	}
+
+	b.WriteBlob(extra)
+	for _, d := range data {
+		b.WriteBlob(d)
+	}
+
+	_, err := b.WriteTo(w)
+	return err
+}
+
+func toNRGBA(m image.Image) *image.NRGBA {
+	var rect = m.Bounds()
+	var img = image.NewNRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(img, img.Rect, m, rect.Min, draw.Src)
+	return img
+}
+
+func commonPrefix(data [][]byte) int {
+	var n = len(data[0])
+	for _, d := range data[1:] {
+		var i = 0
+		for i < n && i < len(d) && d[i] == data[0][i] {
+			i++
+		}
+		n = i
+	}
+	return n
+}
+
+// Downsample image to half its size using an alpha-weighted box filter
+func downsample(m *image.NRGBA) *image.NRGBA {
+	var w = m.Rect.Dx()
+	var h = m.Rect.Dy()
+
+	var dw = w / 2
+	var dh = h / 2
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	var img = image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var r, g, b, a, n uint32
+			for sy := y * 2; sy < y*2+2 && sy < h; sy++ {
+				for sx := x * 2; sx < x*2+2 && sx < w; sx++ {
+					var p = m.Pix[m.PixOffset(sx, sy):]
+					var pa = uint32(p[3])
+					r += uint32(p[0]) * pa
+					g += uint32(p[1]) * pa
+					b += uint32(p[2]) * pa
+					a += pa
+					n++
+				}
+			}
+
+			var p = img.Pix[img.PixOffset(x, y):]
+			if a > 0 {
+				p[0] = uint8((r + a/2) / a)
+				p[1] = uint8((g + a/2) / a)
+				p[2] = uint8((b + a/2) / a)
+			}
+			p[3] = uint8((a + n/2) / n)
+		}
+	}
+
+	return img
+}
+
+func encodePalette(m *image.NRGBA, idx *paletteIndex, alphaBits uint32) []byte {
+	var num = m.Rect.Dx() * m.Rect.Dy()
+	var res = make([]byte, num+(num*int(alphaBits)+7)/8)
+	var alpha = res[num:]
+
+	for i := 0; i < num; i++ {
+		var p = m.Pix[i*4:]
+		res[i] = idx.Index(p[0], p[1], p[2])
+
+		var a = p[3]
+		switch alphaBits {
+		case 1:
+			if a >= 0x80 {
+				alpha[i/8] |= 1 << uint(i%8)
+			}
+		case 4:
+			alpha[i/2] |= (a >> 4) << (uint(i%2) * 4)
+		case 8:
+			alpha[i] = a
+		}
+	}
+
+	return res
+}
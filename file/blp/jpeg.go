@@ -0,0 +1,327 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package blp
+
+import (
+	"bytes"
+	"image"
+	"math"
+)
+
+// BLP1 JPEG data is a baseline JPEG with four components (B, G, R, A) at full resolution and
+// without color transform, which image/jpeg cannot write. Like the files written by the game,
+// all components use quantization table 0, the first component uses Huffman tables 0 and the
+// others use Huffman tables 1, and there is no APP14 marker.
+
+// Standard luminance quantization table (ITU T.81 Annex K), in zigzag order
+var jpegQuant = [64]byte{
+	16, 11, 12, 14, 12, 10, 16, 14,
+	13, 14, 18, 17, 16, 19, 24, 40,
+	26, 24, 22, 22, 24, 49, 35, 37,
+	29, 40, 58, 51, 61, 60, 57, 51,
+	56, 55, 64, 72, 92, 78, 64, 68,
+	87, 69, 55, 56, 80, 109, 81, 87,
+	95, 98, 103, 104, 103, 62, 77, 113,
+	121, 112, 100, 120, 92, 101, 103, 99,
+}
+
+// Natural order index of each zigzag position
+var jpegZigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+type huffmanSpec struct {
+	count [16]byte
+	value []byte
+}
+
+// Standard Huffman tables (ITU T.81 Annex K): luminance DC/AC (0) and chrominance DC/AC (1)
+var jpegHuffman = [4]huffmanSpec{
+	{
+		[16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125},
+		[]byte{
+			0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+			0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+			0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+			0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+			0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+			0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+			0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+			0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+			0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+			0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+			0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+			0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+			0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+			0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+			0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+			0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+			0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+			0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+			0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+			0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+	{
+		[16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119},
+		[]byte{
+			0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+			0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+			0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+			0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+			0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+			0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+			0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+			0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+			0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+			0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+			0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+			0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+			0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+			0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+			0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+			0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+			0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+			0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+}
+
+// Huffman code (lower size bits) for each value
+type huffmanCode struct {
+	code uint32
+	size uint8
+}
+
+type huffmanTable [256]huffmanCode
+
+func (s *huffmanSpec) table() *huffmanTable {
+	var res huffmanTable
+	var code uint32
+	var k = 0
+	for i, n := range s.count {
+		for j := 0; j < int(n); j++ {
+			res[s.value[k]] = huffmanCode{code: code, size: uint8(i + 1)}
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return &res
+}
+
+var jpegTables = [4]*huffmanTable{
+	jpegHuffman[0].table(),
+	jpegHuffman[1].table(),
+	jpegHuffman[2].table(),
+	jpegHuffman[3].table(),
+}
+
+// DCT basis, jpegCos[x][u] = C(u)/2 * cos((2x+1)uπ/16)
+var jpegCos = func() (res [8][8]float64) {
+	for x := 0; x < 8; x++ {
+		for u := 0; u < 8; u++ {
+			var c = 0.5
+			if u == 0 {
+				c = 0.5 / math.Sqrt2
+			}
+			res[x][u] = c * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+	return
+}()
+
+// Entropy coded segment writer with 0xFF byte stuffing
+type jpegBitWriter struct {
+	buf  *bytes.Buffer
+	bits uint32
+	n    uint
+}
+
+func (w *jpegBitWriter) emit(bits uint32, size uint8) {
+	for i := int(size) - 1; i >= 0; i-- {
+		w.bits = w.bits<<1 | (bits>>uint(i))&1
+		w.n++
+		if w.n == 8 {
+			w.buf.WriteByte(byte(w.bits))
+			if w.bits == 0xFF {
+				w.buf.WriteByte(0x00)
+			}
+			w.bits = 0
+			w.n = 0
+		}
+	}
+}
+
+func (w *jpegBitWriter) flush() {
+	for w.n != 0 {
+		w.emit(1, 1)
+	}
+}
+
+// emitValue writes the Huffman code for (run<<4 | size) followed by the size bits of v
+func (w *jpegBitWriter) emitValue(t *huffmanTable, run int, v int) {
+	var a = v
+	if a < 0 {
+		a = -a
+		v--
+	}
+	var size = uint8(0)
+	for a > 0 {
+		size++
+		a >>= 1
+	}
+
+	var h = t[run<<4|int(size)]
+	w.emit(h.code, h.size)
+	if size > 0 {
+		w.emit(uint32(v)&(1<<size-1), size)
+	}
+}
+
+func jpegSegment(buf *bytes.Buffer, marker byte, data []byte) {
+	buf.Write([]byte{0xFF, marker, byte((len(data) + 2) >> 8), byte(len(data) + 2)})
+	buf.Write(data)
+}
+
+// encodeJPEG encodes m as 4-component BGRA JPEG, alpha is set to 0xFF
+func encodeJPEG(m *image.NRGBA, quality int) []byte {
+	var scale int
+	if quality < 50 {
+		scale = 5000 / quality
+	} else {
+		scale = 200 - quality*2
+	}
+
+	var quant [64]int
+	var dqt = []byte{0x00}
+	for i, q := range jpegQuant {
+		var v = (int(q)*scale + 50) / 100
+		if v < 1 {
+			v = 1
+		} else if v > 255 {
+			v = 255
+		}
+		quant[i] = v
+		dqt = append(dqt, byte(v))
+	}
+
+	var w = m.Rect.Dx()
+	var h = m.Rect.Dy()
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+	jpegSegment(&buf, 0xDB, dqt)
+	for i, s := range jpegHuffman {
+		var dht = []byte{byte(i%2)<<4 | byte(i/2)}
+		dht = append(dht, s.count[:]...)
+		dht = append(dht, s.value...)
+		jpegSegment(&buf, 0xC4, dht)
+	}
+	jpegSegment(&buf, 0xC0, []byte{
+		8, byte(h >> 8), byte(h), byte(w >> 8), byte(w), 4,
+		1, 0x11, 0, 2, 0x11, 0, 3, 0x11, 0, 4, 0x11, 0,
+	})
+	jpegSegment(&buf, 0xDA, []byte{4, 1, 0x00, 2, 0x11, 3, 0x11, 4, 0x11, 0, 63, 0})
+
+	var bw = jpegBitWriter{buf: &buf}
+	var pred [4]int
+	var blk [64]float64
+	var tmp [64]float64
+	for by := 0; by < h; by += 8 {
+		for bx := 0; bx < w; bx += 8 {
+			for c := 0; c < 4; c++ {
+				// Level shifted samples, edge pixels are repeated for partial blocks
+				for y := 0; y < 8; y++ {
+					for x := 0; x < 8; x++ {
+						var v = 0xFF
+						if c < 3 {
+							var px, py = bx + x, by + y
+							if px >= w {
+								px = w - 1
+							}
+							if py >= h {
+								py = h - 1
+							}
+							v = int(m.Pix[m.PixOffset(px, py)+2-c])
+						}
+						blk[y*8+x] = float64(v - 128)
+					}
+				}
+
+				// Separable 2D DCT, rows then columns
+				for y := 0; y < 8; y++ {
+					for u := 0; u < 8; u++ {
+						var s float64
+						for x := 0; x < 8; x++ {
+							s += blk[y*8+x] * jpegCos[x][u]
+						}
+						tmp[y*8+u] = s
+					}
+				}
+				for u := 0; u < 8; u++ {
+					for v := 0; v < 8; v++ {
+						var s float64
+						for y := 0; y < 8; y++ {
+							s += tmp[y*8+u] * jpegCos[y][v]
+						}
+						blk[v*8+u] = s
+					}
+				}
+
+				var dc, ac = jpegTables[0], jpegTables[1]
+				if c > 0 {
+					dc, ac = jpegTables[2], jpegTables[3]
+				}
+
+				var q0 = int(math.Round(blk[0] / float64(quant[0])))
+				bw.emitValue(dc, 0, q0-pred[c])
+				pred[c] = q0
+
+				var run = 0
+				for k := 1; k < 64; k++ {
+					var q = int(math.Round(blk[jpegZigzag[k]] / float64(quant[k])))
+					if q == 0 {
+						run++
+						continue
+					}
+					for run > 15 {
+						bw.emitValue(ac, 15, 0)
+						run -= 16
+					}
+					bw.emitValue(ac, run, q)
+					run = 0
+				}
+				if run > 0 {
+					bw.emitValue(ac, 0, 0)
+				}
+			}
+		}
+	}
+
+	bw.flush()
+	buf.Write([]byte{0xFF, 0xD9})
+
+	return buf.Bytes()
+}
@@ -0,0 +1,165 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package blp
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+type colorCount struct {
+	rgb [3]uint8
+	n   int
+}
+
+type colorBox []colorCount
+
+func (b colorBox) weight() int {
+	var n = 0
+	for _, c := range b {
+		n += c.n
+	}
+	return n
+}
+
+// Returns the channel with the largest range and its size
+func (b colorBox) widest() (int, int) {
+	var ch, size = 0, -1
+	for i := 0; i < 3; i++ {
+		var lo, hi = 255, 0
+		for _, c := range b {
+			var v = int(c.rgb[i])
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo > size {
+			ch, size = i, hi-lo
+		}
+	}
+	return ch, size
+}
+
+func (b colorBox) mean() color.NRGBA {
+	var sum [3]int
+	var n = 0
+	for _, c := range b {
+		for i := 0; i < 3; i++ {
+			sum[i] += int(c.rgb[i]) * c.n
+		}
+		n += c.n
+	}
+	return color.NRGBA{
+		R: uint8((sum[0] + n/2) / n),
+		G: uint8((sum[1] + n/2) / n),
+		B: uint8((sum[2] + n/2) / n),
+		A: 0xFF,
+	}
+}
+
+// Split the box at the weighted median of its widest channel
+func (b colorBox) split() (colorBox, colorBox) {
+	var ch, _ = b.widest()
+	sort.Slice(b, func(i, j int) bool { return b[i].rgb[ch] < b[j].rgb[ch] })
+
+	var half = b.weight() / 2
+	var n = 0
+	for i := 0; i < len(b)-1; i++ {
+		n += b[i].n
+		if n >= half {
+			return b[:i+1], b[i+1:]
+		}
+	}
+	return b[:len(b)-1], b[len(b)-1:]
+}
+
+// Quantize the colors of m to a palette of at most size colors using median cut
+func quantize(m *image.NRGBA, size int) color.Palette {
+	var hist = make(map[[3]uint8]int)
+	for i := 0; i < len(m.Pix); i += 4 {
+		if m.Pix[i+3] == 0 {
+			// Color of fully transparent pixels is irrelevant
+			continue
+		}
+		hist[[3]uint8{m.Pix[i+0], m.Pix[i+1], m.Pix[i+2]}]++
+	}
+
+	var box = make(colorBox, 0, len(hist))
+	for rgb, n := range hist {
+		box = append(box, colorCount{rgb: rgb, n: n})
+	}
+
+	// Stable order for deterministic output
+	sort.Slice(box, func(i, j int) bool {
+		var a, b = box[i].rgb, box[j].rgb
+		return a[0] < b[0] || (a[0] == b[0] && (a[1] < b[1] || (a[1] == b[1] && a[2] < b[2])))
+	})
+
+	var pal = make(color.Palette, 0, size)
+	if len(box) <= size {
+		for _, c := range box {
+			pal = append(pal, color.NRGBA{c.rgb[0], c.rgb[1], c.rgb[2], 0xFF})
+		}
+		if len(pal) == 0 {
+			pal = append(pal, color.NRGBA{A: 0xFF})
+		}
+		return pal
+	}
+
+	var boxes = []colorBox{box}
+	for len(boxes) < size {
+		// Split the box with the largest weighted range
+		var best, score = -1, 0
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			var _, r = b.widest()
+			if s := r * b.weight(); s > score {
+				best, score = i, s
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		var l, r = boxes[best].split()
+		boxes[best] = l
+		boxes = append(boxes, r)
+	}
+
+	for _, b := range boxes {
+		pal = append(pal, b.mean())
+	}
+	return pal
+}
+
+// Nearest color lookup with caching
+type paletteIndex struct {
+	pal   color.Palette
+	cache map[[3]uint8]uint8
+}
+
+func newPaletteIndex(pal color.Palette) *paletteIndex {
+	return &paletteIndex{
+		pal:   pal,
+		cache: make(map[[3]uint8]uint8),
+	}
+}
+
+func (p *paletteIndex) Index(r, g, b uint8) uint8 {
+	var key = [3]uint8{r, g, b}
+	if i, ok := p.cache[key]; ok {
+		return i
+	}
+
+	var i = uint8(p.pal.Index(color.NRGBA{r, g, b, 0xFF}))
+	p.cache[key] = i
+	return i
+}
@@ -0,0 +1,56 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package dds
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/nielsAD/gowarcraft3/file/internal/bc"
+)
+
+func decodeBlock(blk []byte, f format, res *[16]color.NRGBA) {
+	var ch [16]uint8
+	switch f {
+	case formatBC1:
+		bc.DecodeColorBlock(blk, true, res)
+	case formatBC2:
+		bc.DecodeExplicitBlock(blk, &ch)
+		for i := range res {
+			res[i].A = ch[i]
+		}
+		bc.DecodeColorBlock(blk[8:], false, res)
+	case formatBC3:
+		bc.DecodeInterpolatedBlock(blk, &ch)
+		for i := range res {
+			res[i].A = ch[i]
+		}
+		bc.DecodeColorBlock(blk[8:], false, res)
+	case formatBC4:
+		// Single channel, decoded as grayscale
+		bc.DecodeInterpolatedBlock(blk, &ch)
+		for i := range res {
+			res[i] = color.NRGBA{ch[i], ch[i], ch[i], 0xFF}
+		}
+	case formatBC5:
+		// Two channels (red, green), commonly used for normal maps
+		bc.DecodeInterpolatedBlock(blk, &ch)
+		for i := range res {
+			res[i] = color.NRGBA{R: ch[i], A: 0xFF}
+		}
+		bc.DecodeInterpolatedBlock(blk[8:], &ch)
+		for i := range res {
+			res[i].G = ch[i]
+		}
+	case formatBC7:
+		decodeBC7Block(blk, res)
+	}
+}
+
+func decodeBlocks(rect image.Rectangle, data []byte, f format) image.Image {
+	return bc.DecodeImage(rect, data, f.blockSize(), func(blk []byte, res *[16]color.NRGBA) {
+		decodeBlock(blk, f, res)
+	})
+}
@@ -0,0 +1,326 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package dds
+
+import (
+	"image/color"
+)
+
+type bc7Mode struct {
+	subsets       uint
+	partitionBits uint
+	rotationBits  uint
+	indexSelBits  uint
+	colorBits     uint
+	alphaBits     uint
+	endpointPBits uint
+	sharedPBits   uint
+	indexBits     uint
+	secIndexBits  uint
+}
+
+var bc7Modes = [8]bc7Mode{
+	{3, 4, 0, 0, 4, 0, 1, 0, 3, 0},
+	{2, 6, 0, 0, 6, 0, 0, 1, 3, 0},
+	{3, 6, 0, 0, 5, 0, 0, 0, 2, 0},
+	{2, 6, 0, 0, 7, 0, 1, 0, 2, 0},
+	{1, 0, 2, 1, 5, 6, 0, 0, 2, 3},
+	{1, 0, 2, 0, 7, 8, 0, 0, 2, 2},
+	{1, 0, 0, 0, 7, 7, 1, 0, 4, 0},
+	{2, 6, 0, 0, 5, 5, 1, 0, 2, 0},
+}
+
+var bc7Weights = [5][]int{
+	nil,
+	nil,
+	{0, 21, 43, 64},
+	{0, 9, 18, 27, 37, 46, 55, 64},
+	{0, 4, 9, 13, 17, 21, 26, 30, 34, 38, 43, 47, 51, 55, 60, 64},
+}
+
+// Subset of each pixel for 2-subset partitions (1 bit per pixel)
+var bc7Partitions2 = [64]uint16{
+	0xCCCC, 0x8888, 0xEEEE, 0xECC8, 0xC880, 0xFEEC, 0xFEC8, 0xEC80,
+	0xC800, 0xFFEC, 0xFE80, 0xE800, 0xFFE8, 0xFF00, 0xFFF0, 0xF000,
+	0xF710, 0x008E, 0x7100, 0x08CE, 0x008C, 0x7310, 0x3100, 0x8CCE,
+	0x088C, 0x3110, 0x6666, 0x366C, 0x17E8, 0x0FF0, 0x718E, 0x399C,
+	0xAAAA, 0xF0F0, 0x5A5A, 0x33CC, 0x3C3C, 0x55AA, 0x9696, 0xA55A,
+	0x73CE, 0x13C8, 0x324C, 0x3BDC, 0x6996, 0xC33C, 0x9966, 0x0660,
+	0x0272, 0x04E4, 0x4E40, 0x2720, 0xC936, 0x936C, 0x39C6, 0x639C,
+	0x9336, 0x9CC6, 0x817E, 0xE718, 0xCCF0, 0x0FCC, 0x7744, 0xEE22,
+}
+
+// Subset of each pixel for 3-subset partitions
+var bc7Partitions3 = [64][16]uint8{
+	{0, 0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 1, 2, 2, 2, 2},
+	{0, 0, 0, 1, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 2, 0, 0, 1, 2, 2, 1, 1, 2, 2, 1, 1},
+	{0, 2, 2, 2, 0, 0, 2, 2, 0, 0, 1, 1, 0, 1, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2},
+	{0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 2, 2, 0, 0, 2, 2},
+	{0, 0, 2, 2, 0, 0, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1},
+	{0, 0, 1, 1, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2},
+	{0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2},
+	{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2},
+	{0, 1, 1, 2, 0, 1, 1, 2, 0, 1, 1, 2, 0, 1, 1, 2},
+	{0, 1, 2, 2, 0, 1, 2, 2, 0, 1, 2, 2, 0, 1, 2, 2},
+	{0, 0, 1, 1, 0, 1, 1, 2, 1, 1, 2, 2, 1, 2, 2, 2},
+	{0, 0, 1, 1, 2, 0, 0, 1, 2, 2, 0, 0, 2, 2, 2, 0},
+	{0, 0, 0, 1, 0, 0, 1, 1, 0, 1, 1, 2, 1, 1, 2, 2},
+	{0, 1, 1, 1, 0, 0, 1, 1, 2, 0, 0, 1, 2, 2, 0, 0},
+	{0, 0, 0, 0, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1, 2, 2},
+	{0, 0, 2, 2, 0, 0, 2, 2, 0, 0, 2, 2, 1, 1, 1, 1},
+	{0, 1, 1, 1, 0, 1, 1, 1, 0, 2, 2, 2, 0, 2, 2, 2},
+	{0, 0, 0, 1, 0, 0, 0, 1, 2, 2, 2, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 0, 0, 1, 1, 0, 1, 2, 2, 0, 1, 2, 2},
+	{0, 0, 0, 0, 1, 1, 0, 0, 2, 2, 1, 0, 2, 2, 1, 0},
+	{0, 1, 2, 2, 0, 1, 2, 2, 0, 0, 1, 1, 0, 0, 0, 0},
+	{0, 0, 1, 2, 0, 0, 1, 2, 1, 1, 2, 2, 2, 2, 2, 2},
+	{0, 1, 1, 0, 1, 2, 2, 1, 1, 2, 2, 1, 0, 1, 1, 0},
+	{0, 0, 0, 0, 0, 1, 1, 0, 1, 2, 2, 1, 1, 2, 2, 1},
+	{0, 0, 2, 2, 1, 1, 0, 2, 1, 1, 0, 2, 0, 0, 2, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 2, 0, 0, 2, 2, 2, 2, 2},
+	{0, 0, 1, 1, 0, 1, 2, 2, 0, 1, 2, 2, 0, 0, 1, 1},
+	{0, 0, 0, 0, 2, 0, 0, 0, 2, 2, 1, 1, 2, 2, 2, 1},
+	{0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 2, 2, 2},
+	{0, 2, 2, 2, 0, 0, 2, 2, 0, 0, 1, 2, 0, 0, 1, 1},
+	{0, 0, 1, 1, 0, 0, 1, 2, 0, 0, 2, 2, 0, 2, 2, 2},
+	{0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0, 0, 1, 2, 0},
+	{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 0, 0, 0, 0},
+	{0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0},
+	{0, 1, 2, 0, 2, 0, 1, 2, 1, 2, 0, 1, 0, 1, 2, 0},
+	{0, 0, 1, 1, 2, 2, 0, 0, 1, 1, 2, 2, 0, 0, 1, 1},
+	{0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 0, 0, 0, 0, 1, 1},
+	{0, 1, 0, 1, 0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 2, 1, 2, 1, 2, 1},
+	{0, 0, 2, 2, 1, 1, 2, 2, 0, 0, 2, 2, 1, 1, 2, 2},
+	{0, 0, 2, 2, 0, 0, 1, 1, 0, 0, 2, 2, 0, 0, 1, 1},
+	{0, 2, 2, 0, 1, 2, 2, 1, 0, 2, 2, 0, 1, 2, 2, 1},
+	{0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2, 0, 1, 0, 1},
+	{0, 0, 0, 0, 2, 1, 2, 1, 2, 1, 2, 1, 2, 1, 2, 1},
+	{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 2, 2, 2, 2},
+	{0, 2, 2, 2, 0, 1, 1, 1, 0, 2, 2, 2, 0, 1, 1, 1},
+	{0, 0, 0, 2, 1, 1, 1, 2, 0, 0, 0, 2, 1, 1, 1, 2},
+	{0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 1, 2, 2, 1, 1, 2},
+	{0, 2, 2, 2, 0, 1, 1, 1, 0, 1, 1, 1, 0, 2, 2, 2},
+	{0, 0, 0, 2, 1, 1, 1, 2, 1, 1, 1, 2, 0, 0, 0, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2, 2, 1, 1, 2},
+	{0, 1, 1, 0, 0, 1, 1, 0, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 0, 2, 2, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 2, 2},
+	{0, 0, 2, 2, 1, 1, 2, 2, 1, 1, 2, 2, 0, 0, 2, 2},
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 1, 2},
+	{0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 1},
+	{0, 2, 2, 2, 1, 2, 2, 2, 0, 2, 2, 2, 1, 2, 2, 2},
+	{0, 1, 0, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+	{0, 1, 1, 1, 2, 0, 1, 1, 2, 2, 0, 1, 2, 2, 2, 0},
+}
+
+// Anchor index of the second subset for 2-subset partitions
+var bc7Anchors2 = [64]uint8{
+	15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15,
+	15, 2, 8, 2, 2, 8, 8, 15, 2, 8, 2, 2, 8, 8, 2, 2,
+	15, 15, 6, 8, 2, 8, 15, 15, 2, 8, 2, 2, 2, 15, 15, 6,
+	6, 2, 6, 8, 15, 15, 2, 2, 15, 15, 15, 15, 15, 2, 2, 15,
+}
+
+// Anchor index of the second subset for 3-subset partitions
+var bc7Anchors3a = [64]uint8{
+	3, 3, 15, 15, 8, 3, 15, 15, 8, 8, 6, 6, 6, 5, 3, 3,
+	3, 3, 8, 15, 3, 3, 6, 10, 5, 8, 8, 6, 8, 5, 15, 15,
+	8, 15, 3, 5, 6, 10, 8, 15, 15, 3, 15, 5, 15, 15, 15, 15,
+	3, 15, 5, 5, 5, 8, 5, 10, 5, 10, 8, 13, 15, 12, 3, 3,
+}
+
+// Anchor index of the third subset for 3-subset partitions
+var bc7Anchors3b = [64]uint8{
+	15, 8, 8, 3, 15, 15, 3, 8, 15, 15, 15, 15, 15, 15, 15, 8,
+	15, 8, 15, 3, 15, 8, 15, 8, 3, 15, 6, 10, 15, 15, 10, 8,
+	15, 3, 15, 10, 10, 8, 9, 10, 6, 15, 8, 15, 3, 6, 6, 8,
+	15, 3, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 3, 15, 15, 8,
+}
+
+// Little-endian bit reader for 128-bit blocks
+type bitReader struct {
+	lo  uint64
+	hi  uint64
+	pos uint
+}
+
+func (r *bitReader) read(n uint) uint8 {
+	var res uint64
+	for i := uint(0); i < n; i++ {
+		var p = r.pos + i
+		var bit uint64
+		if p < 64 {
+			bit = (r.lo >> p) & 1
+		} else {
+			bit = (r.hi >> (p - 64)) & 1
+		}
+		res |= bit << i
+	}
+	r.pos += n
+	return uint8(res)
+}
+
+func bc7Subset(m *bc7Mode, partition uint8, i int) int {
+	switch m.subsets {
+	case 2:
+		return int(bc7Partitions2[partition]>>uint(i)) & 1
+	case 3:
+		return int(bc7Partitions3[partition][i])
+	default:
+		return 0
+	}
+}
+
+func bc7IsAnchor(m *bc7Mode, partition uint8, i int) bool {
+	switch {
+	case i == 0:
+		return true
+	case m.subsets == 2:
+		return i == int(bc7Anchors2[partition])
+	case m.subsets == 3:
+		return i == int(bc7Anchors3a[partition]) || i == int(bc7Anchors3b[partition])
+	default:
+		return false
+	}
+}
+
+func bc7Interpolate(e0 uint8, e1 uint8, w int) uint8 {
+	return uint8((int(e0)*(64-w) + int(e1)*w + 32) >> 6)
+}
+
+func decodeBC7Block(blk []byte, res *[16]color.NRGBA) {
+	var r bitReader
+	for i := 0; i < 8; i++ {
+		r.lo |= uint64(blk[i]) << (uint(i) * 8)
+		r.hi |= uint64(blk[i+8]) << (uint(i) * 8)
+	}
+
+	var mode = 0
+	for mode < 8 && r.read(1) == 0 {
+		mode++
+	}
+	if mode >= 8 {
+		// Reserved mode, decodes to transparent black
+		*res = [16]color.NRGBA{}
+		return
+	}
+
+	var m = &bc7Modes[mode]
+	var partition = r.read(m.partitionBits)
+	var rotation = r.read(m.rotationBits)
+	var indexSel = r.read(m.indexSelBits)
+
+	// Endpoints [subset*2+n][channel]
+	var ep [6][4]uint8
+	var numEP = int(m.subsets) * 2
+	for c := 0; c < 3; c++ {
+		for e := 0; e < numEP; e++ {
+			ep[e][c] = r.read(m.colorBits)
+		}
+	}
+	if m.alphaBits > 0 {
+		for e := 0; e < numEP; e++ {
+			ep[e][3] = r.read(m.alphaBits)
+		}
+	}
+
+	var colorBits = m.colorBits
+	var alphaBits = m.alphaBits
+	if m.endpointPBits > 0 || m.sharedPBits > 0 {
+		var pbits [6]uint8
+		if m.endpointPBits > 0 {
+			for e := 0; e < numEP; e++ {
+				pbits[e] = r.read(1)
+			}
+		} else {
+			for s := 0; s < int(m.subsets); s++ {
+				var p = r.read(1)
+				pbits[s*2], pbits[s*2+1] = p, p
+			}
+		}
+		for e := 0; e < numEP; e++ {
+			for c := 0; c < 4; c++ {
+				ep[e][c] = ep[e][c]<<1 | pbits[e]
+			}
+		}
+		colorBits++
+		if alphaBits > 0 {
+			alphaBits++
+		}
+	}
+
+	// Expand endpoints to 8 bits
+	for e := 0; e < numEP; e++ {
+		for c := 0; c < 3; c++ {
+			ep[e][c] = ep[e][c]<<(8-colorBits) | ep[e][c]>>(2*colorBits-8)
+		}
+		if alphaBits > 0 {
+			ep[e][3] = ep[e][3]<<(8-alphaBits) | ep[e][3]>>(2*alphaBits-8)
+		} else {
+			ep[e][3] = 0xFF
+		}
+	}
+
+	var idx [16]uint8
+	for i := 0; i < 16; i++ {
+		var n = m.indexBits
+		if bc7IsAnchor(m, partition, i) {
+			n--
+		}
+		idx[i] = r.read(n)
+	}
+
+	var idx2 [16]uint8
+	if m.secIndexBits > 0 {
+		for i := 0; i < 16; i++ {
+			var n = m.secIndexBits
+			if i == 0 {
+				n--
+			}
+			idx2[i] = r.read(n)
+		}
+	}
+
+	for i := 0; i < 16; i++ {
+		var s = bc7Subset(m, partition, i)
+		var e0 = ep[s*2]
+		var e1 = ep[s*2+1]
+
+		var cw, aw int
+		if m.secIndexBits == 0 {
+			cw = bc7Weights[m.indexBits][idx[i]]
+			aw = cw
+		} else if indexSel == 0 {
+			cw = bc7Weights[m.indexBits][idx[i]]
+			aw = bc7Weights[m.secIndexBits][idx2[i]]
+		} else {
+			cw = bc7Weights[m.secIndexBits][idx2[i]]
+			aw = bc7Weights[m.indexBits][idx[i]]
+		}
+
+		var c = [4]uint8{
+			bc7Interpolate(e0[0], e1[0], cw),
+			bc7Interpolate(e0[1], e1[1], cw),
+			bc7Interpolate(e0[2], e1[2], cw),
+			bc7Interpolate(e0[3], e1[3], aw),
+		}
+
+		switch rotation {
+		case 1:
+			c[0], c[3] = c[3], c[0]
+		case 2:
+			c[1], c[3] = c[3], c[1]
+		case 3:
+			c[2], c[3] = c[3], c[2]
+		}
+
+		res[i] = color.NRGBA{c[0], c[1], c[2], c[3]}
+	}
+}
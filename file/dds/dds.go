@@ -0,0 +1,390 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package dds is a DirectDraw Surface image format decoder.
+package dds
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Errors
+var (
+	ErrBadFormat          = errors.New("dds: Invalid file format")
+	ErrInvalidPixelFormat = errors.New("dds: Pixel format not supported")
+)
+
+// Header constant for DDS files
+var Header = protocol.DString("DDS ")
+
+func init() {
+	image.RegisterFormat("dds", Header.String(), Decode, DecodeConfig)
+}
+
+// Pixel format flags
+const (
+	pfAlphaPixels = 0x00001
+	pfAlpha       = 0x00002
+	pfFourCC      = 0x00004
+	pfRGB         = 0x00040
+	pfLuminance   = 0x20000
+)
+
+// FourCC codes
+var (
+	fourCCDXT1 = protocol.DString("DXT1")
+	fourCCDXT2 = protocol.DString("DXT2")
+	fourCCDXT3 = protocol.DString("DXT3")
+	fourCCDXT4 = protocol.DString("DXT4")
+	fourCCDXT5 = protocol.DString("DXT5")
+	fourCCATI1 = protocol.DString("ATI1")
+	fourCCBC4U = protocol.DString("BC4U")
+	fourCCATI2 = protocol.DString("ATI2")
+	fourCCBC5U = protocol.DString("BC5U")
+	fourCCDX10 = protocol.DString("DX10")
+)
+
+// DXGI formats
+const (
+	dxgiR8G8B8A8     = 28
+	dxgiR8G8B8A8SRGB = 29
+	dxgiR8G8         = 49
+	dxgiR8           = 61
+	dxgiA8           = 65
+	dxgiBC1          = 71
+	dxgiBC1SRGB      = 72
+	dxgiBC2          = 74
+	dxgiBC2SRGB      = 75
+	dxgiBC3          = 77
+	dxgiBC3SRGB      = 78
+	dxgiBC4          = 80
+	dxgiBC5          = 83
+	dxgiB5G6R5       = 85
+	dxgiB5G5R5A1     = 86
+	dxgiB8G8R8A8     = 87
+	dxgiB8G8R8X8     = 88
+	dxgiB8G8R8A8SRGB = 91
+	dxgiB8G8R8X8SRGB = 93
+	dxgiBC7          = 98
+	dxgiBC7SRGB      = 99
+	dxgiB4G4R4A4     = 115
+	dxgiResTexture2D = 3
+)
+
+type format int
+
+const (
+	formatMask format = iota
+	formatBC1
+	formatBC2
+	formatBC3
+	formatBC4
+	formatBC5
+	formatBC7
+)
+
+func (f format) blockSize() int {
+	switch f {
+	case formatBC1, formatBC4:
+		return 8
+	case formatBC2, formatBC3, formatBC5, formatBC7:
+		return 16
+	default:
+		return 0
+	}
+}
+
+type header struct {
+	Width    uint32
+	Height   uint32
+	Mipmaps  uint32
+	Format   format
+	BitCount uint32
+	Flags    uint32
+	Mask     [4]uint32
+}
+
+func maskFormat(bits uint32, flags uint32, r, g, b, a uint32) *header {
+	return &header{Format: formatMask, BitCount: bits, Flags: flags, Mask: [4]uint32{r, g, b, a}}
+}
+
+func dxgiFormat(f uint32) (*header, error) {
+	switch f {
+	case dxgiBC1, dxgiBC1SRGB:
+		return &header{Format: formatBC1}, nil
+	case dxgiBC2, dxgiBC2SRGB:
+		return &header{Format: formatBC2}, nil
+	case dxgiBC3, dxgiBC3SRGB:
+		return &header{Format: formatBC3}, nil
+	case dxgiBC4:
+		return &header{Format: formatBC4}, nil
+	case dxgiBC5:
+		return &header{Format: formatBC5}, nil
+	case dxgiBC7, dxgiBC7SRGB:
+		return &header{Format: formatBC7}, nil
+	case dxgiR8G8B8A8, dxgiR8G8B8A8SRGB:
+		return maskFormat(32, pfRGB|pfAlphaPixels, 0x000000FF, 0x0000FF00, 0x00FF0000, 0xFF000000), nil
+	case dxgiB8G8R8A8, dxgiB8G8R8A8SRGB:
+		return maskFormat(32, pfRGB|pfAlphaPixels, 0x00FF0000, 0x0000FF00, 0x000000FF, 0xFF000000), nil
+	case dxgiB8G8R8X8, dxgiB8G8R8X8SRGB:
+		return maskFormat(32, pfRGB, 0x00FF0000, 0x0000FF00, 0x000000FF, 0), nil
+	case dxgiB5G6R5:
+		return maskFormat(16, pfRGB, 0xF800, 0x07E0, 0x001F, 0), nil
+	case dxgiB5G5R5A1:
+		return maskFormat(16, pfRGB|pfAlphaPixels, 0x7C00, 0x03E0, 0x001F, 0x8000), nil
+	case dxgiB4G4R4A4:
+		return maskFormat(16, pfRGB|pfAlphaPixels, 0x0F00, 0x00F0, 0x000F, 0xF000), nil
+	case dxgiR8G8:
+		return maskFormat(16, pfRGB, 0x00FF, 0xFF00, 0, 0), nil
+	case dxgiR8:
+		return maskFormat(8, pfRGB, 0xFF, 0, 0, 0), nil
+	case dxgiA8:
+		return maskFormat(8, pfAlpha, 0, 0, 0, 0xFF), nil
+	default:
+		return nil, ErrInvalidPixelFormat
+	}
+}
+
+func decodeHeader(b *protocol.Buffer) (*header, error) {
+	if b.Size() < 128 || b.ReadLEDString() != Header || b.ReadUInt32() != 124 {
+		return nil, ErrBadFormat
+	}
+
+	b.ReadUInt32() //flags
+	var height = b.ReadUInt32()
+	var width = b.ReadUInt32()
+	b.ReadUInt32() //pitchOrLinearSize
+	b.ReadUInt32() //depth
+	var mipmaps = b.ReadUInt32()
+	b.Skip(11 * 4) //reserved
+
+	if b.ReadUInt32() != 32 {
+		return nil, ErrBadFormat
+	}
+
+	var pfFlags = b.ReadUInt32()
+	var fourCC = b.ReadLEDString()
+	var bitCount = b.ReadUInt32()
+	var mask [4]uint32
+	for i := 0; i < len(mask); i++ {
+		mask[i] = b.ReadUInt32()
+	}
+	b.Skip(5 * 4) //caps, caps2, caps3, caps4, reserved
+
+	var h *header
+	var err error
+
+	switch {
+	case pfFlags&pfFourCC != 0:
+		switch fourCC {
+		case fourCCDXT1:
+			h = &header{Format: formatBC1}
+		case fourCCDXT2, fourCCDXT3:
+			h = &header{Format: formatBC2}
+		case fourCCDXT4, fourCCDXT5:
+			h = &header{Format: formatBC3}
+		case fourCCATI1, fourCCBC4U:
+			h = &header{Format: formatBC4}
+		case fourCCATI2, fourCCBC5U:
+			h = &header{Format: formatBC5}
+		case fourCCDX10:
+			if b.Size() < 20 {
+				return nil, ErrBadFormat
+			}
+			var dxgi = b.ReadUInt32()
+			var dim = b.ReadUInt32()
+			b.ReadUInt32() //miscFlag
+			b.ReadUInt32() //arraySize
+			b.ReadUInt32() //miscFlags2
+
+			if dim != dxgiResTexture2D {
+				return nil, ErrInvalidPixelFormat
+			}
+			if h, err = dxgiFormat(dxgi); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, ErrInvalidPixelFormat
+		}
+	case pfFlags&(pfRGB|pfLuminance|pfAlpha) != 0:
+		switch bitCount {
+		case 8, 16, 24, 32:
+		default:
+			return nil, ErrInvalidPixelFormat
+		}
+		h = maskFormat(bitCount, pfFlags, mask[0], mask[1], mask[2], mask[3])
+	default:
+		return nil, ErrInvalidPixelFormat
+	}
+
+	if width == 0 || height == 0 || width > 65535 || height > 65535 {
+		return nil, ErrBadFormat
+	}
+
+	h.Width = width
+	h.Height = height
+	h.Mipmaps = mipmaps
+	if h.Mipmaps == 0 {
+		h.Mipmaps = 1
+	}
+
+	return h, nil
+}
+
+func (h *header) mipmapBounds(level int) image.Rectangle {
+	var w = int(h.Width >> uint(level))
+	var ht = int(h.Height >> uint(level))
+	if w < 1 {
+		w = 1
+	}
+	if ht < 1 {
+		ht = 1
+	}
+	return image.Rect(0, 0, w, ht)
+}
+
+func (h *header) mipmapSize(rect image.Rectangle) int {
+	if bs := h.Format.blockSize(); bs != 0 {
+		return ((rect.Dx() + 3) / 4) * ((rect.Dy() + 3) / 4) * bs
+	}
+	return rect.Dx() * rect.Dy() * int(h.BitCount/8)
+}
+
+func (h *header) numMipmaps() int {
+	var n = 0
+	for n < int(h.Mipmaps) && (h.Width|h.Height)>>uint(n) != 0 {
+		n++
+	}
+	return n
+}
+
+func (h *header) decodeMipmap(b *protocol.Buffer, level int) (image.Image, error) {
+	var rect = h.mipmapBounds(level)
+	var size = h.mipmapSize(rect)
+	if b.Size() < size {
+		return nil, ErrBadFormat
+	}
+
+	var data = b.ReadBlob(size)
+	switch h.Format {
+	case formatMask:
+		return decodeMask(rect, data, h), nil
+	default:
+		return decodeBlocks(rect, data, h.Format), nil
+	}
+}
+
+func shiftOf(mask uint32) (uint32, uint32) {
+	if mask == 0 {
+		return 0, 0
+	}
+	var shift = uint32(0)
+	for mask&1 == 0 {
+		mask >>= 1
+		shift++
+	}
+	return shift, mask
+}
+
+func decodeMask(rect image.Rectangle, data []byte, h *header) image.Image {
+	var bpp = int(h.BitCount / 8)
+
+	var shift [4]uint32
+	var scale [4]uint32
+	for i := 0; i < 4; i++ {
+		shift[i], scale[i] = shiftOf(h.Mask[i])
+	}
+
+	var img = image.NewNRGBA(rect)
+	for i, n := 0, rect.Dx()*rect.Dy(); i < n; i++ {
+		var v uint32
+		for j := 0; j < bpp; j++ {
+			v |= uint32(data[i*bpp+j]) << (uint(j) * 8)
+		}
+
+		var c [4]uint8
+		for j := 0; j < 4; j++ {
+			if scale[j] == 0 {
+				continue
+			}
+			c[j] = uint8(((v & h.Mask[j]) >> shift[j]) * 255 / scale[j])
+		}
+
+		switch {
+		case h.Flags&pfLuminance != 0:
+			c[1], c[2] = c[0], c[0]
+		case h.Flags&(pfRGB|pfLuminance) == 0:
+			c[0], c[1], c[2] = 0, 0, 0
+		}
+		if h.Flags&(pfAlphaPixels|pfAlpha) == 0 || scale[3] == 0 {
+			c[3] = 0xFF
+		}
+
+		img.SetNRGBA(i%rect.Dx(), i/rect.Dx(), color.NRGBA{c[0], c[1], c[2], c[3]})
+	}
+
+	return img
+}
+
+// DecodeConfig returns the color model and dimensions of a DDS image without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	var b protocol.Buffer
+	if _, err := b.ReadSizeFrom(r, 148); err != nil && err != io.ErrUnexpectedEOF {
+		return image.Config{}, err
+	}
+
+	h, err := decodeHeader(&b)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      int(h.Width),
+		Height:     int(h.Height),
+	}, nil
+}
+
+// Decode a DDS image. Only take the first image if it's a mipmap.
+func Decode(r io.Reader) (image.Image, error) {
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, err
+	}
+
+	h, err := decodeHeader(&b)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.decodeMipmap(&b, 0)
+}
+
+// DecodeAll decodes a DDS image and returns all of its mipmap levels, starting with the full size image.
+// Only the first surface is decoded for cube maps and texture arrays.
+func DecodeAll(r io.Reader) ([]image.Image, error) {
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, err
+	}
+
+	h, err := decodeHeader(&b)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = make([]image.Image, h.numMipmaps())
+	for i := range res {
+		if res[i], err = h.decodeMipmap(&b, i); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,269 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package dds_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/dds"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+type pixelFormat struct {
+	flags  uint32
+	fourCC string
+	bits   uint32
+	mask   [4]uint32
+	dxgi   uint32
+}
+
+func makeDDS(w int, h int, pf pixelFormat, mipmaps ...[]byte) []byte {
+	var b protocol.Buffer
+	b.WriteLEDString(dds.Header)
+	b.WriteUInt32(124)
+	b.WriteUInt32(0x1007)
+	b.WriteUInt32(uint32(h))
+	b.WriteUInt32(uint32(w))
+	b.WriteUInt32(0)
+	b.WriteUInt32(0)
+	b.WriteUInt32(uint32(len(mipmaps)))
+	b.WriteBlob(make([]byte, 11*4))
+
+	b.WriteUInt32(32)
+	b.WriteUInt32(pf.flags)
+	b.WriteLEDString(protocol.DString(pf.fourCC))
+	b.WriteUInt32(pf.bits)
+	for _, m := range pf.mask {
+		b.WriteUInt32(m)
+	}
+	b.WriteBlob(make([]byte, 5*4))
+
+	if pf.fourCC == "DX10" {
+		b.WriteUInt32(pf.dxgi)
+		b.WriteUInt32(3)
+		b.WriteUInt32(0)
+		b.WriteUInt32(1)
+		b.WriteUInt32(0)
+	}
+
+	for _, m := range mipmaps {
+		b.WriteBlob(m)
+	}
+
+	return b.Bytes
+}
+
+// Little-endian bit writer for BC7 blocks
+type bitWriter struct {
+	blk [16]byte
+	pos uint
+}
+
+func (w *bitWriter) write(v uint, n uint) *bitWriter {
+	for i := uint(0); i < n; i++ {
+		if v&(1<<i) != 0 {
+			w.blk[(w.pos+i)/8] |= 1 << ((w.pos + i) % 8)
+		}
+	}
+	w.pos += n
+	return w
+}
+
+// Mode 6 block with gradient from black to red, index of pixel i is i
+func bc7Mode6() []byte {
+	var w bitWriter
+	w.write(1<<6, 7)
+	w.write(0, 7).write(127, 7) // R
+	w.write(0, 7).write(0, 7)   // G
+	w.write(0, 7).write(0, 7)   // B
+	w.write(127, 7).write(127, 7)
+	w.write(0, 1).write(0, 1) // P-bits
+	w.write(0, 3)
+	for i := uint(1); i < 16; i++ {
+		w.write(i, 4)
+	}
+	return w.blk[:]
+}
+
+// Mode 1 block with partition 13 (top half subset 0, bottom half subset 1), red/blue solid colors
+func bc7Mode1() []byte {
+	var w bitWriter
+	w.write(1<<1, 2)
+	w.write(13, 6)
+	w.write(63, 6).write(63, 6).write(0, 6).write(0, 6) // R
+	w.write(0, 6).write(0, 6).write(0, 6).write(0, 6)   // G
+	w.write(0, 6).write(0, 6).write(63, 6).write(63, 6) // B
+	w.write(1, 1).write(1, 1)                           // Shared p-bits
+	for i := 0; i < 16; i++ {
+		if i == 0 || i == 15 {
+			w.write(0, 2)
+		} else {
+			w.write(0, 3)
+		}
+	}
+	return w.blk[:]
+}
+
+func TestDecode(t *testing.T) {
+	var red = color.NRGBA{0xFF, 0x00, 0x00, 0xFF}
+
+	// c0=red, c1=blue, all indices 0 (red)
+	var bc1 = []byte{0x00, 0xF8, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x00}
+	// c0=blue, c1=red (3-color mode), all indices 3 (transparent)
+	var bc1a = []byte{0x1F, 0x00, 0x00, 0xF8, 0xFF, 0xFF, 0xFF, 0xFF}
+	// Explicit alpha 0x8
+	var bc2 = append(bytes.Repeat([]byte{0x88}, 8), bc1...)
+	// Interpolated, all indices 1 (value 0x40)
+	var bc4 = []byte{0xFF, 0x40, 0x49, 0x92, 0x24, 0x49, 0x92, 0x24}
+	var bc3 = append(append([]byte{}, bc4...), bc1...)
+	var bc5 = append(append([]byte{}, bc4...), 0x80, 0x80, 0, 0, 0, 0, 0, 0)
+
+	var files = []struct {
+		name    string
+		data    []byte
+		mipmaps int
+		bounds  image.Rectangle
+		px      map[image.Point]color.NRGBA
+	}{
+		{
+			"DXT1",
+			makeDDS(8, 4, pixelFormat{flags: 0x4, fourCC: "DXT1"}, bytes.Repeat(bc1, 2), bc1, bc1, bc1),
+			4,
+			image.Rect(0, 0, 8, 4),
+			map[image.Point]color.NRGBA{{0, 0}: red, {7, 3}: red},
+		},
+		{
+			"DXT1 (alpha)",
+			makeDDS(4, 4, pixelFormat{flags: 0x4, fourCC: "DXT1"}, bc1a),
+			1,
+			image.Rect(0, 0, 4, 4),
+			map[image.Point]color.NRGBA{{0, 0}: {}},
+		},
+		{
+			"DXT3",
+			makeDDS(3, 3, pixelFormat{flags: 0x4, fourCC: "DXT3"}, bc2, bc2),
+			2,
+			image.Rect(0, 0, 3, 3),
+			map[image.Point]color.NRGBA{{2, 2}: {0xFF, 0x00, 0x00, 0x88}},
+		},
+		{
+			"DXT5",
+			makeDDS(4, 4, pixelFormat{flags: 0x4, fourCC: "DXT5"}, bc3),
+			1,
+			image.Rect(0, 0, 4, 4),
+			map[image.Point]color.NRGBA{{1, 1}: {0xFF, 0x00, 0x00, 0x40}},
+		},
+		{
+			"BC4",
+			makeDDS(4, 4, pixelFormat{flags: 0x4, fourCC: "DX10", dxgi: 80}, bc4),
+			1,
+			image.Rect(0, 0, 4, 4),
+			map[image.Point]color.NRGBA{{3, 0}: {0x40, 0x40, 0x40, 0xFF}},
+		},
+		{
+			"BC5",
+			makeDDS(4, 4, pixelFormat{flags: 0x4, fourCC: "ATI2"}, bc5),
+			1,
+			image.Rect(0, 0, 4, 4),
+			map[image.Point]color.NRGBA{{0, 3}: {0x40, 0x80, 0x00, 0xFF}},
+		},
+		{
+			"BC7 (mode 6)",
+			makeDDS(4, 4, pixelFormat{flags: 0x4, fourCC: "DX10", dxgi: 98}, bc7Mode6()),
+			1,
+			image.Rect(0, 0, 4, 4),
+			map[image.Point]color.NRGBA{
+				{0, 0}: {0x00, 0x00, 0x00, 0xFE},
+				{1, 0}: {0x10, 0x00, 0x00, 0xFE},
+				{0, 2}: {0x87, 0x00, 0x00, 0xFE},
+				{3, 3}: {0xFE, 0x00, 0x00, 0xFE},
+			},
+		},
+		{
+			"BC7 (mode 1)",
+			makeDDS(4, 4, pixelFormat{flags: 0x4, fourCC: "DX10", dxgi: 99}, bc7Mode1()),
+			1,
+			image.Rect(0, 0, 4, 4),
+			map[image.Point]color.NRGBA{
+				{0, 0}: {0xFF, 0x02, 0x02, 0xFF},
+				{3, 1}: {0xFF, 0x02, 0x02, 0xFF},
+				{0, 2}: {0x02, 0x02, 0xFF, 0xFF},
+				{3, 3}: {0x02, 0x02, 0xFF, 0xFF},
+			},
+		},
+		{
+			"A8R8G8B8",
+			makeDDS(2, 1, pixelFormat{flags: 0x41, bits: 32, mask: [4]uint32{0xFF0000, 0xFF00, 0xFF, 0xFF000000}}, []byte{0x01, 0x02, 0x03, 0x04, 0, 0, 0, 0}, []byte{0, 0, 0, 0}),
+			2,
+			image.Rect(0, 0, 2, 1),
+			map[image.Point]color.NRGBA{{0, 0}: {0x03, 0x02, 0x01, 0x04}},
+		},
+		{
+			"R5G6B5",
+			makeDDS(1, 1, pixelFormat{flags: 0x40, bits: 16, mask: [4]uint32{0xF800, 0x07E0, 0x001F, 0}}, []byte{0x1F, 0xF8}),
+			1,
+			image.Rect(0, 0, 1, 1),
+			map[image.Point]color.NRGBA{{0, 0}: {0xFF, 0x00, 0xFF, 0xFF}},
+		},
+		{
+			"L8",
+			makeDDS(1, 1, pixelFormat{flags: 0x20000, bits: 8, mask: [4]uint32{0xFF, 0, 0, 0}}, []byte{0x7F}),
+			1,
+			image.Rect(0, 0, 1, 1),
+			map[image.Point]color.NRGBA{{0, 0}: {0x7F, 0x7F, 0x7F, 0xFF}},
+		},
+		{
+			"B8G8R8A8",
+			makeDDS(1, 1, pixelFormat{flags: 0x4, fourCC: "DX10", dxgi: 87}, []byte{0x01, 0x02, 0x03, 0x04}),
+			1,
+			image.Rect(0, 0, 1, 1),
+			map[image.Point]color.NRGBA{{0, 0}: {0x03, 0x02, 0x01, 0x04}},
+		},
+	}
+
+	for _, f := range files {
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(f.data))
+		if err != nil {
+			t.Fatal(f.name, err)
+		}
+		if format != "dds" || cfg.Width != f.bounds.Dx() || cfg.Height != f.bounds.Dy() {
+			t.Fatalf("%v: config mismatch %v %+v\n", f.name, format, cfg)
+		}
+
+		img, err := dds.DecodeAll(bytes.NewReader(f.data))
+		if err != nil {
+			t.Fatal(f.name, err)
+		}
+		if len(img) != f.mipmaps {
+			t.Fatalf("%v: expected %v mipmaps, got %v\n", f.name, f.mipmaps, len(img))
+		}
+		if img[0].Bounds() != f.bounds {
+			t.Fatalf("%v: bounds mismatch %v\n", f.name, img[0].Bounds())
+		}
+
+		for p, c := range f.px {
+			if px := color.NRGBAModel.Convert(img[0].At(p.X, p.Y)).(color.NRGBA); px != c {
+				t.Fatalf("%v: pixel %v mismatch %v != %v\n", f.name, p, px, c)
+			}
+		}
+
+		if _, err := dds.DecodeAll(bytes.NewReader(f.data[:len(f.data)-1])); err != dds.ErrBadFormat {
+			t.Fatalf("%v: expected ErrBadFormat for truncated input, got %v\n", f.name, err)
+		}
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	var data = makeDDS(4, 4, pixelFormat{flags: 0x4, fourCC: "DX10", dxgi: 95}, make([]byte, 16))
+	if _, err := dds.Decode(bytes.NewReader(data)); err != dds.ErrInvalidPixelFormat {
+		t.Fatal("Expected ErrInvalidPixelFormat, got", err)
+	}
+	if _, err := dds.Decode(bytes.NewReader(data[4:])); err != dds.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+}
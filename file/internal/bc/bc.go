@@ -0,0 +1,114 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package bc implements block compression (DXT/BCn) decoding shared by the blp and dds packages.
+package bc
+
+import (
+	"image"
+	"image/color"
+)
+
+// RGB565 converts a 16-bit 5:6:5 color to NRGBA
+func RGB565(c uint16) color.NRGBA {
+	var r = uint8(c>>11) & 0x1F
+	var g = uint8(c>>5) & 0x3F
+	var b = uint8(c) & 0x1F
+	return color.NRGBA{
+		R: r<<3 | r>>2,
+		G: g<<2 | g>>4,
+		B: b<<3 | b>>2,
+		A: 0xFF,
+	}
+}
+
+func lerp(a uint8, b uint8, wa int, wb int) uint8 {
+	return uint8((int(a)*wa + int(b)*wb) / (wa + wb))
+}
+
+// DecodeColorBlock decodes a 4x4 color block (DXT1/BC1) into res
+//
+// BC1 blocks support a 3-color mode with transparent black, otherwise the alpha values in res are left untouched.
+func DecodeColorBlock(blk []byte, bc1 bool, res *[16]color.NRGBA) {
+	var c0 = uint16(blk[0]) | uint16(blk[1])<<8
+	var c1 = uint16(blk[2]) | uint16(blk[3])<<8
+
+	var pal [4]color.NRGBA
+	pal[0] = RGB565(c0)
+	pal[1] = RGB565(c1)
+
+	if c0 > c1 || !bc1 {
+		pal[2] = color.NRGBA{lerp(pal[0].R, pal[1].R, 2, 1), lerp(pal[0].G, pal[1].G, 2, 1), lerp(pal[0].B, pal[1].B, 2, 1), 0xFF}
+		pal[3] = color.NRGBA{lerp(pal[0].R, pal[1].R, 1, 2), lerp(pal[0].G, pal[1].G, 1, 2), lerp(pal[0].B, pal[1].B, 1, 2), 0xFF}
+	} else {
+		pal[2] = color.NRGBA{lerp(pal[0].R, pal[1].R, 1, 1), lerp(pal[0].G, pal[1].G, 1, 1), lerp(pal[0].B, pal[1].B, 1, 1), 0xFF}
+		pal[3] = color.NRGBA{}
+	}
+
+	var idx = uint32(blk[4]) | uint32(blk[5])<<8 | uint32(blk[6])<<16 | uint32(blk[7])<<24
+	for i := 0; i < 16; i++ {
+		var a = res[i].A
+		res[i] = pal[(idx>>(uint(i)*2))&0x03]
+		if !bc1 {
+			res[i].A = a
+		}
+	}
+}
+
+// DecodeExplicitBlock decodes a 4x4 block of explicit 4-bit values (DXT3/BC2 alpha)
+func DecodeExplicitBlock(blk []byte, res *[16]uint8) {
+	for i := 0; i < 16; i++ {
+		var a = (blk[i/2] >> (uint(i%2) * 4)) & 0x0F
+		res[i] = a<<4 | a
+	}
+}
+
+// DecodeInterpolatedBlock decodes a 4x4 block of interpolated values (DXT5/BC3 alpha, BC4, BC5)
+func DecodeInterpolatedBlock(blk []byte, res *[16]uint8) {
+	var pal [8]uint8
+	pal[0] = blk[0]
+	pal[1] = blk[1]
+	if pal[0] > pal[1] {
+		for i := 1; i < 7; i++ {
+			pal[i+1] = lerp(pal[0], pal[1], 7-i, i)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			pal[i+1] = lerp(pal[0], pal[1], 5-i, i)
+		}
+		pal[6] = 0x00
+		pal[7] = 0xFF
+	}
+
+	var idx = uint64(blk[2]) | uint64(blk[3])<<8 | uint64(blk[4])<<16 | uint64(blk[5])<<24 | uint64(blk[6])<<32 | uint64(blk[7])<<40
+	for i := 0; i < 16; i++ {
+		res[i] = pal[(idx>>(uint(i)*3))&0x07]
+	}
+}
+
+// DecodeImage decodes rect from a sequence of 4x4 blocks of blockSize bytes using f
+//
+// data must contain at least ((w+3)/4)*((h+3)/4) blocks.
+func DecodeImage(rect image.Rectangle, data []byte, blockSize int, f func(blk []byte, res *[16]color.NRGBA)) *image.NRGBA {
+	var bw = (rect.Dx() + 3) / 4
+	var bh = (rect.Dy() + 3) / 4
+
+	var img = image.NewNRGBA(rect)
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			var px [16]color.NRGBA
+			f(data[(by*bw+bx)*blockSize:], &px)
+
+			for i := 0; i < 16; i++ {
+				var x = bx*4 + i%4
+				var y = by*4 + i/4
+				if x < rect.Max.X && y < rect.Max.Y {
+					img.SetNRGBA(x, y, px[i])
+				}
+			}
+		}
+	}
+
+	return img
+}
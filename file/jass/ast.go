@@ -0,0 +1,323 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node in the abstract syntax tree
+type Node interface {
+	Position() Pos
+}
+
+// Decl is a top-level declaration
+type Decl interface {
+	Node
+	declNode()
+}
+
+// Stmt is a statement in a function body
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Expr is an expression
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// File is a parsed JASS script, declarations are stored in source order
+type File struct {
+	Decls []Decl
+}
+
+// TypeDecl declares a new handle type, syntax: type <Name> extends <Base>
+type TypeDecl struct {
+	Pos
+	Name string
+	Base string
+}
+
+// GlobalsDecl is a block of global variables, syntax: globals ... endglobals
+type GlobalsDecl struct {
+	Pos
+	Vars []*VarDecl
+}
+
+// VarDecl declares a global or local variable, syntax: [constant] <Type> [array] <Name> [= <Init>]
+type VarDecl struct {
+	Pos
+	Constant bool
+	Type     string
+	Array    bool
+	Name     string
+	Init     Expr
+}
+
+// Param of a function
+type Param struct {
+	Type string
+	Name string
+}
+
+// Signature of a function, Returns is "nothing" for functions without return value
+type Signature struct {
+	Name    string
+	Params  []Param
+	Returns string
+}
+
+// NativeDecl declares a native function, syntax: [constant] native <Signature>
+type NativeDecl struct {
+	Pos
+	Constant bool
+	Signature
+}
+
+// FuncDecl defines a function, syntax: [constant] function <Signature> <Locals> <Body> endfunction
+type FuncDecl struct {
+	Pos
+	Constant bool
+	Signature
+	Locals []*VarDecl
+	Body   []Stmt
+}
+
+func (*TypeDecl) declNode()    {}
+func (*GlobalsDecl) declNode() {}
+func (*NativeDecl) declNode()  {}
+func (*FuncDecl) declNode()    {}
+
+// SetStmt assigns a value to a variable, syntax: set <Name>[<Index>] = <Value>
+type SetStmt struct {
+	Pos
+	Name  string
+	Index Expr
+	Value Expr
+}
+
+// CallStmt calls a function and discards the result, syntax: call <Call>
+type CallStmt struct {
+	Pos
+	Call *CallExpr
+}
+
+// ElseIfClause of an IfStmt
+type ElseIfClause struct {
+	Pos
+	Cond Expr
+	Body []Stmt
+}
+
+// ElseClause of an IfStmt
+type ElseClause struct {
+	Pos
+	Body []Stmt
+}
+
+// IfStmt is a conditional statement, syntax: if <Cond> then <Body> [elseif ...] [else ...] endif
+type IfStmt struct {
+	Pos
+	Cond   Expr
+	Body   []Stmt
+	ElseIf []*ElseIfClause
+	Else   *ElseClause
+}
+
+// LoopStmt repeats Body until an exitwhen condition is met, syntax: loop <Body> endloop
+type LoopStmt struct {
+	Pos
+	Body []Stmt
+}
+
+// ExitWhenStmt breaks out of a loop if Cond is true, syntax: exitwhen <Cond>
+type ExitWhenStmt struct {
+	Pos
+	Cond Expr
+}
+
+// ReturnStmt returns from a function, Value is nil if no value is returned, syntax: return [<Value>]
+type ReturnStmt struct {
+	Pos
+	Value Expr
+}
+
+// DebugStmt is a statement that is only executed in debug mode, syntax: debug <Stmt>
+type DebugStmt struct {
+	Pos
+	Stmt Stmt
+}
+
+func (*SetStmt) stmtNode()      {}
+func (*CallStmt) stmtNode()     {}
+func (*IfStmt) stmtNode()       {}
+func (*LoopStmt) stmtNode()     {}
+func (*ExitWhenStmt) stmtNode() {}
+func (*ReturnStmt) stmtNode()   {}
+func (*DebugStmt) stmtNode()    {}
+
+// Ident refers to a variable
+type Ident struct {
+	Pos
+	Name string
+}
+
+// IndexExpr refers to an array element, syntax: <Name>[<Index>]
+type IndexExpr struct {
+	Pos
+	Name  string
+	Index Expr
+}
+
+// CallExpr calls a function, syntax: <Name>(<Args>)
+type CallExpr struct {
+	Pos
+	Name string
+	Args []Expr
+}
+
+// FuncRef refers to a function (code value), syntax: function <Name>
+type FuncRef struct {
+	Pos
+	Name string
+}
+
+// IntLit is an integer literal (decimal, octal, hexadecimal or rawcode), Raw is the literal as found in the source
+type IntLit struct {
+	Pos
+	Raw string
+}
+
+// RealLit is a real literal, Raw is the literal as found in the source
+type RealLit struct {
+	Pos
+	Raw string
+}
+
+// StringLit is a string literal, Raw is the quoted literal as found in the source
+type StringLit struct {
+	Pos
+	Raw string
+}
+
+// BoolLit is either true or false
+type BoolLit struct {
+	Pos
+	Value bool
+}
+
+// NullLit is the null handle
+type NullLit struct {
+	Pos
+}
+
+// UnaryExpr applies Op (Not, Plus or Minus) to X
+type UnaryExpr struct {
+	Pos
+	Op Token
+	X  Expr
+}
+
+// BinaryExpr applies Op to X and Y
+type BinaryExpr struct {
+	Pos
+	Op Token
+	X  Expr
+	Y  Expr
+}
+
+// ParenExpr is a parenthesized expression
+type ParenExpr struct {
+	Pos
+	X Expr
+}
+
+func (*Ident) exprNode()      {}
+func (*IndexExpr) exprNode()  {}
+func (*CallExpr) exprNode()   {}
+func (*FuncRef) exprNode()    {}
+func (*IntLit) exprNode()     {}
+func (*RealLit) exprNode()    {}
+func (*StringLit) exprNode()  {}
+func (*BoolLit) exprNode()    {}
+func (*NullLit) exprNode()    {}
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+func (*ParenExpr) exprNode()  {}
+
+// Rawcode returns true if the literal is a rawcode ('A' or 'Abcd')
+func (l *IntLit) Rawcode() bool {
+	return strings.HasPrefix(l.Raw, "'")
+}
+
+// Value of integer literal, overflowing values wrap around like they do in game
+func (l *IntLit) Value() (int32, error) {
+	var s = l.Raw
+	switch {
+	case strings.HasPrefix(s, "'"):
+		var v uint32
+		for _, c := range []byte(unescape(s[1 : len(s)-1])) {
+			v = v<<8 | uint32(c)
+		}
+		return int32(v), nil
+	case strings.HasPrefix(s, "$"):
+		v, err := strconv.ParseUint(s[1:], 16, 64)
+		return int32(v), err
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		v, err := strconv.ParseUint(s[2:], 16, 64)
+		return int32(v), err
+	case len(s) > 1 && s[0] == '0':
+		v, err := strconv.ParseUint(s[1:], 8, 64)
+		return int32(v), err
+	default:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return int32(v), err
+	}
+}
+
+// Value of real literal
+func (l *RealLit) Value() (float32, error) {
+	v, err := strconv.ParseFloat(l.Raw, 32)
+	return float32(v), err
+}
+
+// Value of string literal with escape sequences resolved
+func (l *StringLit) Value() string {
+	return unescape(l.Raw[1 : len(l.Raw)-1])
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
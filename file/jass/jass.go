@@ -0,0 +1,224 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package jass implements a lexer, parser and printer for JASS scripts.
+package jass
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Standard library script file names
+const (
+	CommonJ   = "Scripts\\common.j"
+	BlizzardJ = "Scripts\\blizzard.j"
+)
+
+// Decode reads and parses a JASS script from r
+func Decode(r io.Reader) (*File, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(src)
+}
+
+// Open and parse fileName from stor
+func Open(stor *fs.Storage, fileName string) (*File, error) {
+	f, err := stor.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(f)
+}
+
+// OpenStd opens and parses common.j and blizzard.j from stor
+func OpenStd(stor *fs.Storage) (common *File, blizzard *File, err error) {
+	if common, err = Open(stor, CommonJ); err != nil {
+		return nil, nil, err
+	}
+	if blizzard, err = Open(stor, BlizzardJ); err != nil {
+		return nil, nil, err
+	}
+	return common, blizzard, nil
+}
+
+// Inspect traverses the AST in depth-first order, children of n are skipped if fn returns false
+func Inspect(n Node, fn func(Node) bool) {
+	if n == nil || !fn(n) {
+		return
+	}
+
+	switch n := n.(type) {
+	case *File:
+		for _, d := range n.Decls {
+			Inspect(d, fn)
+		}
+	case *GlobalsDecl:
+		for _, v := range n.Vars {
+			Inspect(v, fn)
+		}
+	case *VarDecl:
+		if n.Init != nil {
+			Inspect(n.Init, fn)
+		}
+	case *FuncDecl:
+		for _, v := range n.Locals {
+			Inspect(v, fn)
+		}
+		inspectStmts(n.Body, fn)
+	case *SetStmt:
+		if n.Index != nil {
+			Inspect(n.Index, fn)
+		}
+		Inspect(n.Value, fn)
+	case *CallStmt:
+		Inspect(n.Call, fn)
+	case *IfStmt:
+		Inspect(n.Cond, fn)
+		inspectStmts(n.Body, fn)
+		for _, e := range n.ElseIf {
+			Inspect(e, fn)
+		}
+		if n.Else != nil {
+			Inspect(n.Else, fn)
+		}
+	case *ElseIfClause:
+		Inspect(n.Cond, fn)
+		inspectStmts(n.Body, fn)
+	case *ElseClause:
+		inspectStmts(n.Body, fn)
+	case *LoopStmt:
+		inspectStmts(n.Body, fn)
+	case *ExitWhenStmt:
+		Inspect(n.Cond, fn)
+	case *ReturnStmt:
+		if n.Value != nil {
+			Inspect(n.Value, fn)
+		}
+	case *DebugStmt:
+		Inspect(n.Stmt, fn)
+	case *IndexExpr:
+		Inspect(n.Index, fn)
+	case *CallExpr:
+		for _, a := range n.Args {
+			Inspect(a, fn)
+		}
+	case *UnaryExpr:
+		Inspect(n.X, fn)
+	case *BinaryExpr:
+		Inspect(n.X, fn)
+		Inspect(n.Y, fn)
+	case *ParenExpr:
+		Inspect(n.X, fn)
+	}
+}
+
+func inspectStmts(s []Stmt, fn func(Node) bool) {
+	for _, v := range s {
+		Inspect(v, fn)
+	}
+}
+
+// Position of the file (always 1:1)
+func (f *File) Position() Pos {
+	return Pos{Line: 1, Col: 1}
+}
+
+// Natives declared in f
+func (f *File) Natives() map[string]*NativeDecl {
+	var res = map[string]*NativeDecl{}
+	for _, d := range f.Decls {
+		if n, ok := d.(*NativeDecl); ok {
+			res[n.Name] = n
+		}
+	}
+	return res
+}
+
+// Functions defined in f
+func (f *File) Functions() map[string]*FuncDecl {
+	var res = map[string]*FuncDecl{}
+	for _, d := range f.Decls {
+		if n, ok := d.(*FuncDecl); ok {
+			res[n.Name] = n
+		}
+	}
+	return res
+}
+
+// Globals declared in f
+func (f *File) Globals() map[string]*VarDecl {
+	var res = map[string]*VarDecl{}
+	for _, d := range f.Decls {
+		if g, ok := d.(*GlobalsDecl); ok {
+			for _, v := range g.Vars {
+				res[v.Name] = v
+			}
+		}
+	}
+	return res
+}
+
+// Calls counts the number of calls (and function references) per function name
+func (f *File) Calls() map[string]int {
+	var res = map[string]int{}
+	Inspect(f, func(n Node) bool {
+		switch n := n.(type) {
+		case *CallExpr:
+			res[n.Name]++
+		case *FuncRef:
+			res[n.Name]++
+		}
+		return true
+	})
+	return res
+}
+
+// CalledNatives returns the sorted names of natives declared in f or lib (i.e. common.j) that are called in f
+func (f *File) CalledNatives(lib ...*File) []string {
+	var natives = f.Natives()
+	for _, l := range lib {
+		for k, v := range l.Natives() {
+			natives[k] = v
+		}
+	}
+
+	var res []string
+	for name := range f.Calls() {
+		if natives[name] != nil {
+			res = append(res, name)
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// Rawcodes returns the sorted four character rawcodes (i.e. 'hfoo') referenced in f
+func (f *File) Rawcodes() []protocol.DWordString {
+	var set = map[protocol.DWordString]struct{}{}
+	Inspect(f, func(n Node) bool {
+		if l, ok := n.(*IntLit); ok && l.Rawcode() {
+			if v := unescape(l.Raw[1 : len(l.Raw)-1]); len(v) == 4 {
+				set[protocol.DString(v)] = struct{}{}
+			}
+		}
+		return true
+	})
+
+	var res = make([]protocol.DWordString, 0, len(set))
+	for k := range set {
+		res = append(res, k)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].String() < res[j].String() })
+	return res
+}
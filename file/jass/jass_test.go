@@ -0,0 +1,235 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/file/jass"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+var commonJ = `// Comment
+type agent extends handle
+type unit  extends widget
+
+globals
+    constant integer bj_MAX_PLAYERS = 12 // Trailing comment
+endglobals
+
+native GetTriggerUnit takes nothing returns unit
+constant native GetUnitTypeId takes unit whichUnit returns integer
+native DisplayTextToPlayer takes player toPlayer, real x, real y, string message returns nothing
+native Player takes integer number returns player
+`
+
+var blizzardJ = `globals
+    integer array bj_counts
+    string bj_msg = "Hello \"world\"\n"
+endglobals
+
+function IsFootman takes unit u returns boolean
+    return GetUnitTypeId(u) == 'hfoo' or GetUnitTypeId(u)=='hkni'
+endfunction
+
+function Count takes integer n returns integer
+    local integer i = 0
+    local integer s
+    set s = 0
+    loop
+        exitwhen i >= n
+        if not IsFootman(GetTriggerUnit()) and i != $0A then
+            set s = s + (i - 1) * 2
+        elseif i > 0x10 then
+            debug call DisplayTextToPlayer(Player(0), 0., .5, bj_msg)
+        else
+            set bj_counts[i] = -i
+        endif
+        set i = i + 1
+    endloop
+    return s
+endfunction
+`
+
+func TestOpen(t *testing.T) {
+	var dir = t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "Scripts"), 0755)
+	os.WriteFile(filepath.Join(dir, "Scripts", "common.j"), []byte(commonJ), 0644)
+	os.WriteFile(filepath.Join(dir, "Scripts", "blizzard.j"), []byte(blizzardJ), 0644)
+
+	var stor = fs.Open(dir)
+	defer stor.Close()
+
+	common, blizzard, err := jass.OpenStd(stor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(common.Decls) != 7 || len(common.Natives()) != 4 || len(common.Globals()) != 1 {
+		t.Fatal("common.j mismatch", common)
+	}
+	if n := common.Natives()["GetUnitTypeId"]; !n.Constant || n.Returns != "integer" || !reflect.DeepEqual(n.Params, []jass.Param{{Type: "unit", Name: "whichUnit"}}) {
+		t.Fatal("Native mismatch", n)
+	}
+
+	var count = blizzard.Functions()["Count"]
+	if count == nil || count.Position() != (jass.Pos{Line: 10, Col: 1}) || len(count.Locals) != 2 || len(count.Body) != 3 {
+		t.Fatal("Function mismatch", count)
+	}
+	if g := blizzard.Globals()["bj_msg"]; g.Init.(*jass.StringLit).Value() != "Hello \"world\"\n" {
+		t.Fatal("String mismatch", g.Init)
+	}
+
+	if n := blizzard.CalledNatives(common); !reflect.DeepEqual(n, []string{"DisplayTextToPlayer", "GetTriggerUnit", "GetUnitTypeId", "Player"}) {
+		t.Fatal("CalledNatives mismatch", n)
+	}
+	if r := blizzard.Rawcodes(); !reflect.DeepEqual(r, []protocol.DWordString{protocol.DString("hfoo"), protocol.DString("hkni")}) {
+		t.Fatal("Rawcodes mismatch", r)
+	}
+	if c := blizzard.Calls(); c["IsFootman"] != 1 || c["GetUnitTypeId"] != 2 {
+		t.Fatal("Calls mismatch", c)
+	}
+
+	for _, f := range []*jass.File{common, blizzard} {
+		var src = f.String()
+		f2, err := jass.Parse([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f2.String() != src {
+			t.Fatal("Round trip mismatch", src)
+		}
+
+		// Printed source may be laid out differently, so compare without positions
+		clearPos(reflect.ValueOf(f))
+		clearPos(reflect.ValueOf(f2))
+		if !reflect.DeepEqual(f, f2) {
+			t.Fatal("AST round trip mismatch", src)
+		}
+	}
+
+	bom, err := jass.Parse(append([]byte("\ufeff"), commonJ...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bom.Decls) != len(common.Decls) || bom.Decls[0].Position() != (jass.Pos{Line: 2, Col: 1}) {
+		t.Fatal("BOM mismatch", bom)
+	}
+
+	if _, err := jass.Open(stor, "Scripts\\war3map.j"); !os.IsNotExist(err) {
+		t.Fatal("Expected ErrNotExist, got", err)
+	}
+}
+
+// clearPos zeroes every jass.Pos reachable from v
+func clearPos(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearPos(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearPos(v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			clearPos(v.MapIndex(k))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(jass.Pos{}) {
+			if v.CanSet() {
+				v.Set(reflect.Zero(v.Type()))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				clearPos(v.Field(i))
+			}
+		}
+	}
+}
+
+func TestExpr(t *testing.T) {
+	var exprs = []struct {
+		src string
+		out string
+	}{
+		{"1 + 2 * 3", "1 + 2 * 3"},
+		{"(1 + 2) * 3", "(1 + 2) * 3"},
+		{"a or b and c", "a or b and c"},
+		{"not a==b", "not a == b"},
+		{"-x[i]+f(1,2)", "-x[i] + f(1, 2)"},
+		{"1-2-3", "1 - 2 - 3"},
+		{"function F", "function F"},
+	}
+
+	for _, e := range exprs {
+		f, err := jass.Parse([]byte("globals\ninteger x = " + e.src + "\nendglobals"))
+		if err != nil {
+			t.Fatal(e.src, err)
+		}
+		if s := f.String(); s != "globals\n    integer x = "+e.out+"\nendglobals\n" {
+			t.Fatalf("Expression mismatch %q", s)
+		}
+	}
+
+	// Left associativity and precedence
+	var b = &jass.BinaryExpr{
+		Op: jass.Minus,
+		X:  &jass.IntLit{Raw: "1"},
+		Y: &jass.BinaryExpr{
+			Op: jass.Minus,
+			X:  &jass.IntLit{Raw: "2"},
+			Y:  &jass.IntLit{Raw: "3"},
+		},
+	}
+	var f = jass.File{Decls: []jass.Decl{&jass.GlobalsDecl{Vars: []*jass.VarDecl{{Type: "integer", Name: "x", Init: b}}}}}
+	if s := f.String(); s != "globals\n    integer x = 1 - (2 - 3)\nendglobals\n" {
+		t.Fatalf("Printer mismatch %q", s)
+	}
+
+	var ints = map[string]int32{
+		"42":         42,
+		"052":        42,
+		"$2A":        42,
+		"0x2a":       42,
+		"'*'":        42,
+		"'hfoo'":     0x68666F6F,
+		"4294967295": -1,
+	}
+	for raw, v := range ints {
+		if n, err := (&jass.IntLit{Raw: raw}).Value(); err != nil || n != v {
+			t.Fatal("Value mismatch", raw, n, err)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var errs = []struct {
+		src string
+		pos jass.Pos
+	}{
+		{"globals\n    integer = 1\nendglobals", jass.Pos{Line: 2, Col: 13}},
+		{"function F takes nothing returns nothing\n    call F(\nendfunction", jass.Pos{Line: 2, Col: 12}},
+		{"function F takes nothing returns nothing\n    set x = 1\n    local integer y\nendfunction", jass.Pos{Line: 3, Col: 5}},
+		{"function F takes nothing returns nothing\n    if x then\nendfunction", jass.Pos{Line: 3, Col: 1}},
+		{"native F takes nothing returns nothing\nset x = 1", jass.Pos{Line: 2, Col: 1}},
+		{"globals\n    string s = \"abc\nendglobals", jass.Pos{Line: 2, Col: 16}},
+		{"globals\n    integer x = 1 ! 2\nendglobals", jass.Pos{Line: 2, Col: 19}},
+	}
+
+	for _, e := range errs {
+		_, err := jass.Parse([]byte(e.src))
+		if jerr, ok := err.(*jass.Error); !ok || jerr.Pos != e.pos {
+			t.Fatalf("Expected error at %v, got %v", e.pos, err)
+		}
+	}
+}
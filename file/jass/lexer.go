@@ -0,0 +1,382 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Token enum
+type Token uint8
+
+// Tokens
+const (
+	EOF Token = iota
+	Newline
+	Identifier
+	IntLiteral
+	RealLiteral
+	StringLiteral
+	RawcodeLiteral
+
+	// Operators
+	Assign       // =
+	Equal        // ==
+	NotEqual     // !=
+	Less         // <
+	LessEqual    // <=
+	Greater      // >
+	GreaterEqual // >=
+	Plus         // +
+	Minus        // -
+	Mul          // *
+	Div          // /
+	LParen       // (
+	RParen       // )
+	LBracket     // [
+	RBracket     // ]
+	Comma        // ,
+
+	// Keywords
+	keywordBegin
+	Type
+	Extends
+	Globals
+	EndGlobals
+	Constant
+	Native
+	Takes
+	Returns
+	Nothing
+	Function
+	EndFunction
+	Local
+	Array
+	Set
+	Call
+	If
+	Then
+	ElseIf
+	Else
+	EndIf
+	Loop
+	EndLoop
+	ExitWhen
+	Return
+	Debug
+	And
+	Or
+	Not
+	True
+	False
+	Null
+	keywordEnd
+)
+
+var tokens = [...]string{
+	EOF:            "EOF",
+	Newline:        "newline",
+	Identifier:     "identifier",
+	IntLiteral:     "integer",
+	RealLiteral:    "real",
+	StringLiteral:  "string",
+	RawcodeLiteral: "rawcode",
+
+	Assign:       "=",
+	Equal:        "==",
+	NotEqual:     "!=",
+	Less:         "<",
+	LessEqual:    "<=",
+	Greater:      ">",
+	GreaterEqual: ">=",
+	Plus:         "+",
+	Minus:        "-",
+	Mul:          "*",
+	Div:          "/",
+	LParen:       "(",
+	RParen:       ")",
+	LBracket:     "[",
+	RBracket:     "]",
+	Comma:        ",",
+
+	Type:        "type",
+	Extends:     "extends",
+	Globals:     "globals",
+	EndGlobals:  "endglobals",
+	Constant:    "constant",
+	Native:      "native",
+	Takes:       "takes",
+	Returns:     "returns",
+	Nothing:     "nothing",
+	Function:    "function",
+	EndFunction: "endfunction",
+	Local:       "local",
+	Array:       "array",
+	Set:         "set",
+	Call:        "call",
+	If:          "if",
+	Then:        "then",
+	ElseIf:      "elseif",
+	Else:        "else",
+	EndIf:       "endif",
+	Loop:        "loop",
+	EndLoop:     "endloop",
+	ExitWhen:    "exitwhen",
+	Return:      "return",
+	Debug:       "debug",
+	And:         "and",
+	Or:          "or",
+	Not:         "not",
+	True:        "true",
+	False:       "false",
+	Null:        "null",
+}
+
+var keywords = map[string]Token{}
+
+func init() {
+	for t := keywordBegin + 1; t < keywordEnd; t++ {
+		keywords[tokens[t]] = t
+	}
+}
+
+func (t Token) String() string {
+	if int(t) < len(tokens) && tokens[t] != "" {
+		return tokens[t]
+	}
+	return fmt.Sprintf("Token(0x%02X)", uint8(t))
+}
+
+// Keyword returns true if t is a reserved word
+func (t Token) Keyword() bool {
+	return t > keywordBegin && t < keywordEnd
+}
+
+// Precedence of binary operator t, 0 if t is not a binary operator
+func (t Token) Precedence() int {
+	switch t {
+	case Or:
+		return 1
+	case And:
+		return 2
+	case Equal, NotEqual, Less, LessEqual, Greater, GreaterEqual:
+		return 3
+	case Plus, Minus:
+		return 4
+	case Mul, Div:
+		return 5
+	default:
+		return 0
+	}
+}
+
+// Pos is a position in the source file (1-based)
+type Pos struct {
+	Line int
+	Col  int
+}
+
+// Position returns p (so that Pos can be embedded in AST nodes)
+func (p Pos) Position() Pos {
+	return p
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// Error is a syntax error at a position in the source file
+type Error struct {
+	Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jass: %s: %s", e.Pos, e.Msg)
+}
+
+// Lexer splits JASS source into tokens, comments are skipped
+type Lexer struct {
+	src  []byte
+	off  int
+	line int
+	col  int
+}
+
+// UTF-8 byte order mark, written by some editors at the start of a file
+var bom = []byte("\ufeff")
+
+// NewLexer creates a lexer for src, a leading byte order mark is skipped
+func NewLexer(src []byte) *Lexer {
+	return &Lexer{src: bytes.TrimPrefix(src, bom), line: 1, col: 1}
+}
+
+func (l *Lexer) peek(n int) byte {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+func (l *Lexer) advance() {
+	if l.src[l.off] == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	l.off++
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// Next token, returns the token type, its position and literal text
+func (l *Lexer) Next() (Token, Pos, string, error) {
+	for l.off < len(l.src) {
+		var c = l.src[l.off]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f' {
+			l.advance()
+		} else if c == '/' && l.peek(1) == '/' {
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance()
+			}
+		} else {
+			break
+		}
+	}
+
+	var pos = Pos{Line: l.line, Col: l.col}
+	if l.off >= len(l.src) {
+		return EOF, pos, "", nil
+	}
+
+	var start = l.off
+	var c = l.src[l.off]
+
+	switch {
+	case c == '\n':
+		l.advance()
+		return Newline, pos, "\n", nil
+
+	case isLetter(c):
+		for l.off < len(l.src) && (isLetter(l.src[l.off]) || isDigit(l.src[l.off]) || l.src[l.off] == '_') {
+			l.advance()
+		}
+		var lit = string(l.src[start:l.off])
+		if t, ok := keywords[lit]; ok {
+			return t, pos, lit, nil
+		}
+		return Identifier, pos, lit, nil
+
+	case c == '$' || (c == '0' && (l.peek(1) == 'x' || l.peek(1) == 'X')):
+		l.advance()
+		if c == '0' {
+			l.advance()
+		}
+		if !isHexDigit(l.peek(0)) {
+			return EOF, pos, "", &Error{Pos: pos, Msg: "invalid hexadecimal literal"}
+		}
+		for l.off < len(l.src) && isHexDigit(l.src[l.off]) {
+			l.advance()
+		}
+		return IntLiteral, pos, string(l.src[start:l.off]), nil
+
+	case isDigit(c) || (c == '.' && isDigit(l.peek(1))):
+		var tok = IntLiteral
+		for l.off < len(l.src) && (isDigit(l.src[l.off]) || l.src[l.off] == '.') {
+			if l.src[l.off] == '.' {
+				if tok == RealLiteral {
+					break
+				}
+				tok = RealLiteral
+			}
+			l.advance()
+		}
+		return tok, pos, string(l.src[start:l.off]), nil
+
+	case c == '"' || c == '\'':
+		l.advance()
+		for {
+			if l.off >= len(l.src) {
+				return EOF, pos, "", &Error{Pos: pos, Msg: "unterminated literal"}
+			}
+			var d = l.src[l.off]
+			l.advance()
+			if d == c {
+				break
+			}
+			if d == '\\' && l.off < len(l.src) {
+				l.advance()
+			}
+		}
+		if c == '\'' {
+			return RawcodeLiteral, pos, string(l.src[start:l.off]), nil
+		}
+		return StringLiteral, pos, string(l.src[start:l.off]), nil
+	}
+
+	l.advance()
+
+	var tok Token
+	switch c {
+	case '=':
+		tok = Assign
+		if l.peek(0) == '=' {
+			l.advance()
+			tok = Equal
+		}
+	case '!':
+		if l.peek(0) != '=' {
+			return EOF, pos, "", &Error{Pos: pos, Msg: "unexpected character '!'"}
+		}
+		l.advance()
+		tok = NotEqual
+	case '<':
+		tok = Less
+		if l.peek(0) == '=' {
+			l.advance()
+			tok = LessEqual
+		}
+	case '>':
+		tok = Greater
+		if l.peek(0) == '=' {
+			l.advance()
+			tok = GreaterEqual
+		}
+	case '+':
+		tok = Plus
+	case '-':
+		tok = Minus
+	case '*':
+		tok = Mul
+	case '/':
+		tok = Div
+	case '(':
+		tok = LParen
+	case ')':
+		tok = RParen
+	case '[':
+		tok = LBracket
+	case ']':
+		tok = RBracket
+	case ',':
+		tok = Comma
+	default:
+		return EOF, pos, "", &Error{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", c)}
+	}
+
+	return tok, pos, string(l.src[start:l.off]), nil
+}
@@ -0,0 +1,378 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+import (
+	"fmt"
+)
+
+type parser struct {
+	lex *Lexer
+	tok Token
+	pos Pos
+	lit string
+}
+
+func (p *parser) next() {
+	tok, pos, lit, err := p.lex.Next()
+	if err != nil {
+		panic(err)
+	}
+	p.tok, p.pos, p.lit = tok, pos, lit
+}
+
+func (p *parser) fail(format string, a ...interface{}) {
+	panic(&Error{Pos: p.pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+func (p *parser) unexpected(what string) {
+	if p.tok == Identifier {
+		p.fail("expected %s, found identifier %q", what, p.lit)
+	}
+	p.fail("expected %s, found %q", what, p.tok.String())
+}
+
+func (p *parser) expect(tok Token) Pos {
+	var pos = p.pos
+	if p.tok != tok {
+		p.unexpected(fmt.Sprintf("%q", tok.String()))
+	}
+	p.next()
+	return pos
+}
+
+func (p *parser) got(tok Token) bool {
+	if p.tok != tok {
+		return false
+	}
+	p.next()
+	return true
+}
+
+func (p *parser) ident() string {
+	var lit = p.lit
+	if p.tok != Identifier {
+		p.unexpected("identifier")
+	}
+	p.next()
+	return lit
+}
+
+func (p *parser) endOfLine() {
+	if p.tok == EOF {
+		return
+	}
+	p.expect(Newline)
+	p.skipNewlines()
+}
+
+func (p *parser) skipNewlines() {
+	for p.tok == Newline {
+		p.next()
+	}
+}
+
+func (p *parser) file() *File {
+	var f File
+
+	p.skipNewlines()
+	for p.tok != EOF {
+		f.Decls = append(f.Decls, p.decl())
+	}
+
+	return &f
+}
+
+func (p *parser) decl() Decl {
+	var pos = p.pos
+	switch p.tok {
+	case Type:
+		p.next()
+		var d = TypeDecl{Pos: pos, Name: p.ident()}
+		p.expect(Extends)
+		d.Base = p.ident()
+		p.endOfLine()
+		return &d
+	case Globals:
+		p.next()
+		p.endOfLine()
+		var d = GlobalsDecl{Pos: pos}
+		for p.tok != EndGlobals {
+			d.Vars = append(d.Vars, p.varDecl(true))
+			p.endOfLine()
+		}
+		p.next()
+		p.endOfLine()
+		return &d
+	case Constant, Native, Function:
+		var constant = p.got(Constant)
+		if p.got(Native) {
+			var d = NativeDecl{Pos: pos, Constant: constant, Signature: p.signature()}
+			p.endOfLine()
+			return &d
+		}
+
+		p.expect(Function)
+		var d = FuncDecl{Pos: pos, Constant: constant, Signature: p.signature()}
+		p.endOfLine()
+		for p.tok == Local {
+			var lpos = p.pos
+			p.next()
+			var v = p.varDecl(false)
+			v.Pos = lpos
+			d.Locals = append(d.Locals, v)
+			p.endOfLine()
+		}
+		d.Body = p.stmts(EndFunction)
+		p.expect(EndFunction)
+		p.endOfLine()
+		return &d
+	default:
+		p.unexpected("declaration")
+		return nil
+	}
+}
+
+func (p *parser) signature() Signature {
+	var s = Signature{Name: p.ident()}
+
+	p.expect(Takes)
+	if !p.got(Nothing) {
+		for {
+			var t = p.ident()
+			s.Params = append(s.Params, Param{Type: t, Name: p.ident()})
+			if !p.got(Comma) {
+				break
+			}
+		}
+	}
+
+	p.expect(Returns)
+	if p.got(Nothing) {
+		s.Returns = Nothing.String()
+	} else {
+		s.Returns = p.ident()
+	}
+
+	return s
+}
+
+func (p *parser) varDecl(global bool) *VarDecl {
+	var d = VarDecl{Pos: p.pos}
+	if global {
+		d.Constant = p.got(Constant)
+	}
+
+	d.Type = p.ident()
+	d.Array = p.got(Array)
+	d.Name = p.ident()
+
+	if !d.Array && p.got(Assign) {
+		d.Init = p.expr()
+	} else if d.Constant {
+		p.unexpected("\"=\"")
+	}
+
+	return &d
+}
+
+func (p *parser) stmts(end ...Token) []Stmt {
+	var res []Stmt
+	for {
+		for _, t := range end {
+			if p.tok == t {
+				return res
+			}
+		}
+		if p.tok == EOF {
+			p.unexpected(fmt.Sprintf("%q", end[0].String()))
+		}
+		res = append(res, p.stmt())
+		p.endOfLine()
+	}
+}
+
+func (p *parser) stmt() Stmt {
+	var pos = p.pos
+	switch p.tok {
+	case Set:
+		p.next()
+		var s = SetStmt{Pos: pos, Name: p.ident()}
+		if p.got(LBracket) {
+			s.Index = p.expr()
+			p.expect(RBracket)
+		}
+		p.expect(Assign)
+		s.Value = p.expr()
+		return &s
+	case Call:
+		p.next()
+		var cpos = p.pos
+		return &CallStmt{Pos: pos, Call: p.call(cpos, p.ident())}
+	case If:
+		p.next()
+		var s = IfStmt{Pos: pos, Cond: p.expr()}
+		p.expect(Then)
+		p.endOfLine()
+		s.Body = p.stmts(ElseIf, Else, EndIf)
+		for p.tok == ElseIf {
+			var c = ElseIfClause{Pos: p.pos}
+			p.next()
+			c.Cond = p.expr()
+			p.expect(Then)
+			p.endOfLine()
+			c.Body = p.stmts(ElseIf, Else, EndIf)
+			s.ElseIf = append(s.ElseIf, &c)
+		}
+		if p.tok == Else {
+			s.Else = &ElseClause{Pos: p.pos}
+			p.next()
+			p.endOfLine()
+			s.Else.Body = p.stmts(EndIf)
+		}
+		p.expect(EndIf)
+		return &s
+	case Loop:
+		p.next()
+		p.endOfLine()
+		var s = LoopStmt{Pos: pos, Body: p.stmts(EndLoop)}
+		p.expect(EndLoop)
+		return &s
+	case ExitWhen:
+		p.next()
+		return &ExitWhenStmt{Pos: pos, Cond: p.expr()}
+	case Return:
+		p.next()
+		var s = ReturnStmt{Pos: pos}
+		if p.tok != Newline && p.tok != EOF {
+			s.Value = p.expr()
+		}
+		return &s
+	case Debug:
+		p.next()
+		switch p.tok {
+		case Set, Call, If, Loop:
+			return &DebugStmt{Pos: pos, Stmt: p.stmt()}
+		default:
+			p.unexpected("statement after \"debug\"")
+		}
+	case Local:
+		p.fail("local variables must be declared at the start of a function")
+	default:
+		p.unexpected("statement")
+	}
+	return nil
+}
+
+func (p *parser) call(pos Pos, name string) *CallExpr {
+	var c = CallExpr{Pos: pos, Name: name}
+	p.expect(LParen)
+	if !p.got(RParen) {
+		for {
+			c.Args = append(c.Args, p.expr())
+			if !p.got(Comma) {
+				break
+			}
+		}
+		p.expect(RParen)
+	}
+	return &c
+}
+
+func (p *parser) expr() Expr {
+	return p.binary(1)
+}
+
+// Precedence climbing, all binary operators are left-associative
+func (p *parser) binary(prec int) Expr {
+	var x = p.unary()
+	for {
+		var op = p.tok
+		var oprec = op.Precedence()
+		if oprec < prec {
+			return x
+		}
+
+		var pos = p.pos
+		p.next()
+		x = &BinaryExpr{Pos: pos, Op: op, X: x, Y: p.binary(oprec + 1)}
+	}
+}
+
+func (p *parser) unary() Expr {
+	var pos = p.pos
+	switch p.tok {
+	case Not, Plus, Minus:
+		var op = p.tok
+		p.next()
+		return &UnaryExpr{Pos: pos, Op: op, X: p.unary()}
+	default:
+		return p.primary()
+	}
+}
+
+func (p *parser) primary() Expr {
+	var pos = p.pos
+	var lit = p.lit
+	switch p.tok {
+	case IntLiteral, RawcodeLiteral:
+		p.next()
+		return &IntLit{Pos: pos, Raw: lit}
+	case RealLiteral:
+		p.next()
+		return &RealLit{Pos: pos, Raw: lit}
+	case StringLiteral:
+		p.next()
+		return &StringLit{Pos: pos, Raw: lit}
+	case True, False:
+		p.next()
+		return &BoolLit{Pos: pos, Value: lit == True.String()}
+	case Null:
+		p.next()
+		return &NullLit{Pos: pos}
+	case Function:
+		p.next()
+		return &FuncRef{Pos: pos, Name: p.ident()}
+	case LParen:
+		p.next()
+		var x = p.expr()
+		p.expect(RParen)
+		return &ParenExpr{Pos: pos, X: x}
+	case Identifier:
+		p.next()
+		switch p.tok {
+		case LParen:
+			return p.call(pos, lit)
+		case LBracket:
+			p.next()
+			var x = IndexExpr{Pos: pos, Name: lit, Index: p.expr()}
+			p.expect(RBracket)
+			return &x
+		default:
+			return &Ident{Pos: pos, Name: lit}
+		}
+	default:
+		p.unexpected("expression")
+		return nil
+	}
+}
+
+// Parse JASS source, returns an *Error with the position of the first syntax error
+func Parse(src []byte) (f *File, err error) {
+	var p = parser{lex: NewLexer(src)}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			f, err = nil, e
+		}
+	}()
+
+	p.next()
+	return p.file(), nil
+}
@@ -0,0 +1,240 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package jass
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Indent used by the printer
+const Indent = "    "
+
+type printer struct {
+	w     *bufio.Writer
+	depth int
+}
+
+func (p *printer) line(s ...string) {
+	for i := 0; i < p.depth; i++ {
+		p.w.WriteString(Indent)
+	}
+	for _, v := range s {
+		p.w.WriteString(v)
+	}
+	p.w.WriteByte('\n')
+}
+
+func (p *printer) decl(d Decl) {
+	switch d := d.(type) {
+	case *TypeDecl:
+		p.line("type ", d.Name, " extends ", d.Base)
+	case *GlobalsDecl:
+		p.line("globals")
+		p.depth++
+		for _, v := range d.Vars {
+			p.line(varDecl(v))
+		}
+		p.depth--
+		p.line("endglobals")
+	case *NativeDecl:
+		p.line(constant(d.Constant), "native ", signature(&d.Signature))
+	case *FuncDecl:
+		p.line(constant(d.Constant), "function ", signature(&d.Signature))
+		p.depth++
+		for _, v := range d.Locals {
+			p.line("local ", varDecl(v))
+		}
+		p.stmts(d.Body)
+		p.depth--
+		p.line("endfunction")
+	}
+}
+
+func (p *printer) stmts(s []Stmt) {
+	for _, v := range s {
+		p.stmt("", v)
+	}
+}
+
+func (p *printer) stmt(prefix string, s Stmt) {
+	switch s := s.(type) {
+	case *SetStmt:
+		if s.Index != nil {
+			p.line(prefix, "set ", s.Name, "[", expr(s.Index), "] = ", expr(s.Value))
+		} else {
+			p.line(prefix, "set ", s.Name, " = ", expr(s.Value))
+		}
+	case *CallStmt:
+		p.line(prefix, "call ", expr(s.Call))
+	case *IfStmt:
+		p.line(prefix, "if ", expr(s.Cond), " then")
+		p.block(s.Body)
+		for _, e := range s.ElseIf {
+			p.line("elseif ", expr(e.Cond), " then")
+			p.block(e.Body)
+		}
+		if s.Else != nil {
+			p.line("else")
+			p.block(s.Else.Body)
+		}
+		p.line("endif")
+	case *LoopStmt:
+		p.line(prefix, "loop")
+		p.block(s.Body)
+		p.line("endloop")
+	case *ExitWhenStmt:
+		p.line(prefix, "exitwhen ", expr(s.Cond))
+	case *ReturnStmt:
+		if s.Value != nil {
+			p.line(prefix, "return ", expr(s.Value))
+		} else {
+			p.line(prefix, "return")
+		}
+	case *DebugStmt:
+		p.stmt(prefix+"debug ", s.Stmt)
+	}
+}
+
+func (p *printer) block(s []Stmt) {
+	p.depth++
+	p.stmts(s)
+	p.depth--
+}
+
+func constant(c bool) string {
+	if c {
+		return "constant "
+	}
+	return ""
+}
+
+func signature(s *Signature) string {
+	var sb strings.Builder
+	sb.WriteString(s.Name)
+	sb.WriteString(" takes ")
+	if len(s.Params) == 0 {
+		sb.WriteString("nothing")
+	}
+	for i, a := range s.Params {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(a.Type)
+		sb.WriteByte(' ')
+		sb.WriteString(a.Name)
+	}
+	sb.WriteString(" returns ")
+	sb.WriteString(s.Returns)
+	return sb.String()
+}
+
+func varDecl(v *VarDecl) string {
+	var sb strings.Builder
+	sb.WriteString(constant(v.Constant))
+	sb.WriteString(v.Type)
+	if v.Array {
+		sb.WriteString(" array")
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(v.Name)
+	if v.Init != nil {
+		sb.WriteString(" = ")
+		sb.WriteString(expr(v.Init))
+	}
+	return sb.String()
+}
+
+func expr(e Expr) string {
+	var sb strings.Builder
+	writeExpr(&sb, e)
+	return sb.String()
+}
+
+// Parentheses are added where needed, so that ASTs that were not produced by the parser print correctly
+func writeOperand(sb *strings.Builder, e Expr, prec int) {
+	if b, ok := e.(*BinaryExpr); ok && b.Op.Precedence() < prec {
+		sb.WriteByte('(')
+		writeExpr(sb, e)
+		sb.WriteByte(')')
+	} else {
+		writeExpr(sb, e)
+	}
+}
+
+func writeExpr(sb *strings.Builder, e Expr) {
+	switch e := e.(type) {
+	case *Ident:
+		sb.WriteString(e.Name)
+	case *IndexExpr:
+		sb.WriteString(e.Name)
+		sb.WriteByte('[')
+		writeExpr(sb, e.Index)
+		sb.WriteByte(']')
+	case *CallExpr:
+		sb.WriteString(e.Name)
+		sb.WriteByte('(')
+		for i, a := range e.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeExpr(sb, a)
+		}
+		sb.WriteByte(')')
+	case *FuncRef:
+		sb.WriteString("function ")
+		sb.WriteString(e.Name)
+	case *IntLit:
+		sb.WriteString(e.Raw)
+	case *RealLit:
+		sb.WriteString(e.Raw)
+	case *StringLit:
+		sb.WriteString(e.Raw)
+	case *BoolLit:
+		if e.Value {
+			sb.WriteString("true")
+		} else {
+			sb.WriteString("false")
+		}
+	case *NullLit:
+		sb.WriteString("null")
+	case *UnaryExpr:
+		sb.WriteString(e.Op.String())
+		if e.Op == Not {
+			sb.WriteByte(' ')
+		}
+		writeOperand(sb, e.X, Mul.Precedence()+1)
+	case *BinaryExpr:
+		var prec = e.Op.Precedence()
+		writeOperand(sb, e.X, prec)
+		sb.WriteByte(' ')
+		sb.WriteString(e.Op.String())
+		sb.WriteByte(' ')
+		writeOperand(sb, e.Y, prec+1)
+	case *ParenExpr:
+		sb.WriteByte('(')
+		writeExpr(sb, e.X)
+		sb.WriteByte(')')
+	}
+}
+
+// Fprint writes the JASS source of f to w, comments and original formatting are not preserved
+func Fprint(w io.Writer, f *File) error {
+	var p = printer{w: bufio.NewWriter(w)}
+	for i, d := range f.Decls {
+		if _, fn := d.(*FuncDecl); fn && i > 0 {
+			p.w.WriteByte('\n')
+		}
+		p.decl(d)
+	}
+	return p.w.Flush()
+}
+
+func (f *File) String() string {
+	var sb strings.Builder
+	Fprint(&sb, f)
+	return sb.String()
+}
@@ -0,0 +1,367 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package slk
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Category enum
+type Category uint8
+
+// Object categories
+const (
+	CategoryUnit Category = iota
+	CategoryItem
+	CategoryAbility
+	CategoryBuff
+	CategoryUpgrade
+	CategoryDestructable
+)
+
+func (c Category) String() string {
+	switch c {
+	case CategoryUnit:
+		return "Unit"
+	case CategoryItem:
+		return "Item"
+	case CategoryAbility:
+		return "Ability"
+	case CategoryBuff:
+		return "Buff"
+	case CategoryUpgrade:
+		return "Upgrade"
+	case CategoryDestructable:
+		return "Destructable"
+	default:
+		return fmt.Sprintf("Category(0x%02X)", uint8(c))
+	}
+}
+
+var races = []string{"Campaign", "Human", "Neutral", "NightElf", "Orc", "Undead"}
+
+func profileFiles(kind string, extra ...string) []string {
+	var res []string
+	for _, r := range append(races, extra...) {
+		res = append(res, "Units\\"+r+kind+"Func.txt", "Units\\"+r+kind+"Strings.txt")
+	}
+	return res
+}
+
+var categoryFiles = []struct {
+	cat     Category
+	slk     []string
+	profile []string
+}{
+	{
+		CategoryUnit,
+		[]string{"Units\\UnitData.slk", "Units\\UnitBalance.slk", "Units\\UnitUI.slk", "Units\\UnitWeapons.slk", "Units\\UnitAbilities.slk"},
+		profileFiles("Unit"),
+	},
+	{
+		CategoryItem,
+		[]string{"Units\\ItemData.slk"},
+		[]string{"Units\\ItemFunc.txt", "Units\\ItemStrings.txt"},
+	},
+	{
+		CategoryAbility,
+		[]string{"Units\\AbilityData.slk"},
+		profileFiles("Ability", "Common", "Item"),
+	},
+	{
+		CategoryBuff,
+		[]string{"Units\\AbilityBuffData.slk"},
+		profileFiles("Ability", "Common", "Item"),
+	},
+	{
+		CategoryUpgrade,
+		[]string{"Units\\UpgradeData.slk"},
+		profileFiles("Upgrade"),
+	},
+	{
+		CategoryDestructable,
+		[]string{"Units\\DestructableData.slk"},
+		nil,
+	},
+}
+
+var stringFiles = []string{
+	"UI\\WorldEditStrings.txt",
+	"UI\\WorldEditGameStrings.txt",
+}
+
+// Object with all its fields (from SLK tables and profile data), keys are stored in lower case
+type Object struct {
+	ID       protocol.DWordString
+	Category Category
+	Fields   map[string]string
+}
+
+// Data contains the base game data for all objects
+type Data struct {
+	Objects map[protocol.DWordString]*Object
+
+	// WorldEdit strings (used to resolve WESTRING_ references)
+	Strings map[string]string
+
+	// Map trigger strings (used to resolve TRIGSTR_ references), see w3m.Map.GameData
+	TriggerStrings map[int]string
+}
+
+func openTable(stor *fs.Storage, fileName string) (*Table, error) {
+	f, err := stor.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(f)
+}
+
+func openProfile(stor *fs.Storage, fileName string) (Profile, error) {
+	f, err := stor.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return DecodeProfile(f)
+}
+
+// ProfileFiles returns the profile (.txt) files that contain data for objects in category c
+func (c Category) ProfileFiles() []string {
+	for _, f := range categoryFiles {
+		if f.cat == c {
+			return f.profile
+		}
+	}
+	return nil
+}
+
+// LoadProfile reads and merges the profile files in stor, missing files are skipped
+func LoadProfile(stor *fs.Storage, fileNames []string) (Profile, error) {
+	var res = Profile{}
+	for _, fileName := range fileNames {
+		p, err := openProfile(stor, fileName)
+		if err == os.ErrNotExist {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res.Merge(p)
+	}
+	return res, nil
+}
+
+// Load game data from stor, missing files are skipped
+//
+// Objects that only appear in profile data are added to the first category that lists the
+// profile file (abilities and buffs share their profile files).
+func Load(stor *fs.Storage) (*Data, error) {
+	var d = Data{
+		Objects: map[protocol.DWordString]*Object{},
+		Strings: map[string]string{},
+	}
+
+	var ids = make([][]protocol.DWordString, len(categoryFiles))
+	for ci, c := range categoryFiles {
+		for _, fileName := range c.slk {
+			tbl, err := openTable(stor, fileName)
+			if err == os.ErrNotExist {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			for _, r := range tbl.Rows {
+				var id protocol.DWordString
+				if len(r[0]) != 4 || id.UnmarshalText([]byte(r[0])) != nil {
+					continue
+				}
+
+				var obj = d.Objects[id]
+				if obj == nil {
+					obj = &Object{ID: id, Category: c.cat, Fields: map[string]string{}}
+					d.Objects[id] = obj
+					ids[ci] = append(ids[ci], id)
+				} else if obj.Category != c.cat {
+					continue
+				}
+
+				for i, h := range tbl.Header[1:] {
+					var k = strings.ToLower(h)
+					if _, ok := obj.Fields[k]; !ok {
+						obj.Fields[k] = r[i+1]
+					}
+				}
+			}
+		}
+	}
+
+	// Profile data, read after all SLK tables so shared profile files cannot claim objects of later categories
+	var profiles = map[string]Profile{}
+	for ci, c := range categoryFiles {
+		for _, fileName := range c.profile {
+			var p, ok = profiles[fileName]
+			if !ok {
+				var err error
+				p, err = openProfile(stor, fileName)
+				if err != nil && err != os.ErrNotExist {
+					return nil, err
+				}
+				profiles[fileName] = p
+			}
+
+			for _, id := range ids[ci] {
+				var sec = p[id.String()]
+				for k, v := range sec {
+					if _, ok := d.Objects[id].Fields[k]; !ok {
+						d.Objects[id].Fields[k] = v
+					}
+				}
+			}
+
+			for name, sec := range p {
+				var id protocol.DWordString
+				if len(name) != 4 || id.UnmarshalText([]byte(name)) != nil || d.Objects[id] != nil {
+					continue
+				}
+
+				var obj = &Object{ID: id, Category: c.cat, Fields: make(map[string]string, len(sec))}
+				for k, v := range sec {
+					obj.Fields[k] = v
+				}
+				d.Objects[id] = obj
+				ids[ci] = append(ids[ci], id)
+			}
+		}
+	}
+
+	for _, fileName := range stringFiles {
+		p, err := openProfile(stor, fileName)
+		if err == os.ErrNotExist {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, sec := range p {
+			for k, v := range sec {
+				var key = strings.ToUpper(k)
+				if _, ok := d.Strings[key]; !ok {
+					d.Strings[key] = v
+				}
+			}
+		}
+	}
+
+	return &d, nil
+}
+
+var reTrigStr = regexp.MustCompile(`^TRIGSTR_(\d+)$`)
+
+// Resolve TRIGSTR_ and WESTRING_ references in s
+func (d *Data) Resolve(s string) string {
+	for i := 0; i < 8; i++ {
+		if strings.HasPrefix(s, "WESTRING_") {
+			v, ok := d.Strings[strings.ToUpper(s)]
+			if !ok {
+				return s
+			}
+			s = strings.Trim(v, "\"")
+		} else if m := reTrigStr.FindStringSubmatch(s); m != nil && d.TriggerStrings != nil {
+			id, _ := strconv.Atoi(m[1])
+			v, ok := d.TriggerStrings[id]
+			if !ok {
+				return s
+			}
+			s = v
+		} else {
+			break
+		}
+	}
+	return s
+}
+
+// Get the (resolved) value of field for object id
+func (d *Data) Get(id protocol.DWordString, field string) (string, bool) {
+	var obj = d.Objects[id]
+	if obj == nil {
+		return "", false
+	}
+
+	v, ok := obj.Fields[strings.ToLower(field)]
+	if !ok {
+		return "", false
+	}
+
+	var list = SplitList(v)
+	for i := range list {
+		list[i] = d.Resolve(list[i])
+	}
+	return strings.Join(list, ","), true
+}
+
+// First (resolved) value of a comma separated list in the first field that exists
+func (d *Data) first(id protocol.DWordString, fields ...string) string {
+	var obj = d.Objects[id]
+	if obj == nil {
+		return ""
+	}
+	for _, f := range fields {
+		if v, ok := obj.Fields[strings.ToLower(f)]; ok && v != "" && v != "-" && v != "_" {
+			return d.Resolve(SplitList(v)[0])
+		}
+	}
+	return ""
+}
+
+func (d *Data) firstInt(id protocol.DWordString, fields ...string) int {
+	var v = d.first(id, fields...)
+	if n, err := strconv.Atoi(v); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return int(f)
+	}
+	return 0
+}
+
+// Name of object id
+func (d *Data) Name(id protocol.DWordString) string {
+	return d.first(id, "Name", "Bufftip", "EditorName")
+}
+
+// GoldCost of object id
+func (d *Data) GoldCost(id protocol.DWordString) int {
+	return d.firstInt(id, "goldcost", "goldbase")
+}
+
+// LumberCost of object id
+func (d *Data) LumberCost(id protocol.DWordString) int {
+	return d.firstInt(id, "lumbercost", "lumberbase")
+}
+
+// BuildTime of object id (in seconds)
+func (d *Data) BuildTime(id protocol.DWordString) int {
+	return d.firstInt(id, "bldtm", "timebase")
+}
+
+// Hotkey of object id
+func (d *Data) Hotkey(id protocol.DWordString) string {
+	return d.first(id, "Hotkey", "Researchhotkey")
+}
+
+// Icon path of object id
+func (d *Data) Icon(id protocol.DWordString) string {
+	return d.first(id, "Art", "Buffart")
+}
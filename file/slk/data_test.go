@@ -0,0 +1,106 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package slk_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/file/slk"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+func TestDecodeProfile(t *testing.T) {
+	p, err := slk.DecodeProfile(strings.NewReader("\ufeff// Comment\r\n[hfoo]\r\nName=Footman\r\nHOTKEY = F\r\n\r\n[Rhme]\nName=\"Iron Forged Swords\",\"Steel, Forged Swords\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := p.Get("hfoo", "Hotkey"); !ok || v != "F" {
+		t.Fatal("Get mismatch", v, ok)
+	}
+	if v, _ := p.Get("Rhme", "name"); !reflect.DeepEqual(slk.SplitList(v), []string{"Iron Forged Swords", "Steel, Forged Swords"}) {
+		t.Fatal("SplitList mismatch", slk.SplitList(v))
+	}
+
+	p.Merge(slk.Profile{"hfoo": {"name": "Other", "art": "BTNFootman.blp"}})
+	if !reflect.DeepEqual(p["hfoo"], map[string]string{"name": "Footman", "hotkey": "F", "art": "BTNFootman.blp"}) {
+		t.Fatal("Merge mismatch", p["hfoo"])
+	}
+
+	if _, err := slk.DecodeProfile(strings.NewReader("[hfoo\n")); err != slk.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	var dir = t.TempDir()
+	var files = map[string]string{
+		"Units/UnitBalance.slk":         unitBalance,
+		"Units/UnitData.slk":            "ID;PWXL;N;E\nC;Y1;X1;K\"unitID\"\nC;X2;K\"bldtm\"\nC;Y2;X1;K\"hfoo\"\nC;X2;K20\nE\n",
+		"Units/UpgradeData.slk":         "ID;PWXL;N;E\nC;Y1;X1;K\"upgradeid\"\nC;X2;K\"goldbase\"\nC;X3;K\"timebase\"\nC;Y2;X1;K\"Rhme\"\nC;X2;K100\nC;X3;K60\nE\n",
+		"Units/HumanUnitFunc.txt":       "[hfoo]\nArt=ReplaceableTextures\\CommandButtons\\BTNFootman.blp\n",
+		"Units/HumanUnitStrings.txt":    "[hfoo]\nName=WESTRING_UNIT_FOOTMAN\nHotkey=F\n[hpea]\nName=TRIGSTR_001\n[hmpr]\nName=Priest\n",
+		"Units/HumanUpgradeStrings.txt": "[Rhme]\nName=Iron Forged Swords,Steel Forged Swords\nHotkey=S\n",
+		"UI/WorldEditStrings.txt":       "[WorldEditStrings]\nWESTRING_UNIT_FOOTMAN=\"Footman\"\n",
+	}
+	for name, content := range files {
+		var path = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stor = fs.Open(dir)
+	defer stor.Close()
+
+	d, err := slk.Load(stor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hfoo = protocol.DString("hfoo")
+	var hpea = protocol.DString("hpea")
+	var rhme = protocol.DString("Rhme")
+	var hmpr = protocol.DString("hmpr")
+
+	if len(d.Objects) != 4 || d.Objects[hfoo].Category != slk.CategoryUnit || d.Objects[rhme].Category != slk.CategoryUpgrade {
+		t.Fatal("Objects mismatch", d.Objects)
+	}
+
+	if n := d.Name(hfoo); n != "Footman" {
+		t.Fatal("Name mismatch", n)
+	}
+	if d.GoldCost(hfoo) != 135 || d.BuildTime(hfoo) != 20 || d.Hotkey(hfoo) != "F" || d.Icon(hfoo) != "ReplaceableTextures\\CommandButtons\\BTNFootman.blp" {
+		t.Fatal("Field mismatch", d.Objects[hfoo].Fields)
+	}
+	if d.Name(rhme) != "Iron Forged Swords" || d.GoldCost(rhme) != 100 || d.BuildTime(rhme) != 60 || d.Hotkey(rhme) != "S" {
+		t.Fatal("Upgrade mismatch", d.Objects[rhme].Fields)
+	}
+
+	if d.Objects[hmpr].Category != slk.CategoryUnit || d.Name(hmpr) != "Priest" {
+		t.Fatal("Profile-only object mismatch", d.Objects[hmpr])
+	}
+
+	if n := d.Name(hpea); n != "TRIGSTR_001" {
+		t.Fatal("Expected unresolved trigger string, got", n)
+	}
+	d.TriggerStrings = map[int]string{1: "Peasant"}
+	if n := d.Name(hpea); n != "Peasant" {
+		t.Fatal("Name mismatch", n)
+	}
+
+	if v, ok := d.Get(rhme, "NAME"); !ok || v != "Iron Forged Swords,Steel Forged Swords" {
+		t.Fatal("Get mismatch", v)
+	}
+	if _, ok := d.Get(protocol.DString("hkni"), "Name"); ok {
+		t.Fatal("Expected missing object")
+	}
+}
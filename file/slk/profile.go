@@ -0,0 +1,107 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package slk
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Profile data (INI format) with sections of key/value pairs, keys are stored in lower case
+type Profile map[string]map[string]string
+
+// DecodeProfile decodes a profile (.txt) file from r
+func DecodeProfile(r io.Reader) (Profile, error) {
+	var s = bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var res = Profile{}
+	var sec map[string]string
+
+	for first := true; s.Scan(); first = false {
+		var line = s.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "//") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if line[0] == '[' {
+			var end = strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, ErrBadFormat
+			}
+
+			var name = line[1:end]
+			sec = res[name]
+			if sec == nil {
+				sec = map[string]string{}
+				res[name] = sec
+			}
+			continue
+		}
+
+		var eq = strings.IndexByte(line, '=')
+		if eq < 0 || sec == nil {
+			continue
+		}
+
+		sec[strings.ToLower(strings.TrimSpace(line[:eq]))] = strings.TrimSpace(line[eq+1:])
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Merge adds the sections and keys from o that are not yet in p
+func (p Profile) Merge(o Profile) {
+	for name, sec := range o {
+		var dst = p[name]
+		if dst == nil {
+			dst = make(map[string]string, len(sec))
+			p[name] = dst
+		}
+		for k, v := range sec {
+			if _, ok := dst[k]; !ok {
+				dst[k] = v
+			}
+		}
+	}
+}
+
+// Get value of key in section
+func (p Profile) Get(section string, key string) (string, bool) {
+	var sec = p[section]
+	if sec == nil {
+		return "", false
+	}
+	v, ok := sec[strings.ToLower(key)]
+	return v, ok
+}
+
+// SplitList splits a comma separated list of (optionally quoted) values
+func SplitList(v string) []string {
+	var res []string
+	var cur strings.Builder
+	var quoted = false
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '"':
+			quoted = !quoted
+		case v[i] == ',' && !quoted:
+			res = append(res, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(v[i])
+		}
+	}
+	return append(res, cur.String())
+}
@@ -0,0 +1,183 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package slk implements decoders for Warcraft III game data (SYLK tables and profile files).
+package slk
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Errors
+var (
+	ErrBadFormat = errors.New("slk: Invalid file format")
+)
+
+// Table with game data, the first row is used as header
+type Table struct {
+	Header []string
+	Rows   [][]string
+
+	cols map[string]int
+	rows map[string]int
+}
+
+// Split record into fields, ";;" is an escaped semicolon
+func splitRecord(line string) []string {
+	var res []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] != ';' {
+			cur.WriteByte(line[i])
+		} else if i+1 < len(line) && line[i+1] == ';' {
+			cur.WriteByte(';')
+			i++
+		} else {
+			res = append(res, cur.String())
+			cur.Reset()
+		}
+	}
+	return append(res, cur.String())
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// Decode a SYLK table from r
+func Decode(r io.Reader) (*Table, error) {
+	var s = bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	if !s.Scan() || !strings.HasPrefix(s.Text(), "ID") {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrBadFormat
+	}
+
+	var cells [][]string
+	var x, y = 1, 1
+
+scan:
+	for s.Scan() {
+		var fields = splitRecord(strings.TrimRight(s.Text(), "\r"))
+		switch fields[0] {
+		case "E":
+			break scan
+		case "C", "F":
+			var val *string
+			for _, f := range fields[1:] {
+				if len(f) == 0 {
+					continue
+				}
+				switch f[0] {
+				case 'X':
+					n, err := strconv.Atoi(f[1:])
+					if err != nil || n < 1 {
+						return nil, ErrBadFormat
+					}
+					x = n
+				case 'Y':
+					n, err := strconv.Atoi(f[1:])
+					if err != nil || n < 1 {
+						return nil, ErrBadFormat
+					}
+					y = n
+				case 'K':
+					var v = unquote(f[1:])
+					val = &v
+				}
+			}
+
+			if fields[0] != "C" || val == nil {
+				continue
+			}
+
+			for len(cells) < y {
+				cells = append(cells, nil)
+			}
+			for len(cells[y-1]) < x {
+				cells[y-1] = append(cells[y-1], "")
+			}
+			cells[y-1][x-1] = *val
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(cells) == 0 {
+		return nil, ErrBadFormat
+	}
+
+	var t = Table{
+		Header: cells[0],
+		Rows:   make([][]string, 0, len(cells)-1),
+	}
+
+	for _, c := range cells[1:] {
+		if len(c) == 0 {
+			continue
+		}
+		for len(c) < len(t.Header) {
+			c = append(c, "")
+		}
+		t.Rows = append(t.Rows, c)
+	}
+
+	t.index()
+	return &t, nil
+}
+
+func (t *Table) index() {
+	t.cols = make(map[string]int, len(t.Header))
+	for i, h := range t.Header {
+		var k = strings.ToLower(h)
+		if _, ok := t.cols[k]; !ok {
+			t.cols[k] = i
+		}
+	}
+
+	t.rows = make(map[string]int, len(t.Rows))
+	for i, r := range t.Rows {
+		var k = strings.ToLower(r[0])
+		if _, ok := t.rows[k]; !ok {
+			t.rows[k] = i
+		}
+	}
+}
+
+// Column index of name (case-insensitive), -1 if not found
+func (t *Table) Column(name string) int {
+	if i, ok := t.cols[strings.ToLower(name)]; ok {
+		return i
+	}
+	return -1
+}
+
+// Row index of the row where the first column equals key (case-insensitive), -1 if not found
+func (t *Table) Row(key string) int {
+	if i, ok := t.rows[strings.ToLower(key)]; ok {
+		return i
+	}
+	return -1
+}
+
+// Get value in column col for the row with key
+func (t *Table) Get(key string, col string) (string, bool) {
+	var r = t.Row(key)
+	var c = t.Column(col)
+	if r < 0 || c < 0 || c >= len(t.Rows[r]) {
+		return "", false
+	}
+	return t.Rows[r][c], true
+}
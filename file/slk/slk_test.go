@@ -0,0 +1,58 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package slk_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/slk"
+)
+
+var unitBalance = `ID;PWXL;N;E
+B;X4;Y4;D0
+C;Y1;X1;K"unitBalanceID"
+C;X2;K"goldcost"
+C;X3;K"HP"
+C;X4;K"comment;;note"
+C;Y2;X1;K"hfoo"
+C;X2;K135
+C;X3;K420
+F;Y3;X1
+C;K"hpea"
+C;X3;K220
+C;X4;K"-"
+E
+C;Y4;X1;K"ignored"
+`
+
+func TestDecode(t *testing.T) {
+	tbl, err := slk.Decode(strings.NewReader(strings.Replace(unitBalance, "\n", "\r\n", -1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tbl.Header, []string{"unitBalanceID", "goldcost", "HP", "comment;note"}) {
+		t.Fatal("Header mismatch", tbl.Header)
+	}
+	if !reflect.DeepEqual(tbl.Rows, [][]string{{"hfoo", "135", "420", ""}, {"hpea", "", "220", "-"}}) {
+		t.Fatal("Rows mismatch", tbl.Rows)
+	}
+
+	if tbl.Column("hp") != 2 || tbl.Column("foo") != -1 || tbl.Row("HPEA") != 1 || tbl.Row("ignored") != -1 {
+		t.Fatal("Index mismatch")
+	}
+	if v, ok := tbl.Get("hfoo", "GoldCost"); !ok || v != "135" {
+		t.Fatal("Get mismatch", v, ok)
+	}
+	if _, ok := tbl.Get("hfoo", "lumbercost"); ok {
+		t.Fatal("Expected missing column")
+	}
+
+	if _, err := slk.Decode(strings.NewReader("C;X1;K1\n")); err != slk.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+}
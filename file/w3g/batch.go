@@ -0,0 +1,96 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var errStopBatch = errors.New("w3g: Stop batch")
+
+// BatchResult of a single file in OpenDir
+type BatchResult struct {
+	Path   string
+	Replay *Replay
+	Err    error
+}
+
+// OpenDir recursively scans root for replay files (.w3g and .nwg) and opens them using
+// workers goroutines (GOMAXPROCS if <= 0). f is called sequentially for every file, in
+// order of completion. Scanning stops if f returns an error, which is then returned.
+func OpenDir(root string, workers int, f func(res *BatchResult) error) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var done = make(chan struct{})
+	var paths = make(chan string, workers)
+	var results = make(chan *BatchResult, workers)
+
+	var walkErr error
+	go func() {
+		defer close(paths)
+		walkErr = filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if e.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".w3g", ".nwg":
+			default:
+				return nil
+			}
+
+			select {
+			case paths <- path:
+				return nil
+			case <-done:
+				return errStopBatch
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				rep, err := Open(path)
+				select {
+				case results <- &BatchResult{Path: path, Replay: rep, Err: err}:
+				case <-done:
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	for res := range results {
+		if err != nil {
+			continue
+		}
+		if err = f(res); err != nil {
+			close(done)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	return walkErr
+}
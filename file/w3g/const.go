@@ -14,6 +14,7 @@ var (
 	ErrInvalidChecksum = errors.New("w3g: Checksum invalid")
 	ErrUnexpectedConst = errors.New("w3g: Unexpected constant value")
 	ErrUnknownRecord   = errors.New("w3g: Unknown record ID")
+	ErrUnknownAction   = errors.New("w3g: Unknown action ID")
 )
 
 // Signature constant for w3g files
@@ -33,6 +33,9 @@ type Decompressor struct {
 	crcData uint16
 	buf     [12]byte
 	bufr    *bufio.Reader
+
+	// Called at the start of each block with compressed offset and decompressed block size
+	onBlock func(offset uint32, size uint32)
 }
 
 // NewDecompressor for compressed w3g data
@@ -69,6 +72,42 @@ func (r *toByteReader) ReadByte() (byte, error) {
 	return r.b[0], err
 }
 
+// blockHeaderSize returns the size of a data block header for game version v
+func blockHeaderSize(v uint32) int {
+	if v > 0 && v < 10032 {
+		return 8
+	}
+	return 12
+}
+
+// decodeBlockHeader returns compressed size, decompressed size and data checksum of block header b.
+// valid is false if the header checksum does not match. Overwrites the checksums in b.
+func decodeBlockHeader(b []byte, v uint32) (lenDeflate uint32, sizeBlock uint32, crcData uint16, valid bool) {
+	var pbuf = protocol.Buffer{Bytes: b}
+	if v == 0 || v >= 10032 {
+		lenDeflate = pbuf.ReadUInt32()
+		sizeBlock = pbuf.ReadUInt32()
+	} else {
+		lenDeflate = uint32(pbuf.ReadUInt16())
+		sizeBlock = uint32(pbuf.ReadUInt16())
+	}
+
+	var crcHead = pbuf.ReadUInt16()
+	crcData = pbuf.ReadUInt16()
+
+	var l = len(b)
+	b[l-4], b[l-3], b[l-2], b[l-1] = 0, 0, 0, 0
+	var crc = crc32.ChecksumIEEE(b)
+
+	return lenDeflate, sizeBlock, crcData, crcHead == uint16(crc^crc>>16)
+}
+
+// dataChecksum as stored in data block headers
+func dataChecksum(b []byte) uint16 {
+	var crc = crc32.ChecksumIEEE(b)
+	return uint16(crc ^ crc>>16)
+}
+
 func (d *Decompressor) nextBlock() error {
 	if d.NumBlocks == 0 {
 		return io.EOF
@@ -79,10 +118,8 @@ func (d *Decompressor) nextBlock() error {
 
 	d.NumBlocks--
 
-	var lenHead = len(d.buf)
-	if d.GameVersion > 0 && d.GameVersion < 10032 {
-		lenHead -= 4
-	}
+	var offset = d.SizeRead
+	var lenHead = blockHeaderSize(d.GameVersion)
 
 	n, err := io.ReadFull(d.r, d.buf[:lenHead])
 	d.SizeRead += uint32(n)
@@ -90,24 +127,16 @@ func (d *Decompressor) nextBlock() error {
 		return err
 	}
 
-	var pbuf = protocol.Buffer{Bytes: d.buf[:lenHead]}
-	var lenDeflate uint32
-	if d.GameVersion == 0 || d.GameVersion >= 10032 {
-		lenDeflate = pbuf.ReadUInt32()
-		d.SizeBlock = pbuf.ReadUInt32()
-	} else {
-		lenDeflate = uint32(pbuf.ReadUInt16())
-		d.SizeBlock = uint32(pbuf.ReadUInt16())
-	}
-
-	var crcHead = pbuf.ReadUInt16()
-	d.crcData = pbuf.ReadUInt16()
-
-	d.buf[lenHead-4], d.buf[lenHead-3], d.buf[lenHead-2], d.buf[lenHead-1] = 0, 0, 0, 0
-	var crc = crc32.ChecksumIEEE(d.buf[:lenHead])
-	if crcHead != uint16(crc^crc>>16) {
+	lenDeflate, sizeBlock, crcData, valid := decodeBlockHeader(d.buf[:lenHead], d.GameVersion)
+	if !valid {
 		return ErrInvalidChecksum
 	}
+	d.SizeBlock = sizeBlock
+	d.crcData = crcData
+	if d.onBlock != nil {
+		d.onBlock(offset, d.SizeBlock)
+	}
+
 	// Use limr to keep track of how many compressed bytes are read
 	d.lim.R = d.r
 	d.lim.N = int64(lenDeflate)
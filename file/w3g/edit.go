@@ -0,0 +1,123 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"math"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Duration sums the time increments of the time slots in records
+func Duration(records []Record) uint32 {
+	var res uint32
+	for _, r := range records {
+		if ts, ok := r.(*TimeSlot); ok {
+			res += uint32(ts.TimeIncrementMS)
+		}
+	}
+	return res
+}
+
+// Cut returns a copy of r that ends at game time to (exclusive) and fast-forwards until game time from.
+//
+// The game simulation depends on every action, so actions before from cannot be removed. Instead, chat
+// messages before from are dropped and consecutive time slots without actions are merged. Players that
+// are still in the game at time to leave at the end of the replay.
+func (r *Replay) Cut(from uint32, to uint32) *Replay {
+	var res = *r
+	res.Records = make([]Record, 0, len(r.Records))
+
+	var left = map[uint8]bool{}
+	var time uint32
+	for _, rec := range r.Records {
+		if time >= to {
+			break
+		}
+
+		switch v := rec.(type) {
+		case *TimeSlot:
+			var t = time
+			time += uint32(v.TimeIncrementMS)
+			if t >= from || len(v.Actions) > 0 || v.Fragment {
+				break
+			}
+
+			// Merge empty time slot with previous empty time slot
+			if n := len(res.Records); n > 0 {
+				if p, ok := res.Records[n-1].(*TimeSlot); ok && len(p.Actions) == 0 && !p.Fragment &&
+					uint32(p.TimeIncrementMS)+uint32(v.TimeIncrementMS) <= math.MaxUint16 {
+					var m = *p
+					m.TimeIncrementMS += v.TimeIncrementMS
+					res.Records[n-1] = &m
+					continue
+				}
+			}
+		case *ChatMessage:
+			if time < from {
+				continue
+			}
+		case *PlayerLeft:
+			left[v.PlayerID] = true
+		}
+
+		res.Records = append(res.Records, rec)
+	}
+
+	// Saving player leaves last
+	for i := len(r.PlayerInfo) - 1; i >= 0; i-- {
+		var id = r.PlayerInfo[i].ID
+		if left[id] || id == r.HostPlayer.ID {
+			continue
+		}
+		res.Records = append(res.Records, &PlayerLeft{
+			PlayerID: id,
+			Reason:   w3gs.LeaveDisconnect,
+		})
+	}
+	if !left[r.HostPlayer.ID] {
+		res.Records = append(res.Records, &PlayerLeft{
+			Local:    true,
+			PlayerID: r.HostPlayer.ID,
+			Reason:   w3gs.LeaveDisconnect,
+		})
+	}
+
+	res.DurationMS = Duration(res.Records)
+	return &res
+}
+
+// TrimEnd returns a copy of r without the time slots and chat messages between the last two
+// PlayerLeft records, i.e. the time that the last player remains in the game alone.
+func (r *Replay) TrimEnd() *Replay {
+	var res = *r
+
+	var last = -1
+	var prev = -1
+	for i, rec := range r.Records {
+		if _, ok := rec.(*PlayerLeft); ok {
+			prev, last = last, i
+		}
+	}
+
+	if prev < 0 {
+		res.Records = append([]Record{}, r.Records...)
+		return &res
+	}
+
+	res.Records = make([]Record, 0, len(r.Records))
+	res.Records = append(res.Records, r.Records[:prev+1]...)
+	for _, rec := range r.Records[prev+1 : last] {
+		switch rec.(type) {
+		case *TimeSlot, *ChatMessage:
+			continue
+		}
+		res.Records = append(res.Records, rec)
+	}
+	res.Records = append(res.Records, r.Records[last:]...)
+
+	res.DurationMS = Duration(res.Records)
+	return &res
+}
@@ -0,0 +1,106 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+func countRecords(records []w3g.Record) (actions int, chat int, leaves int) {
+	for _, r := range records {
+		switch v := r.(type) {
+		case *w3g.TimeSlot:
+			actions += len(v.Actions)
+		case *w3g.ChatMessage:
+			chat++
+		case *w3g.PlayerLeft:
+			leaves++
+		}
+	}
+	return
+}
+
+func reencode(t *testing.T, rep *w3g.Replay) *w3g.Replay {
+	var b bytes.Buffer
+	if err := rep.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+	res, err := w3g.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestCut(t *testing.T) {
+	for _, file := range []string{"test_102.w3g", "test_126.w3g", "test_130.w3g", "test_132.w3g"} {
+		rep, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var orig = len(rep.Records)
+		var all = timeRecords(rep.Records)
+		var end = w3g.Duration(rep.Records)
+		var actions, _, leaves = countRecords(rep.Records)
+
+		var cut = rep.Cut(0, end+1)
+		if len(rep.Records) != orig {
+			t.Fatal(file, "Cut modified original")
+		}
+		if !reflect.DeepEqual(cut.Records[:len(rep.Records)], rep.Records) || cut.DurationMS != end {
+			t.Fatal(file, "Full cut mismatch")
+		}
+
+		var from, to = end / 3, end / 2
+		cut = rep.Cut(from, to)
+
+		var expActions, expChat = 0, 0
+		for _, r := range all {
+			if r.ms >= to {
+				break
+			}
+			switch v := r.rec.(type) {
+			case *w3g.TimeSlot:
+				expActions += len(v.Actions)
+			case *w3g.ChatMessage:
+				if r.ms >= from {
+					expChat++
+				}
+			}
+		}
+
+		var cutActions, cutChat, cutLeaves = countRecords(cut.Records)
+		if cutActions != expActions || cutChat != expChat || cutLeaves != len(rep.PlayerInfo) {
+			t.Fatal(file, "Cut record mismatch", cutActions, expActions, cutChat, expChat, cutLeaves)
+		}
+		if cut.DurationMS >= to+1000 || cut.DurationMS+1000 < to || len(cut.Records) >= len(rep.Records) {
+			t.Fatal(file, "Cut duration mismatch", cut.DurationMS, to)
+		}
+		if p, ok := cut.Records[len(cut.Records)-1].(*w3g.PlayerLeft); !ok || p.PlayerID != rep.HostPlayer.ID || !p.Local {
+			t.Fatal(file, "Expected host to leave last")
+		}
+
+		dec := reencode(t, cut)
+		if !reflect.DeepEqual(dec.Records, cut.Records) || dec.DurationMS != cut.DurationMS {
+			t.Fatal(file, "Cut re-encode mismatch")
+		}
+
+		var trim = rep.TrimEnd()
+		var trimActions, _, trimLeaves = countRecords(trim.Records)
+		if trimLeaves != leaves || trimActions > actions || trim.DurationMS > rep.DurationMS {
+			t.Fatal(file, "TrimEnd mismatch", trimLeaves, leaves, trimActions, actions)
+		}
+
+		dec = reencode(t, trim)
+		if !reflect.DeepEqual(dec.Records, trim.Records) {
+			t.Fatal(file, "TrimEnd re-encode mismatch")
+		}
+	}
+}
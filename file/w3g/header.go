@@ -16,14 +16,19 @@ import (
 
 // Header for a Warcraft III recorded game file
 type Header struct {
-	GameVersion  w3gs.GameVersion
-	BuildNumber  uint16
-	DurationMS   uint32
-	SinglePlayer bool
+	GameVersion  w3gs.GameVersion `json:"GameVersion"`
+	BuildNumber  uint16           `json:"BuildNumber"`
+	DurationMS   uint32           `json:"DurationMS"`
+	SinglePlayer bool             `json:"SinglePlayer"`
 }
 
 // FindHeader in r
 func FindHeader(r Peeker) (int, error) {
+	return findHeader(r, nil)
+}
+
+// findHeader discards everything up to the header in r, calling skip (if not nil) on the discarded bytes
+func findHeader(r Peeker, skip func(b []byte)) (int, error) {
 	var n = 0
 
 	for {
@@ -37,6 +42,12 @@ func FindHeader(r Peeker) (int, error) {
 		if del < 0 {
 			del = 2048 - len(Signature) + 1
 		}
+		if del > len(b) {
+			del = len(b)
+		}
+		if skip != nil {
+			skip(b[:del])
+		}
 		nn, err := r.Discard(del)
 		n += nn
 
@@ -46,25 +57,35 @@ func FindHeader(r Peeker) (int, error) {
 	}
 }
 
-// DecodeHeader a w3g file, returns header and a Decompressor to read compressed records
-func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, error) {
+// rawHeader stores the header fields that are needed to decode the data section
+type rawHeader struct {
+	Header
+	SizeHeader uint32
+	SizeFile   uint32
+	SizeBlocks uint32
+	NumBlocks  uint32
+	ValidCRC   bool
+}
+
+// decodeHeader reads the header fields without validating them
+func decodeHeader(r io.Reader) (*rawHeader, int, error) {
 	var buf [68]byte
-	var hdr Header
+	var hdr rawHeader
 
 	n, err := io.ReadFull(r, buf[:64])
 	if err != nil {
-		return nil, nil, n, err
+		return nil, n, err
 	}
 
 	var pbuf = protocol.Buffer{Bytes: buf[:]}
 	if s, err := pbuf.ReadCString(); err != nil {
-		return nil, nil, n, err
+		return nil, n, err
 	} else if s != Signature {
-		return nil, nil, n, ErrBadFormat
+		return nil, n, ErrBadFormat
 	}
 
-	var sizeHeader = pbuf.ReadUInt32()
-	var sizeFile = pbuf.ReadUInt32()
+	hdr.SizeHeader = pbuf.ReadUInt32()
+	hdr.SizeFile = pbuf.ReadUInt32()
 
 	var headerVersion = pbuf.ReadUInt32()
 	switch headerVersion {
@@ -73,20 +94,20 @@ func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, er
 		nn, err := io.ReadFull(r, buf[64:68])
 		n += nn
 		if err != nil {
-			return nil, nil, n, err
+			return nil, n, err
 		}
 
 	default:
-		return nil, nil, n, ErrUnexpectedConst
+		return nil, n, ErrUnexpectedConst
 	}
 
-	var sizeBlocks = pbuf.ReadUInt32()
-	var numBlocks = pbuf.ReadUInt32()
+	hdr.SizeBlocks = pbuf.ReadUInt32()
+	hdr.NumBlocks = pbuf.ReadUInt32()
 
 	switch headerVersion {
 	case 0:
 		if pbuf.ReadUInt16() != 0 {
-			return nil, nil, n, ErrUnexpectedConst
+			return nil, n, ErrUnexpectedConst
 		}
 		hdr.GameVersion.Product = w3gs.ProductROC
 		hdr.GameVersion.Version = uint32(pbuf.ReadUInt16())
@@ -100,22 +121,33 @@ func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, er
 
 	var crc = pbuf.ReadUInt32()
 	buf[n-4], buf[n-3], buf[n-2], buf[n-1] = 0, 0, 0, 0
-	if crc != uint32(crc32.ChecksumIEEE(buf[0:n])) {
+	hdr.ValidCRC = crc == uint32(crc32.ChecksumIEEE(buf[0:n]))
+
+	return &hdr, n, nil
+}
+
+// DecodeHeader a w3g file, returns header and a Decompressor to read compressed records
+func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, error) {
+	hdr, n, err := decodeHeader(r)
+	if err != nil {
+		return nil, nil, n, err
+	}
+	if !hdr.ValidCRC {
 		return nil, nil, n, ErrInvalidChecksum
 	}
 
-	if uint32(n) > sizeHeader || uint32(n) > sizeFile {
+	if uint32(n) > hdr.SizeHeader || uint32(n) > hdr.SizeFile {
 		return nil, nil, n, ErrBadFormat
 	}
 
 	// Skip to start of data section
-	nn, err := io.CopyN(ioutil.Discard, r, int64(sizeHeader-uint32(n)))
+	nn, err := io.CopyN(ioutil.Discard, r, int64(hdr.SizeHeader-uint32(n)))
 	n += int(nn)
 	if err != nil {
 		return nil, nil, n, err
 	}
 
-	return &hdr, NewDecompressor(r, hdr.Encoding(), f, numBlocks, sizeBlocks), n, err
+	return &hdr.Header, NewDecompressor(r, hdr.Encoding(), f, hdr.NumBlocks, hdr.SizeBlocks), n, err
 }
 
 // Encoding for (de)serialization
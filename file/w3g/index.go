@@ -0,0 +1,253 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// IndexEntry describes the first record boundary in a compressed data block
+type IndexEntry struct {
+	Block      uint32 // Block number
+	Offset     uint32 // Compressed offset of block, relative to Index.DataStart
+	DataOffset uint32 // Decompressed offset of block
+	RecordSkip uint32 // Decompressed bytes in block before the first record boundary
+	Records    uint32 // Number of records before the boundary
+	TimeMS     uint32 // Game time at the boundary
+}
+
+// Index of the compressed data blocks in a replay, used to resume decoding at
+// a block boundary instead of decompressing all preceding data
+type Index struct {
+	Encoding  Encoding
+	DataStart int64  // Offset of first data block, relative to start of the replay header
+	NumBlocks uint32 // Number of compressed data blocks
+	SizeTotal uint32 // Overall size of decompressed data
+	Blocks    []IndexEntry
+}
+
+// BuildIndex reads the replay in r (positioned at the start of the header) and indexes its
+// data blocks. Blocks without record boundary (i.e. spanned by a single record) are omitted.
+func BuildIndex(r io.Reader) (*Header, *Index, error) {
+	return buildIndex(r, nil)
+}
+
+func buildIndex(r io.Reader, f func(r Record) error) (*Header, *Index, error) {
+	hdr, data, n, err := DecodeHeader(r, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var idx = Index{
+		Encoding:  data.Encoding,
+		DataStart: int64(n),
+		NumBlocks: data.NumBlocks,
+		SizeTotal: data.SizeTotal,
+	}
+
+	// Blocks are read ahead of records, keep a queue of blocks that do not have a boundary yet
+	var pending []IndexEntry
+	var pendingSize []uint32
+	var dataOffset uint32
+	data.onBlock = func(offset uint32, size uint32) {
+		pending = append(pending, IndexEntry{
+			Block:      idx.NumBlocks - data.NumBlocks - 1,
+			Offset:     offset,
+			DataOffset: dataOffset,
+		})
+		pendingSize = append(pendingSize, size)
+		dataOffset += size
+	}
+
+	var pos uint32
+	var rec uint32
+	var time uint32
+
+	var bufr = bufio.NewReaderSize(data, 8192)
+	for {
+		r, n, err := data.RecordDecoder.Read(bufr)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		// Block containing the start of r is known after reading r
+		for len(pending) > 0 && pending[0].DataOffset+pendingSize[0] <= pos {
+			pending, pendingSize = pending[1:], pendingSize[1:]
+		}
+		if len(pending) > 0 && pending[0].DataOffset <= pos {
+			var e = pending[0]
+			e.RecordSkip = pos - e.DataOffset
+			e.Records = rec
+			e.TimeMS = time
+			idx.Blocks = append(idx.Blocks, e)
+			pending, pendingSize = pending[1:], pendingSize[1:]
+		}
+
+		pos += uint32(n)
+		rec++
+
+		if f != nil {
+			if err := f(r); err != nil {
+				return nil, nil, err
+			}
+		}
+		if ts, ok := r.(*TimeSlot); ok {
+			time += uint32(ts.TimeIncrementMS)
+		}
+	}
+
+	return hdr, &idx, nil
+}
+
+// Find returns the last entry with a game time before ms, or the first entry if there is none.
+// Returns nil if the index is empty.
+func (idx *Index) Find(ms uint32) *IndexEntry {
+	if len(idx.Blocks) == 0 {
+		return nil
+	}
+
+	var i = sort.Search(len(idx.Blocks), func(i int) bool { return idx.Blocks[i].TimeMS >= ms })
+	if i > 0 {
+		i--
+	}
+	return &idx.Blocks[i]
+}
+
+// Seek positions r at the record boundary returned by Find(ms), base is the offset of the
+// replay header in r. Returns a Decompressor that resumes decoding from the boundary.
+// The Decompressor reads ahead, so r must not be used until it is done.
+func (idx *Index) Seek(r io.ReadSeeker, base int64, ms uint32) (*Decompressor, *IndexEntry, error) {
+	var e = idx.Find(ms)
+	if e == nil {
+		return nil, nil, io.EOF
+	}
+
+	if _, err := r.Seek(base+idx.DataStart+int64(e.Offset), io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	var d = NewDecompressor(bufio.NewReaderSize(r, 8192), idx.Encoding, nil, idx.NumBlocks-e.Block, idx.SizeTotal-e.DataOffset)
+	if _, err := io.CopyN(ioutil.Discard, d, int64(e.RecordSkip)); err != nil {
+		return nil, nil, err
+	}
+
+	return d, e, nil
+}
+
+// LazyReplay provides random access to the records of a replay file.
+// Only the game setup records and block index are kept in memory.
+type LazyReplay struct {
+	Replay // Records is always empty
+	Index
+
+	r    io.ReadSeeker
+	base int64
+}
+
+// OpenLazy opens a w3g file for random access, call Close when done
+func OpenLazy(name string) (*LazyReplay, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rep, err := NewLazyReplay(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return rep, nil
+}
+
+// NewLazyReplay indexes the replay in r, r must remain valid while the LazyReplay is used
+func NewLazyReplay(r io.ReadSeeker) (*LazyReplay, error) {
+	base, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	var b = bufio.NewReaderSize(r, 8192)
+	n, err := FindHeader(b)
+	if err != nil {
+		return nil, ErrBadFormat
+	}
+
+	var res = LazyReplay{r: r, base: base + int64(n)}
+	hdr, idx, err := buildIndex(b, func(r Record) error {
+		res.setup(r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.Header = *hdr
+	res.Index = *idx
+	res.defaultSlots()
+
+	return &res, nil
+}
+
+// Close closes the underlying reader if it implements io.Closer
+func (l *LazyReplay) Close() error {
+	if c, ok := l.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Seek to the nearest block boundary before game time ms, returns a Decompressor that
+// resumes decoding from there and the game time at the boundary
+func (l *LazyReplay) Seek(ms uint32) (*Decompressor, uint32, error) {
+	d, e, err := l.Index.Seek(l.r, l.base, ms)
+	if err != nil {
+		return nil, 0, err
+	}
+	return d, e.TimeMS, nil
+}
+
+// ForEach game record starting at game time ms call f with the record and its game time
+func (l *LazyReplay) ForEach(ms uint32, f func(r Record, ms uint32) error) error {
+	d, time, err := l.Seek(ms)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return d.ForEach(func(r Record) error {
+		var t = time
+		if ts, ok := r.(*TimeSlot); ok {
+			time += uint32(ts.TimeIncrementMS)
+		}
+		if t < ms || !isGameRecord(r) {
+			return nil
+		}
+		return f(r, t)
+	})
+}
+
+// Range returns the game records between game time from (inclusive) and to (exclusive)
+func (l *LazyReplay) Range(from uint32, to uint32) ([]Record, error) {
+	var res []Record
+	var errStop = io.EOF
+	if err := l.ForEach(from, func(r Record, ms uint32) error {
+		if ms >= to {
+			return errStop
+		}
+		res = append(res, r)
+		return nil
+	}); err != nil && err != errStop {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,160 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+type timedRecord struct {
+	ms  uint32
+	rec w3g.Record
+}
+
+func timeRecords(records []w3g.Record) []timedRecord {
+	var res []timedRecord
+	var time uint32
+	for _, r := range records {
+		res = append(res, timedRecord{time, r})
+		if ts, ok := r.(*w3g.TimeSlot); ok {
+			time += uint32(ts.TimeIncrementMS)
+		}
+	}
+	return res
+}
+
+func TestIndex(t *testing.T) {
+	for _, file := range []string{"test_102.w3g", "test_126.w3g", "test_130.w3g", "test_132.w3g"} {
+		rep, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		lazy, err := w3g.OpenLazy(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		if !reflect.DeepEqual(lazy.Header, rep.Header) || !reflect.DeepEqual(lazy.GameInfo, rep.GameInfo) ||
+			!reflect.DeepEqual(lazy.SlotInfo, rep.SlotInfo) || !reflect.DeepEqual(lazy.PlayerInfo, rep.PlayerInfo) ||
+			!reflect.DeepEqual(lazy.PlayerExtra, rep.PlayerExtra) || len(lazy.Replay.Records) != 0 {
+			t.Fatal(file, "LazyReplay setup mismatch")
+		}
+
+		if len(lazy.Blocks) == 0 || lazy.Blocks[0].Block != 0 || lazy.Blocks[0].RecordSkip != 0 {
+			t.Fatal(file, "Index mismatch", lazy.Blocks)
+		}
+		for i := 1; i < len(lazy.Blocks); i++ {
+			if lazy.Blocks[i].Block <= lazy.Blocks[i-1].Block || lazy.Blocks[i].TimeMS < lazy.Blocks[i-1].TimeMS {
+				t.Fatal(file, "Index not ordered", lazy.Blocks[i-1], lazy.Blocks[i])
+			}
+		}
+
+		var all = timeRecords(rep.Records)
+		var end = all[len(all)-1].ms + 1
+		for _, from := range []uint32{0, 1, end / 3, end / 2, end - 1, end} {
+			for _, to := range []uint32{from + 1000, from + 60000, end} {
+				var expected []w3g.Record
+				for _, r := range all {
+					if r.ms >= from && r.ms < to {
+						expected = append(expected, r.rec)
+					}
+				}
+
+				rec, err := lazy.Range(from, to)
+				if err != nil {
+					t.Fatal(file, from, to, err)
+				}
+				if !reflect.DeepEqual(rec, expected) {
+					t.Fatalf("%v Range(%v, %v) mismatch, %v != %v\n", file, from, to, len(rec), len(expected))
+				}
+			}
+		}
+
+		if err := lazy.Close(); err != nil {
+			t.Fatal(file, err)
+		}
+	}
+}
+
+func TestIndexPrefix(t *testing.T) {
+	b, err := ioutil.ReadFile("test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data = append(bytes.Repeat([]byte{'x'}, 3000), b...)
+	hdr, idx, err := w3g.BuildIndex(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lazy, err := w3g.NewLazyReplay(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lazy.Header, *hdr) || !reflect.DeepEqual(lazy.Index, *idx) {
+		t.Fatal("Index mismatch")
+	}
+
+	var last = idx.Blocks[len(idx.Blocks)-1]
+	d, ms, err := lazy.Seek(last.TimeMS + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms != last.TimeMS {
+		t.Fatal("Seek mismatch", ms)
+	}
+
+	var n = 0
+	if err := d.ForEach(func(r w3g.Record) error { n++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("Expected records after seek")
+	}
+}
+
+type countingReader struct {
+	io.ReadSeeker
+	reads int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	r.reads++
+	return r.ReadSeeker.Read(b)
+}
+
+func TestIndexSeekBuffered(t *testing.T) {
+	b, err := ioutil.ReadFile("test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, idx, err := w3g.BuildIndex(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r = countingReader{ReadSeeker: bytes.NewReader(b)}
+	d, _, err := idx.Seek(&r, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ForEach(func(r w3g.Record) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unbuffered, the decompressor reads the underlying reader byte by byte
+	if r.reads > len(b)/1024 {
+		t.Fatal("Too many reads", r.reads)
+	}
+}
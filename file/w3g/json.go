@@ -0,0 +1,178 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// JSONSchemaVersion is the version of the JSON representation of Replay
+// It is only incremented for changes that break existing documents (see RecordJSON).
+const JSONSchemaVersion = 1
+
+// RecordTypes maps record type names (as used in JSON) to a constructor for that type
+var RecordTypes = map[string]func() Record{
+	"GameInfo":       func() Record { return &GameInfo{} },
+	"PlayerInfo":     func() Record { return &PlayerInfo{} },
+	"PlayerLeft":     func() Record { return &PlayerLeft{} },
+	"SlotInfo":       func() Record { return &SlotInfo{} },
+	"CountDownStart": func() Record { return &CountDownStart{} },
+	"CountDownEnd":   func() Record { return &CountDownEnd{} },
+	"GameStart":      func() Record { return &GameStart{} },
+	"TimeSlot":       func() Record { return &TimeSlot{} },
+	"ChatMessage":    func() Record { return &ChatMessage{} },
+	"TimeSlotAck":    func() Record { return &TimeSlotAck{} },
+	"Desync":         func() Record { return &Desync{} },
+	"EndTimer":       func() Record { return &EndTimer{} },
+	"PlayerExtra":    func() Record { return &PlayerExtra{} },
+}
+
+// RecordType returns the type name of r (i.e. "TimeSlot")
+func RecordType(r Record) string {
+	var t = reflect.TypeOf(r)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// RecordJSON wraps a Record with a type discriminator for JSON (un)marshaling.
+//
+// Schema:
+//
+//	{
+//	  "type":   string  | record type name (see RecordTypes)
+//	  "record": object  | exported fields of the record type, embedded structs are
+//	                    | flattened and byte slices are base64 encoded
+//	}
+//
+// Record fields (and fields of embedded w3gs types) have explicit json tags that match their
+// Go names, so fields can be renamed without breaking stored documents. Changing a tag
+// requires incrementing JSONSchemaVersion.
+type RecordJSON struct {
+	Record
+}
+
+type recordJSON struct {
+	Type   string          `json:"type"`
+	Record json.RawMessage `json:"record"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r RecordJSON) MarshalJSON() ([]byte, error) {
+	if r.Record == nil {
+		return nil, ErrUnknownRecord
+	}
+
+	b, err := json.Marshal(r.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&recordJSON{
+		Type:   RecordType(r.Record),
+		Record: b,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *RecordJSON) UnmarshalJSON(b []byte) error {
+	var v recordJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	var fun, ok = RecordTypes[v.Type]
+	if !ok {
+		return ErrUnknownRecord
+	}
+
+	var rec = fun()
+	if len(v.Record) > 0 {
+		if err := json.Unmarshal(v.Record, rec); err != nil {
+			return err
+		}
+	}
+
+	r.Record = rec
+	return nil
+}
+
+// replayJSON is the JSON representation of Replay.
+//
+// Schema:
+//
+//	{
+//	  "schema":  number   | JSONSchemaVersion
+//	  "header":  object   | Header
+//	  "game":    object   | GameInfo (includes host player)
+//	  "players": [object] | PlayerInfo for every player but the host
+//	  "extra":   [object] | PlayerExtra
+//	  "slots":   object   | SlotInfo
+//	  "records": [object] | RecordJSON for every game record
+//	}
+type replayJSON struct {
+	Schema      int            `json:"schema"`
+	Header      Header         `json:"header"`
+	GameInfo    GameInfo       `json:"game"`
+	PlayerInfo  []*PlayerInfo  `json:"players"`
+	PlayerExtra []*PlayerExtra `json:"extra"`
+	SlotInfo    SlotInfo       `json:"slots"`
+	Records     []RecordJSON   `json:"records"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r *Replay) MarshalJSON() ([]byte, error) {
+	var v = replayJSON{
+		Schema:      JSONSchemaVersion,
+		Header:      r.Header,
+		GameInfo:    r.GameInfo,
+		PlayerInfo:  []*PlayerInfo{},
+		PlayerExtra: r.PlayerExtra,
+		SlotInfo:    r.SlotInfo,
+		Records:     make([]RecordJSON, len(r.Records)),
+	}
+
+	for _, p := range r.PlayerInfo {
+		if p.ID != r.HostPlayer.ID {
+			v.PlayerInfo = append(v.PlayerInfo, p)
+		}
+	}
+	if v.PlayerExtra == nil {
+		v.PlayerExtra = []*PlayerExtra{}
+	}
+	for i, rec := range r.Records {
+		v.Records[i].Record = rec
+	}
+
+	return json.Marshal(&v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *Replay) UnmarshalJSON(b []byte) error {
+	var v replayJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Schema != JSONSchemaVersion {
+		return ErrBadFormat
+	}
+
+	*r = Replay{
+		Header:      v.Header,
+		GameInfo:    v.GameInfo,
+		SlotInfo:    v.SlotInfo,
+		PlayerExtra: v.PlayerExtra,
+		Records:     make([]Record, len(v.Records)),
+	}
+
+	r.PlayerInfo = append([]*PlayerInfo{&r.GameInfo.HostPlayer}, v.PlayerInfo...)
+	for i, rec := range v.Records {
+		r.Records[i] = rec.Record
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestRecordJSON(t *testing.T) {
+	var records = []w3g.Record{
+		&w3g.PlayerLeft{Local: true, PlayerID: 2, Reason: w3gs.LeaveLost, Counter: 3},
+		&w3g.GameStart{},
+		&w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{
+			Fragment:        true,
+			TimeIncrementMS: 100,
+			Actions:         []w3gs.PlayerAction{{PlayerID: 1, Data: []byte{0x01, 0x02}}},
+		}},
+		&w3g.ChatMessage{Message: w3gs.Message{SenderID: 1, Type: w3gs.MsgChatExtra, Scope: w3gs.ScopeAllies, Content: "gl"}},
+		&w3g.TimeSlotAck{Checksum: []byte{1, 2, 3, 4}},
+		&w3g.EndTimer{GameOver: true, CountDownSec: 5},
+	}
+
+	for _, r := range records {
+		b, err := json.Marshal(w3g.RecordJSON{Record: r})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var v w3g.RecordJSON
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(r, v.Record) {
+			t.Fatalf("Record mismatch for %s: %+v != %+v", w3g.RecordType(r), r, v.Record)
+		}
+	}
+
+	var v w3g.RecordJSON
+	if err := json.Unmarshal([]byte(`{"type":"Foo","record":{}}`), &v); err != w3g.ErrUnknownRecord {
+		t.Fatal("ErrUnknownRecord expected, got", err)
+	}
+	if err := json.Unmarshal([]byte(`{"type":"GameStart"}`), &v); err != nil || w3g.RecordType(v.Record) != "GameStart" {
+		t.Fatal("GameStart expected", err)
+	}
+}
+
+func TestRecordJSONKeys(t *testing.T) {
+	var rec = w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{
+		TimeIncrementMS: 100,
+		Actions:         []w3gs.PlayerAction{{PlayerID: 1, Data: []byte{0x01}}},
+	}}
+
+	b, err := json.Marshal(w3g.RecordJSON{Record: &rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys are part of the schema
+	var expected = `{"type":"TimeSlot","record":{"Fragment":false,"TimeIncrementMS":100,"Actions":[{"PlayerID":1,"Data":"AQ=="}]}}`
+	if string(b) != expected {
+		t.Fatal("JSON mismatch", string(b))
+	}
+}
+
+func TestReplayJSON(t *testing.T) {
+	var files = []string{
+		"test_102.w3g",
+		"test_126.w3g",
+		"test_130.w3g",
+		"test_132.w3g",
+	}
+
+	for _, file := range files {
+		rep, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		b, err := json.Marshal(rep)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var res w3g.Replay
+		if err := json.Unmarshal(b, &res); err != nil {
+			t.Fatal(file, err)
+		}
+
+		if res.Header != rep.Header || !reflect.DeepEqual(res.GameInfo, rep.GameInfo) || len(res.PlayerInfo) != len(rep.PlayerInfo) {
+			t.Fatal(file, "Setup mismatch")
+		}
+		if res.PlayerInfo[0] != &res.GameInfo.HostPlayer {
+			t.Fatal(file, "Host should be first player")
+		}
+
+		var buf1 bytes.Buffer
+		var buf2 bytes.Buffer
+		if err := rep.Encode(&buf1); err != nil {
+			t.Fatal(file, err)
+		}
+		if err := res.Encode(&buf2); err != nil {
+			t.Fatal(file, err)
+		}
+		if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+			t.Fatal(file, "Encoded replay mismatch")
+		}
+
+		dec, err := w3g.Decode(&buf2)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if len(dec.Records) != len(rep.Records) {
+			t.Fatal(file, "Record count mismatch")
+		}
+	}
+
+	var res w3g.Replay
+	if err := json.Unmarshal([]byte(`{"schema":0}`), &res); err != w3g.ErrBadFormat {
+		t.Fatal("ErrBadFormat expected, got", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// NWGHeader is the header that Netease prepends to replays (.nwg files)
+//
+// Its layout is undocumented and no sample has been verified, so everything
+// before the w3g header is stored and written back as is.
+type NWGHeader struct {
+	Data []byte
+}
+
+// FindNWGHeader reads everything in r up to the w3g header, returns nil if the header is found at the start
+func FindNWGHeader(r Peeker) (*NWGHeader, error) {
+	var data []byte
+	if _, err := findHeader(r, func(b []byte) { data = append(data, b...) }); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return &NWGHeader{Data: data}, nil
+}
+
+// DecodeNWG decodes a replay that may be prefixed by an nwg header
+func DecodeNWG(r io.Reader) (*Replay, *NWGHeader, error) {
+	var b = bufio.NewReaderSize(r, 8192)
+	nwg, err := FindNWGHeader(b)
+	if err != nil {
+		return nil, nil, ErrBadFormat
+	}
+
+	rep, err := Decode(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rep, nwg, nil
+}
+
+// OpenNWG opens a w3g or nwg file
+func OpenNWG(name string) (*Replay, *NWGHeader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	return DecodeNWG(f)
+}
+
+// EncodeNWG encodes r to w, prefixed by nwg (if not nil)
+func (r *Replay) EncodeNWG(w io.Writer, nwg *NWGHeader) error {
+	if nwg != nil {
+		if _, err := w.Write(nwg.Data); err != nil {
+			return err
+		}
+	}
+	return r.Encode(w)
+}
+
+// SaveNWG saves r to a file, prefixed by nwg (if not nil)
+func (r *Replay) SaveNWG(name string, nwg *NWGHeader) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.EncodeNWG(f, nwg)
+}
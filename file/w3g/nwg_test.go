@@ -0,0 +1,54 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g_test
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+func TestNWG(t *testing.T) {
+	file, err := os.ReadFile("test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rep, nwg, err := w3g.DecodeNWG(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nwg != nil {
+		t.Fatal("Expected no nwg header for w3g file")
+	}
+
+	var prefix = w3g.NWGHeader{Data: make([]byte, 5000)}
+	for i := range prefix.Data {
+		prefix.Data[i] = byte(i)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.EncodeNWG(&buf, &prefix); err != nil {
+		t.Fatal(err)
+	}
+
+	res, nwg, err := w3g.DecodeNWG(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nwg == nil || !reflect.DeepEqual(*nwg, prefix) {
+		t.Fatal("Prefix mismatch")
+	}
+	if res.Header != rep.Header || len(res.Records) != len(rep.Records) {
+		t.Fatal("Replay mismatch")
+	}
+
+	if _, _, err := w3g.DecodeNWG(bytes.NewReader(prefix.Data)); err != w3g.ErrBadFormat {
+		t.Fatal("ErrBadFormat expected, got", err)
+	}
+}
@@ -0,0 +1,277 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Upper bounds for block sizes, blocks are allocated in full before they are inflated.
+// Blizzard writes blocks of 8 KiB, deflate adds at most a few bytes per 64 KiB.
+const (
+	maxBlockSize   = 64 * 1024
+	maxDeflateSize = maxBlockSize + 1024
+)
+
+// ParallelDecompressor is an io.Reader that decompresses data blocks concurrently
+//
+// Compressed blocks are read ahead (up to twice the number of workers) and inflated
+// in a worker pool, decompressed data is returned in the original block order.
+// Blocks larger than 64 KiB are rejected with ErrBadFormat.
+// Close must be called if the data is not read until the end. Not thread-safe.
+type ParallelDecompressor struct {
+	RecordDecoder
+
+	SizeTotal uint32 // Decompressed size left to read in total
+
+	r         io.Reader
+	numBlocks uint32
+	workers   int
+
+	once  sync.Once
+	close sync.Once
+	done  chan struct{}
+	queue chan chan parallelBlock
+	jobs  chan parallelJob
+	wg    sync.WaitGroup
+
+	cur  []byte
+	err  error
+	bufr *bufio.Reader
+}
+
+type parallelBlock struct {
+	data []byte
+	err  error
+}
+
+type parallelJob struct {
+	data      []byte
+	sizeBlock uint32
+	crcData   uint16
+	res       chan parallelBlock
+}
+
+// NewParallelDecompressor for compressed w3g data, using workers goroutines to inflate blocks (GOMAXPROCS if <= 0)
+func NewParallelDecompressor(r io.Reader, e Encoding, f RecordFactory, numBlocks uint32, sizeTotal uint32, workers int) *ParallelDecompressor {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return &ParallelDecompressor{
+		RecordDecoder: RecordDecoder{
+			RecordFactory: f,
+			Encoding:      e,
+		},
+		SizeTotal: sizeTotal,
+		r:         r,
+		numBlocks: numBlocks,
+		workers:   workers,
+		done:      make(chan struct{}),
+	}
+}
+
+func (d *ParallelDecompressor) start() {
+	d.once.Do(func() {
+		d.queue = make(chan chan parallelBlock, d.workers*2)
+		d.jobs = make(chan parallelJob, d.workers)
+
+		d.wg.Add(d.workers + 1)
+		for i := 0; i < d.workers; i++ {
+			go d.inflate()
+		}
+		go d.readBlocks()
+	})
+}
+
+// readBlocks reads compressed blocks sequentially and queues them for inflation
+func (d *ParallelDecompressor) readBlocks() {
+	defer d.wg.Done()
+	defer close(d.jobs)
+	defer close(d.queue)
+
+	var buf [12]byte
+	var lenHead = blockHeaderSize(d.GameVersion)
+
+	for i := uint32(0); i < d.numBlocks; i++ {
+		var job = parallelJob{res: make(chan parallelBlock, 1)}
+
+		var err error
+		if _, err = io.ReadFull(d.r, buf[:lenHead]); err == nil {
+			lenDeflate, sizeBlock, crcData, valid := decodeBlockHeader(buf[:lenHead], d.GameVersion)
+			if !valid {
+				err = ErrInvalidChecksum
+			} else if sizeBlock > maxBlockSize || lenDeflate > maxDeflateSize {
+				err = ErrBadFormat
+			} else {
+				job.data = make([]byte, lenDeflate)
+				job.sizeBlock = sizeBlock
+				job.crcData = crcData
+				if _, err = io.ReadFull(d.r, job.data); err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+			}
+		}
+
+		select {
+		case d.queue <- job.res:
+		case <-d.done:
+			return
+		}
+
+		if err != nil {
+			job.res <- parallelBlock{err: err}
+			return
+		}
+
+		select {
+		case d.jobs <- job:
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// inflate decompresses queued blocks until the job channel is closed
+func (d *ParallelDecompressor) inflate() {
+	defer d.wg.Done()
+
+	var z io.ReadCloser
+	var b bytes.Reader
+	var one [1]byte
+
+	for job := range d.jobs {
+		if dataChecksum(job.data) != job.crcData {
+			job.res <- parallelBlock{err: ErrInvalidChecksum}
+			continue
+		}
+
+		b.Reset(job.data)
+
+		var err error
+		if z == nil {
+			z, err = zlib.NewReader(&b)
+		} else {
+			err = z.(zlib.Resetter).Reset(&b, nil)
+		}
+
+		var res = parallelBlock{data: make([]byte, job.sizeBlock)}
+		if err == nil {
+			_, err = io.ReadFull(z, res.data)
+		}
+		if err == nil && b.Len() > 0 {
+			// Consume zlib trailer, there should be no data left
+			if n, _ := z.Read(one[:]); n > 0 || b.Len() > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		res.err = err
+		job.res <- res
+	}
+}
+
+// Read implements the io.Reader interface.
+func (d *ParallelDecompressor) Read(b []byte) (int, error) {
+	if d.SizeTotal == 0 {
+		return 0, io.EOF
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	d.start()
+
+	var n = 0
+	var l = len(b)
+	if uint32(l) > d.SizeTotal {
+		b = b[:d.SizeTotal]
+		l = len(b)
+	}
+
+	for n != l {
+		if len(d.cur) == 0 {
+			res, ok := <-d.queue
+			if !ok {
+				d.err = io.EOF
+				return n, d.err
+			}
+
+			var blk = <-res
+			if blk.err != nil {
+				d.err = blk.err
+				d.Close()
+				return n, d.err
+			}
+			d.cur = blk.data
+		}
+
+		var nn = copy(b[n:], d.cur)
+		d.cur = d.cur[nn:]
+		d.SizeTotal -= uint32(nn)
+		n += nn
+	}
+
+	if d.SizeTotal == 0 {
+		d.Close()
+	}
+
+	return n, nil
+}
+
+// ForEach record call f
+func (d *ParallelDecompressor) ForEach(f func(r Record) error) error {
+	if d.bufr == nil {
+		d.bufr = bufio.NewReaderSize(d, 8192)
+	}
+
+	for {
+		rec, _, err := d.RecordDecoder.Read(d.bufr)
+		switch err {
+		case nil:
+			if err := f(rec); err != nil {
+				return err
+			}
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// Close stops reading ahead and waits for all workers to exit
+func (d *ParallelDecompressor) Close() error {
+	d.close.Do(func() {
+		close(d.done)
+	})
+	d.wg.Wait()
+
+	if d.err == nil {
+		d.err = io.EOF
+	}
+	return nil
+}
+
+// DecodeParallel decodes a w3g file, inflating data blocks with workers goroutines (GOMAXPROCS if <= 0)
+func DecodeParallel(r io.Reader, workers int) (*Replay, error) {
+	hdr, data, _, err := DecodeHeader(r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var p = NewParallelDecompressor(r, data.Encoding, nil, data.NumBlocks, data.SizeTotal, workers)
+	defer p.Close()
+
+	return decodeRecords(hdr, p.ForEach)
+}
@@ -0,0 +1,256 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g_test
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+var replayFiles = []string{
+	"test_102.w3g",
+	"test_126.w3g",
+	"test_130.w3g",
+	"test_132.w3g",
+}
+
+func TestParallelDecompressor(t *testing.T) {
+	var b protocol.Buffer
+	var c = w3g.NewCompressor(&b, w3g.Encoding{})
+	for i := 0; i < 1000; i++ {
+		if _, err := c.WriteRecord(&w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{
+			TimeIncrementMS: uint16(i),
+			Actions:         ts.Actions,
+		}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 2, 8} {
+		var i = 0
+		var d = w3g.NewParallelDecompressor(bytes.NewReader(b.Bytes), w3g.Encoding{}, nil, c.NumBlocks, c.SizeTotal, workers)
+		if err := d.ForEach(func(r w3g.Record) error {
+			s, ok := r.(*w3g.TimeSlot)
+			if !ok {
+				t.Fatal("Expected TimeSlot")
+			}
+			if s.TimeIncrementMS != uint16(i) || !reflect.DeepEqual(s.Actions, ts.Actions) {
+				t.Fatal("Corrupt data")
+			}
+			i++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if i != 1000 {
+			t.Fatalf("Expected 1000 records, but got %d", i)
+		}
+		if d.SizeTotal != 0 {
+			t.Fatalf("Expected nothing left to read, but got %d", d.SizeTotal)
+		}
+	}
+
+	// Stop early
+	var d = w3g.NewParallelDecompressor(bytes.NewReader(b.Bytes), w3g.Encoding{}, nil, c.NumBlocks, c.SizeTotal, 2)
+	var stop = errors.New("stop")
+	if err := d.ForEach(func(r w3g.Record) error { return stop }); err != stop {
+		t.Fatal("Expected stop, got", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatal("Expected EOF after Close, got", err)
+	}
+
+	// Oversized blocks
+	for _, size := range [][2]uint32{{0x7FFFFFFF, 8192}, {64, 0x7FFFFFFF}} {
+		var blk = append([]byte{}, b.Bytes...)
+		var hdr = protocol.Buffer{Bytes: blk[:0]}
+		hdr.WriteUInt32(size[0])
+		hdr.WriteUInt32(size[1])
+		hdr.WriteUInt32(0)
+		var crc = crc32.ChecksumIEEE(blk[:12])
+		blk[8], blk[9] = byte(crc^crc>>16), byte((crc^crc>>16)>>8)
+		blk[10], blk[11] = b.Bytes[10], b.Bytes[11]
+
+		var d = w3g.NewParallelDecompressor(bytes.NewReader(blk), w3g.Encoding{}, nil, c.NumBlocks, c.SizeTotal, 2)
+		if _, err := d.Read(make([]byte, 1)); err != w3g.ErrBadFormat {
+			t.Fatal("Expected ErrBadFormat, got", err)
+		}
+	}
+
+	// Corrupt data
+	var corrupt = append([]byte{}, b.Bytes...)
+	corrupt[len(corrupt)/2]++
+	d = w3g.NewParallelDecompressor(bytes.NewReader(corrupt), w3g.Encoding{}, nil, c.NumBlocks, c.SizeTotal, 2)
+	if _, err := io.Copy(ioutil.Discard, d); err != w3g.ErrInvalidChecksum {
+		t.Fatal("Expected ErrInvalidChecksum, got", err)
+	}
+
+	// Truncated data
+	d = w3g.NewParallelDecompressor(bytes.NewReader(b.Bytes[:len(b.Bytes)/2]), w3g.Encoding{}, nil, c.NumBlocks, c.SizeTotal, 2)
+	if _, err := io.Copy(ioutil.Discard, d); err != io.ErrUnexpectedEOF {
+		t.Fatal("Expected ErrUnexpectedEOF, got", err)
+	}
+}
+
+func TestDecodeParallel(t *testing.T) {
+	for _, file := range replayFiles {
+		f, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ref, err := w3g.Decode(bytes.NewReader(f))
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		rep, err := w3g.DecodeParallel(bytes.NewReader(f), 4)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		if !reflect.DeepEqual(ref, rep) {
+			t.Fatal(file, "Replay mismatch")
+		}
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	var dir = t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []string
+	for i, file := range replayFiles {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var name = filepath.Join(dir, file)
+		if i%2 == 1 {
+			name = filepath.Join(dir, "sub", file)
+		}
+		if err := os.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, name)
+	}
+
+	var bad = filepath.Join(dir, "sub", "bad.W3G")
+	if err := os.WriteFile(bad, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected, bad)
+	sort.Strings(expected)
+
+	var paths []string
+	if err := w3g.OpenDir(dir, 2, func(res *w3g.BatchResult) error {
+		paths = append(paths, res.Path)
+		if res.Path == bad {
+			if res.Err == nil {
+				t.Fatal("Expected error for", res.Path)
+			}
+		} else if res.Err != nil || res.Replay == nil || len(res.Replay.Records) == 0 {
+			t.Fatal(res.Path, res.Err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(paths)
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatal("Path mismatch", paths)
+	}
+
+	var stop = errors.New("stop")
+	var n = 0
+	if err := w3g.OpenDir(dir, 1, func(res *w3g.BatchResult) error {
+		n++
+		return stop
+	}); err != stop || n != 1 {
+		t.Fatal("Expected stop after first file, got", err, n)
+	}
+
+	if err := w3g.OpenDir(filepath.Join(dir, "missing"), 1, func(res *w3g.BatchResult) error { return nil }); !os.IsNotExist(err) {
+		t.Fatal("Expected ErrNotExist, got", err)
+	}
+}
+
+func benchmarkDecode(b *testing.B, decode func(r io.Reader) (*w3g.Replay, error)) {
+	f, err := os.ReadFile("test_130.w3g")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(f)))
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := decode(bytes.NewReader(f)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	benchmarkDecode(b, w3g.Decode)
+}
+
+func BenchmarkDecodeParallel(b *testing.B) {
+	benchmarkDecode(b, func(r io.Reader) (*w3g.Replay, error) { return w3g.DecodeParallel(r, 0) })
+}
+
+func benchmarkOpenDir(b *testing.B, workers int) {
+	var dir = b.TempDir()
+	var size int64
+	for i := 0; i < 4; i++ {
+		for _, file := range replayFiles {
+			f, err := os.ReadFile(file)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, string(rune('a'+i))+file), f, 0644); err != nil {
+				b.Fatal(err)
+			}
+			size += int64(len(f))
+		}
+	}
+
+	b.SetBytes(size)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if err := w3g.OpenDir(dir, workers, func(res *w3g.BatchResult) error { return res.Err }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOpenDir1(b *testing.B) { benchmarkOpenDir(b, 1) }
+func BenchmarkOpenDir(b *testing.B)  { benchmarkOpenDir(b, 0) }
@@ -64,12 +64,12 @@ var DefaultFactory = MapFactory{
 //	    4 byte | GameType
 //	    4 byte | LanguageID
 type GameInfo struct {
-	HostPlayer   PlayerInfo
-	GameName     string
-	GameSettings w3gs.GameSettings
-	GameFlags    w3gs.GameFlags
-	NumSlots     uint32
-	LanguageID   uint32
+	HostPlayer   PlayerInfo        `json:"HostPlayer"`
+	GameName     string            `json:"GameName"`
+	GameSettings w3gs.GameSettings `json:"GameSettings"`
+	GameFlags    w3gs.GameFlags    `json:"GameFlags"`
+	NumSlots     uint32            `json:"NumSlots"`
+	LanguageID   uint32            `json:"LanguageID"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -163,10 +163,10 @@ func (rec *GameInfo) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //	              |   0x20=random
 //	              |   0x40=race selectable/fixed
 type PlayerInfo struct {
-	ID          uint8
-	Name        string
-	Race        w3gs.RacePref
-	JoinCounter uint32
+	ID          uint8         `json:"ID"`
+	Name        string        `json:"Name"`
+	Race        w3gs.RacePref `json:"Race"`
+	JoinCounter uint32        `json:"JoinCounter"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -267,10 +267,10 @@ func (rec *PlayerInfo) DeserializeContent(buf *protocol.Buffer, enc *Encoding) e
 //	   1 dword | result - see table below
 //	   1 dword | unknown (number of replays saved this warcraft session?)
 type PlayerLeft struct {
-	Local    bool
-	PlayerID uint8
-	Reason   w3gs.LeaveReason
-	Counter  uint32
+	Local    bool             `json:"Local"`
+	PlayerID uint8            `json:"PlayerID"`
+	Reason   w3gs.LeaveReason `json:"Reason"`
+	Counter  uint32           `json:"Counter"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -666,7 +666,7 @@ func (rec *ChatMessage) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //	   1 byte  | number of bytes following (always 0x04 so far)
 //	   1 dword | checksum
 type TimeSlotAck struct {
-	Checksum []byte
+	Checksum []byte `json:"Checksum"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -744,8 +744,8 @@ func (rec *Desync) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //	           |   0x01 countdown is over (end is forced *now*)
 //	   1 dword | countdown time in sec
 type EndTimer struct {
-	GameOver     bool
-	CountDownSec uint32
+	GameOver     bool   `json:"GameOver"`
+	CountDownSec uint32 `json:"CountDownSec"`
 }
 
 // Serialize encodes the struct into its binary form.
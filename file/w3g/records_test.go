@@ -130,6 +130,20 @@ func TestRecords(t *testing.T) {
 			CountDownSec: 5,
 		},
 		&w3g.PlayerExtra{},
+		&w3g.PlayerExtra{
+			PlayerExtra: w3gs.PlayerExtra{
+				Type: w3gs.PlayerUnits,
+				Units: []w3gs.PlayerDataUnits{
+					w3gs.PlayerDataUnits{
+						PlayerID: 6,
+						Race:     3,
+						Units: []w3gs.PlayerDataUnit{
+							w3gs.PlayerDataUnit{Unit: 1969711215, Count: 1},
+						},
+					},
+				},
+			},
+		},
 		&w3g.PlayerExtra{
 			PlayerExtra: w3gs.PlayerExtra{
 				Type: w3gs.PlayerProfile,
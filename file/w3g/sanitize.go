@@ -0,0 +1,242 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Action identifiers used by Sanitizer
+const (
+	actionTriggerChat = 0x60
+)
+
+// actionSize returns the size of the first action in b (including its ID), or -1 if unknown.
+// Only supports the action layout of patch 1.14b and later.
+func actionSize(b []byte) int {
+	if len(b) == 0 {
+		return -1
+	}
+
+	var size = -1
+	switch b[0] {
+	case 0x01, 0x02, 0x04, 0x05, 0x1A, 0x61, 0x66, 0x67,
+		0x20, 0x22, 0x23, 0x24, 0x25, 0x26, 0x29, 0x2A, 0x2B, 0x2C, 0x2F, 0x30, 0x31, 0x32:
+		size = 1
+	case 0x03, 0x75:
+		size = 2
+	case 0x18:
+		size = 3
+	case 0x07, 0x2E:
+		size = 5
+	case 0x1E, 0x27, 0x28, 0x2D, 0x50:
+		size = 6
+	case 0x1D, 0x21:
+		size = 9
+	case 0x1B, 0x1C, 0x51:
+		size = 10
+	case 0x19, 0x62, 0x68:
+		size = 13
+	case 0x10:
+		size = 15
+	case 0x69, 0x6A, 0x7B:
+		size = 17
+	case 0x11:
+		size = 23
+	case 0x12:
+		size = 31
+	case 0x13:
+		size = 39
+	case 0x14:
+		size = 44
+	case 0x16, 0x17:
+		if len(b) >= 4 {
+			size = 4 + int(uint16(b[2])|uint16(b[3])<<8)*8
+		}
+	case 0x06:
+		if n := bytes.IndexByte(b[1:], 0); n >= 0 {
+			size = 2 + n
+		}
+	case actionTriggerChat:
+		if len(b) >= 9 {
+			if n := bytes.IndexByte(b[9:], 0); n >= 0 {
+				size = 10 + n
+			}
+		}
+	}
+
+	if size > len(b) {
+		return -1
+	}
+	return size
+}
+
+// removeActions returns b without the actions with identifier id, or b itself if it
+// contains no such actions. Returns false (and b itself) if b contains an action that
+// cannot be decoded, the block is then left unchecked.
+func removeActions(b []byte, id byte) ([]byte, bool) {
+	var found = false
+	for i := 0; i < len(b); {
+		var n = actionSize(b[i:])
+		if n <= 0 {
+			return b, false
+		}
+		found = found || b[i] == id
+		i += n
+	}
+	if !found {
+		return b, true
+	}
+
+	var res = make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		var n = actionSize(b[i:])
+		if b[i] != id {
+			res = append(res, b[i:i+n]...)
+		}
+		i += n
+	}
+	return res, true
+}
+
+// SanitizeRules configures which information a Sanitizer removes from a replay.
+//
+// Replay PlayerInfo records do not contain socket addresses (unlike w3gs.PlayerInfo),
+// so there are no IP addresses to strip.
+type SanitizeRules struct {
+	RemoveChat        bool // Remove chat messages
+	RemoveTriggerChat bool // Remove map trigger chat actions, maps that respond to chat events may play back differently
+	RemoveSkins       bool // Remove Reforged in-game skin records
+	RenamePlayers     bool // Rename players to "Player <ID>" and clear battle.net clan and portrait
+	FixColors         bool // Assign sequential colors to player slots
+	GameName          string
+}
+
+// Sanitizer removes private information from replay records according to its rules
+type Sanitizer struct {
+	SanitizeRules
+	Encoding
+
+	// Number of action blocks that contain an unknown action, these are kept as is
+	// and may still contain trigger chat
+	Unchecked int
+}
+
+// PlayerName returns the anonymized name for player id
+func PlayerName(id uint8) string {
+	return fmt.Sprintf("Player %d", id)
+}
+
+// Sanitize rec in place, returns false if rec should be removed
+func (s *Sanitizer) Sanitize(rec Record) bool {
+	switch v := rec.(type) {
+	case *GameInfo:
+		if s.RenamePlayers {
+			if v.GameSettings.HostName == v.HostPlayer.Name {
+				v.GameSettings.HostName = PlayerName(v.HostPlayer.ID)
+			} else {
+				v.GameSettings.HostName = "Host"
+			}
+			v.HostPlayer.Name = PlayerName(v.HostPlayer.ID)
+		}
+		if s.GameName != "" {
+			v.GameName = s.GameName
+		}
+	case *PlayerInfo:
+		if s.RenamePlayers {
+			v.Name = PlayerName(v.ID)
+		}
+	case *PlayerExtra:
+		if s.RemoveSkins && v.Type == w3gs.PlayerSkins {
+			return false
+		}
+		if s.RenamePlayers {
+			for i := range v.Profiles {
+				v.Profiles[i].BattleTag = PlayerName(uint8(v.Profiles[i].PlayerID))
+				v.Profiles[i].Clan = ""
+				v.Profiles[i].Portrait = ""
+			}
+		}
+	case *SlotInfo:
+		if s.FixColors {
+			var maxp uint8 = 24
+			if s.GameVersion > 0 && s.GameVersion < 29 {
+				maxp = 12
+			}
+
+			var c = uint8(0)
+			for i := range v.Slots {
+				if v.Slots[i].Team >= maxp {
+					continue
+				}
+				v.Slots[i].Color = c
+				c++
+			}
+		}
+	case *ChatMessage:
+		if s.RemoveChat {
+			return false
+		}
+	case *TimeSlot:
+		if s.RemoveTriggerChat && (s.GameVersion == 0 || s.GameVersion >= 14) {
+			var n = 0
+			for _, a := range v.Actions {
+				var ok bool
+				if a.Data, ok = removeActions(a.Data, actionTriggerChat); !ok {
+					s.Unchecked++
+				}
+				if len(a.Data) > 0 {
+					v.Actions[n] = a
+					n++
+				}
+			}
+			v.Actions = v.Actions[:n]
+		}
+	}
+
+	return true
+}
+
+// Sanitize the replay in place according to rules, returns ErrUnknownAction if
+// some actions could not be checked for trigger chat (the rest is still sanitized)
+func (r *Replay) Sanitize(rules SanitizeRules) error {
+	var s = Sanitizer{SanitizeRules: rules, Encoding: r.Encoding()}
+
+	s.Sanitize(&r.GameInfo)
+	for i, p := range r.PlayerInfo {
+		if p.ID == r.HostPlayer.ID {
+			// Host is sanitized as part of GameInfo
+			r.PlayerInfo[i] = &r.GameInfo.HostPlayer
+			continue
+		}
+		s.Sanitize(p)
+	}
+
+	var extra = r.PlayerExtra[:0]
+	for _, p := range r.PlayerExtra {
+		if s.Sanitize(p) {
+			extra = append(extra, p)
+		}
+	}
+	r.PlayerExtra = extra
+
+	s.Sanitize(&r.SlotInfo)
+
+	var records = r.Records[:0]
+	for _, rec := range r.Records {
+		if s.Sanitize(rec) {
+			records = append(records, rec)
+		}
+	}
+	r.Records = records
+
+	if s.Unchecked > 0 {
+		return ErrUnknownAction
+	}
+	return nil
+}
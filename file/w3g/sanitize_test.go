@@ -0,0 +1,117 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestSanitize(t *testing.T) {
+	for _, file := range []string{"test_102.w3g", "test_126.w3g", "test_130.w3g", "test_132.w3g"} {
+		rep, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var actions, _, leaves = countRecords(rep.Records)
+		err = rep.Sanitize(w3g.SanitizeRules{
+			RemoveChat:        true,
+			RemoveTriggerChat: true,
+			RemoveSkins:       true,
+			RenamePlayers:     true,
+			FixColors:         true,
+			GameName:          "Tournament",
+		})
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var sanActions, chat, sanLeaves = countRecords(rep.Records)
+		if sanActions != actions || chat != 0 || sanLeaves != leaves {
+			t.Fatal(file, "Record mismatch", sanActions, actions, chat, sanLeaves, leaves)
+		}
+		if rep.GameName != "Tournament" || rep.PlayerInfo[0] != &rep.HostPlayer {
+			t.Fatal(file, "GameInfo mismatch")
+		}
+		for _, p := range rep.PlayerInfo {
+			if p.Name != w3g.PlayerName(p.ID) {
+				t.Fatal(file, "Player not renamed", p)
+			}
+		}
+		for _, p := range rep.PlayerExtra {
+			if p.Type == w3gs.PlayerSkins {
+				t.Fatal(file, "Skins not removed")
+			}
+			for _, pr := range p.Profiles {
+				if pr.BattleTag != w3g.PlayerName(uint8(pr.PlayerID)) || pr.Clan != "" || pr.Portrait != "" {
+					t.Fatal(file, "Profile not renamed", pr)
+				}
+			}
+		}
+
+		dec := reencode(t, rep)
+		if !reflect.DeepEqual(dec.Records, rep.Records) || !reflect.DeepEqual(dec.PlayerInfo, rep.PlayerInfo) {
+			t.Fatal(file, "Sanitize re-encode mismatch")
+		}
+	}
+
+	var trigger = []byte{0x60, 1, 0, 0, 0, 1, 0, 0, 0, '-', 'a', 'p', 0}
+	var ability = []byte{0x10, 0x42, 0x00, 'h', 'p', 'e', 'a', 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	var unknown = []byte{0xFE, 0x60}
+
+	var ts = w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{
+		TimeIncrementMS: 100,
+		Actions: []w3gs.PlayerAction{
+			{PlayerID: 1, Data: append(append([]byte{}, trigger...), ability...)},
+			{PlayerID: 2, Data: trigger},
+			{PlayerID: 3, Data: append(append([]byte{}, trigger...), unknown...)},
+		},
+	}}
+
+	var s = w3g.Sanitizer{SanitizeRules: w3g.SanitizeRules{RemoveTriggerChat: true}}
+	s.GameVersion = 10030
+	if !s.Sanitize(&ts) {
+		t.Fatal("Expected TimeSlot to be kept")
+	}
+
+	var expected = []w3gs.PlayerAction{
+		{PlayerID: 1, Data: ability},
+		{PlayerID: 3, Data: append(append([]byte{}, trigger...), unknown...)},
+	}
+	if !reflect.DeepEqual(ts.Actions, expected) {
+		t.Fatal("Trigger chat mismatch", ts.Actions)
+	}
+	if s.Unchecked != 1 {
+		t.Fatal("Expected 1 unchecked action block, got", s.Unchecked)
+	}
+
+	// Unknown game version
+	ts.Actions = []w3gs.PlayerAction{{PlayerID: 2, Data: trigger}}
+	s.GameVersion = 0
+	if !s.Sanitize(&ts) || len(ts.Actions) != 0 || s.Unchecked != 1 {
+		t.Fatal("Trigger chat not removed for unknown game version", ts.Actions)
+	}
+
+	// Unknown action in a replay
+	var rep = w3g.Replay{Records: []w3g.Record{&w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{
+		Actions: []w3gs.PlayerAction{{PlayerID: 3, Data: append(append([]byte{}, trigger...), unknown...)}},
+	}}}}
+	if err := rep.Sanitize(w3g.SanitizeRules{RemoveTriggerChat: true}); err != w3g.ErrUnknownAction {
+		t.Fatal("Expected ErrUnknownAction, got", err)
+	}
+
+	// Replay without player records
+	var empty w3g.Replay
+	if err := empty.Sanitize(w3g.SanitizeRules{RenamePlayers: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.PlayerInfo) != 0 {
+		t.Fatal("Expected no players", empty.PlayerInfo)
+	}
+}
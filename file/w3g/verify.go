@@ -0,0 +1,264 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ProblemType enum
+type ProblemType uint8
+
+// Problem types
+const (
+	ProblemHeaderChecksum ProblemType = iota
+	ProblemHeaderSize
+	ProblemBlockHeaderChecksum
+	ProblemBlockChecksum
+	ProblemBlockTruncated
+	ProblemBlockCorrupt
+	ProblemNumBlocks
+	ProblemSizeTotal
+	ProblemSizeFile
+	ProblemRecords
+	ProblemDuration
+)
+
+func (p ProblemType) String() string {
+	switch p {
+	case ProblemHeaderChecksum:
+		return "HeaderChecksum"
+	case ProblemHeaderSize:
+		return "HeaderSize"
+	case ProblemBlockHeaderChecksum:
+		return "BlockHeaderChecksum"
+	case ProblemBlockChecksum:
+		return "BlockChecksum"
+	case ProblemBlockTruncated:
+		return "BlockTruncated"
+	case ProblemBlockCorrupt:
+		return "BlockCorrupt"
+	case ProblemNumBlocks:
+		return "NumBlocks"
+	case ProblemSizeTotal:
+		return "SizeTotal"
+	case ProblemSizeFile:
+		return "SizeFile"
+	case ProblemRecords:
+		return "Records"
+	case ProblemDuration:
+		return "Duration"
+	default:
+		return fmt.Sprintf("ProblemType(0x%02X)", uint8(p))
+	}
+}
+
+// Problem found by Verify
+type Problem struct {
+	Type     ProblemType
+	Block    int // Block number, -1 if not specific to a block
+	Expected uint64
+	Actual   uint64
+}
+
+func (p Problem) String() string {
+	if p.Expected == 0 && p.Actual == 0 {
+		if p.Block < 0 {
+			return p.Type.String()
+		}
+		return fmt.Sprintf("%v in block %d", p.Type, p.Block)
+	}
+	if p.Block < 0 {
+		return fmt.Sprintf("%v (expected %d, got %d)", p.Type, p.Expected, p.Actual)
+	}
+	return fmt.Sprintf("%v in block %d (expected %d, got %d)", p.Type, p.Block, p.Expected, p.Actual)
+}
+
+// Report of Verify
+type Report struct {
+	Header
+	Problems    []Problem
+	NumBlocks   uint32 // Blocks found in data section
+	ValidBlocks uint32 // Consecutive decodable blocks from the start of the data section
+	NumRecords  int    // Records decoded from valid blocks
+	GameTimeMS  uint32 // Sum of time slot increments in valid blocks
+}
+
+// Valid returns true if no problems were found
+func (r *Report) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Report) add(t ProblemType, block int, expected uint64, actual uint64) {
+	r.Problems = append(r.Problems, Problem{Type: t, Block: block, Expected: expected, Actual: actual})
+}
+
+// Verify reads the replay in r (positioned at the start of the header) and reports every
+// checksum mismatch, truncated block and inconsistent length. Returns an error only if r
+// cannot be read or does not contain a replay header.
+func Verify(r io.Reader) (*Report, error) {
+	rep, _, err := verify(r)
+	return rep, err
+}
+
+// Repair salvages the records in r and writes a valid replay to w.
+//
+// A missing block makes all subsequent records unusable (the game simulation depends on
+// every action), so only the records in the consecutive decodable blocks from the start
+// of the data section are kept. Blocks with checksum mismatches are kept if they decompress.
+// Returns the Verify report of r.
+func Repair(r io.Reader, w io.Writer) (*Report, error) {
+	rep, records, err := verify(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = Replay{Header: rep.Header}
+	var info = false
+	for _, rec := range records {
+		if _, ok := rec.(*GameInfo); ok {
+			info = true
+		}
+		res.setup(rec)
+		if isGameRecord(rec) {
+			res.Records = append(res.Records, rec)
+		}
+	}
+	if !info {
+		return rep, ErrBadFormat
+	}
+
+	res.defaultSlots()
+	if rep.DurationMS > rep.GameTimeMS {
+		res.DurationMS = rep.GameTimeMS
+	}
+
+	return rep, res.Encode(w)
+}
+
+func verify(r io.Reader) (*Report, []Record, error) {
+	hdr, n, err := decodeHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rep = Report{Header: hdr.Header}
+	if !hdr.ValidCRC {
+		rep.add(ProblemHeaderChecksum, -1, 0, 0)
+	}
+
+	var size = uint64(n)
+	if uint32(n) > hdr.SizeHeader {
+		rep.add(ProblemHeaderSize, -1, uint64(n), uint64(hdr.SizeHeader))
+	} else {
+		nn, err := io.CopyN(ioutil.Discard, r, int64(hdr.SizeHeader-uint32(n)))
+		size += uint64(nn)
+		if err != nil {
+			rep.add(ProblemHeaderSize, -1, uint64(hdr.SizeHeader), size)
+		}
+	}
+
+	var v = hdr.GameVersion.Version
+	var head [12]byte
+	var comp bytes.Buffer
+	var block bytes.Buffer
+	var data []byte
+	var salvage = true
+	var sizeBlocks uint64
+	var sizeLast uint64
+
+	for b := 0; ; b++ {
+		var lenHead = blockHeaderSize(v)
+		nh, err := io.ReadFull(r, head[:lenHead])
+		size += uint64(nh)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			rep.add(ProblemBlockTruncated, b, uint64(lenHead), uint64(nh))
+			break
+		}
+		rep.NumBlocks++
+
+		lenDeflate, sizeBlock, crcData, valid := decodeBlockHeader(head[:lenHead], v)
+		if !valid {
+			rep.add(ProblemBlockHeaderChecksum, b, 0, 0)
+		}
+
+		comp.Reset()
+		nc, err := io.CopyN(&comp, r, int64(lenDeflate))
+		size += uint64(nc)
+		if err != nil {
+			rep.add(ProblemBlockTruncated, b, uint64(lenDeflate), uint64(nc))
+			break
+		}
+		if crc := dataChecksum(comp.Bytes()); crc != crcData {
+			rep.add(ProblemBlockChecksum, b, uint64(crcData), uint64(crc))
+		}
+
+		sizeBlocks += uint64(sizeBlock)
+		sizeLast = uint64(sizeBlock)
+
+		// Blocks are flushed rather than closed, so expect io.ErrUnexpectedEOF after the data
+		block.Reset()
+		var nd int64
+		z, err := zlib.NewReader(&comp)
+		if err == nil {
+			nd, err = io.CopyN(&block, z, int64(sizeBlock))
+		}
+		if err != nil || nd != int64(sizeBlock) {
+			rep.add(ProblemBlockCorrupt, b, uint64(sizeBlock), uint64(nd))
+			salvage = false
+		} else if salvage {
+			data = append(data, block.Bytes()...)
+			rep.ValidBlocks++
+		}
+	}
+
+	if rep.NumBlocks != hdr.NumBlocks {
+		rep.add(ProblemNumBlocks, -1, uint64(hdr.NumBlocks), uint64(rep.NumBlocks))
+	}
+	if uint64(hdr.SizeBlocks) > sizeBlocks || uint64(hdr.SizeBlocks)+sizeLast <= sizeBlocks && sizeBlocks > 0 {
+		rep.add(ProblemSizeTotal, -1, uint64(hdr.SizeBlocks), sizeBlocks)
+	}
+	if uint64(hdr.SizeFile) != size {
+		rep.add(ProblemSizeFile, -1, uint64(hdr.SizeFile), size)
+	}
+
+	if salvage && uint64(hdr.SizeBlocks) <= uint64(len(data)) {
+		// Strip padding
+		data = data[:hdr.SizeBlocks]
+	}
+
+	var records []Record
+	var dec = RecordDecoder{Encoding: hdr.Encoding()}
+	var buf = bufio.NewReader(bytes.NewReader(data))
+	var pos int
+	for {
+		rec, n, err := dec.Read(buf)
+		pos += n
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			rep.add(ProblemRecords, -1, uint64(len(data)), uint64(pos))
+			break
+		}
+
+		records = append(records, rec)
+	}
+
+	rep.NumRecords = len(records)
+	rep.GameTimeMS = Duration(records)
+	// Header duration does not include all time slot increments, only report if it exceeds the game time
+	if rep.DurationMS > rep.GameTimeMS {
+		rep.add(ProblemDuration, -1, uint64(rep.DurationMS), uint64(rep.GameTimeMS))
+	}
+
+	return &rep, records, nil
+}
@@ -0,0 +1,132 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+func hasProblem(rep *w3g.Report, t w3g.ProblemType, block int) bool {
+	for _, p := range rep.Problems {
+		if p.Type == t && p.Block == block {
+			return true
+		}
+	}
+	return false
+}
+
+func repair(t *testing.T, file string, b []byte) (*w3g.Report, *w3g.Replay) {
+	var out bytes.Buffer
+	rep, err := w3g.Repair(bytes.NewReader(b), &out)
+	if err != nil {
+		t.Fatal(file, err)
+	}
+
+	if v, err := w3g.Verify(bytes.NewReader(out.Bytes())); err != nil || !v.Valid() {
+		t.Fatal(file, "Repaired replay invalid", v.Problems, err)
+	}
+
+	res, err := w3g.Decode(&out)
+	if err != nil {
+		t.Fatal(file, err)
+	}
+
+	return rep, res
+}
+
+func TestVerify(t *testing.T) {
+	for _, file := range []string{"test_102.w3g", "test_126.w3g", "test_130.w3g", "test_132.w3g"} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		orig, err := w3g.Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		rep, err := w3g.Verify(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if !rep.Valid() || rep.NumBlocks != rep.ValidBlocks || rep.NumRecords == 0 {
+			t.Fatal(file, "Unexpected problems", rep.Problems)
+		}
+
+		_, res := repair(t, file, b)
+		if !reflect.DeepEqual(res, orig) {
+			t.Fatal(file, "Repair modified valid replay")
+		}
+
+		// Header checksum
+		var c = append([]byte{}, b...)
+		if c[36] == 0 {
+			c[63] ^= 0xFF
+		} else {
+			c[67] ^= 0xFF
+		}
+		rep, _ = w3g.Verify(bytes.NewReader(c))
+		if len(rep.Problems) != 1 || !hasProblem(rep, w3g.ProblemHeaderChecksum, -1) {
+			t.Fatal(file, "Expected header checksum problem", rep.Problems)
+		}
+		if _, err := w3g.Decode(bytes.NewReader(c)); err != w3g.ErrInvalidChecksum {
+			t.Fatal(file, "Expected ErrInvalidChecksum, got", err)
+		}
+		if _, res = repair(t, file, c); !reflect.DeepEqual(res.Records, orig.Records) {
+			t.Fatal(file, "Repair lost records")
+		}
+
+		_, idx, err := w3g.BuildIndex(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		var blk = idx.Blocks[len(idx.Blocks)/2]
+		var off = int(idx.DataStart) + int(blk.Offset)
+
+		// Block data
+		c = append([]byte{}, b...)
+		c[off+16] ^= 0xFF
+		rep, _ = w3g.Verify(bytes.NewReader(c))
+		if !hasProblem(rep, w3g.ProblemBlockChecksum, int(blk.Block)) || !hasProblem(rep, w3g.ProblemBlockCorrupt, int(blk.Block)) ||
+			rep.ValidBlocks != blk.Block || rep.NumBlocks != idx.NumBlocks {
+			t.Fatal(file, "Expected block problems", rep.Problems)
+		}
+
+		rep, res = repair(t, file, c)
+		if len(res.Records) == 0 || len(res.Records) >= len(orig.Records) || res.DurationMS != rep.GameTimeMS ||
+			!reflect.DeepEqual(res.Records, orig.Records[:len(res.Records)]) {
+			t.Fatal(file, "Repair records mismatch", len(res.Records), len(orig.Records))
+		}
+
+		// Block header
+		c = append([]byte{}, b...)
+		c[off] ^= 0x01
+		rep, _ = w3g.Verify(bytes.NewReader(c))
+		if !hasProblem(rep, w3g.ProblemBlockHeaderChecksum, int(blk.Block)) {
+			t.Fatal(file, "Expected block header problem", rep.Problems)
+		}
+		repair(t, file, c)
+
+		// Truncated
+		c = b[:off+20]
+		rep, _ = w3g.Verify(bytes.NewReader(c))
+		if !hasProblem(rep, w3g.ProblemBlockTruncated, int(blk.Block)) || !hasProblem(rep, w3g.ProblemNumBlocks, -1) ||
+			!hasProblem(rep, w3g.ProblemSizeFile, -1) || !hasProblem(rep, w3g.ProblemDuration, -1) {
+			t.Fatal(file, "Expected truncation problems", rep.Problems)
+		}
+		if _, res = repair(t, file, c); !reflect.DeepEqual(res.Records, orig.Records[:len(res.Records)]) {
+			t.Fatal(file, "Repair records mismatch")
+		}
+	}
+
+	if _, err := w3g.Verify(bytes.NewReader([]byte("not a replay, but long enough to read the full header from..."))); err == nil {
+		t.Fatal("Expected error")
+	}
+}
@@ -137,48 +137,74 @@ func Decode(r io.Reader) (*Replay, error) {
 		return nil, err
 	}
 
+	return decodeRecords(hdr, data.ForEach)
+}
+
+// decodeRecords collects the records yielded by forEach into a Replay
+func decodeRecords(hdr *Header, forEach func(f func(r Record) error) error) (*Replay, error) {
 	var res = Replay{Header: *hdr}
-	if err := data.ForEach(func(r Record) error {
-		switch v := r.(type) {
-		case *GameInfo:
-			res.GameInfo = *v
-			res.PlayerInfo = []*PlayerInfo{&res.GameInfo.HostPlayer}
-		case *SlotInfo:
-			res.SlotInfo = *v
-		case *PlayerInfo:
-			res.PlayerInfo = append(res.PlayerInfo, v)
-		case *PlayerExtra:
-			res.PlayerExtra = append(res.PlayerExtra, v)
-		case *CountDownStart, *CountDownEnd, *GameStart, *TimeSlotAck:
-			// Ignore
-		case *ChatMessage:
-			if v.Type == w3gs.MsgChatExtra {
-				res.Records = append(res.Records, v)
-			}
-		default:
-			res.Records = append(res.Records, v)
+	if err := forEach(func(r Record) error {
+		res.setup(r)
+		if isGameRecord(r) {
+			res.Records = append(res.Records, r)
 		}
 		return nil
 	}); err != nil {
 		return nil, err
 	}
 
-	if len(res.SlotInfo.Slots) == 0 {
-		for i, p := range res.PlayerInfo {
-			res.SlotInfo.NumPlayers++
-			res.SlotInfo.Slots = append(res.SlotInfo.Slots, w3gs.SlotData{
-				PlayerID:       uint8(i + 1),
-				DownloadStatus: 100,
-				SlotStatus:     w3gs.SlotOccupied,
-				Computer:       false,
-				Team:           uint8(i % 2),
-				Color:          uint8(i),
-				Race:           p.Race,
-				ComputerType:   w3gs.ComputerNormal,
-				Handicap:       100,
-			})
-		}
+	res.defaultSlots()
+	return &res, nil
+}
+
+// setup stores game setup records in r
+func (r *Replay) setup(rec Record) {
+	switch v := rec.(type) {
+	case *GameInfo:
+		r.GameInfo = *v
+		r.PlayerInfo = []*PlayerInfo{&r.GameInfo.HostPlayer}
+	case *SlotInfo:
+		r.SlotInfo = *v
+	case *PlayerInfo:
+		r.PlayerInfo = append(r.PlayerInfo, v)
+	case *PlayerExtra:
+		r.PlayerExtra = append(r.PlayerExtra, v)
+	}
+}
+
+// isGameRecord returns false for game setup and redundant records
+func isGameRecord(rec Record) bool {
+	switch v := rec.(type) {
+	case *GameInfo, *SlotInfo, *PlayerInfo, *PlayerExtra:
+		return false
+	case *CountDownStart, *CountDownEnd, *GameStart, *TimeSlotAck:
+		// Ignore
+		return false
+	case *ChatMessage:
+		return v.Type == w3gs.MsgChatExtra
+	default:
+		return true
 	}
+}
 
-	return &res, nil
+// defaultSlots generates slot info for replays that do not contain a SlotInfo record
+func (r *Replay) defaultSlots() {
+	if len(r.SlotInfo.Slots) != 0 {
+		return
+	}
+
+	for i, p := range r.PlayerInfo {
+		r.SlotInfo.NumPlayers++
+		r.SlotInfo.Slots = append(r.SlotInfo.Slots, w3gs.SlotData{
+			PlayerID:       uint8(i + 1),
+			DownloadStatus: 100,
+			SlotStatus:     w3gs.SlotOccupied,
+			Computer:       false,
+			Team:           uint8(i % 2),
+			Color:          uint8(i),
+			Race:           p.Race,
+			ComputerType:   w3gs.ComputerNormal,
+			Handicap:       100,
+		})
+	}
 }
@@ -0,0 +1,133 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+const cameraVersion = 0
+
+// Camera setup structure in war3map.w3c file
+type Camera struct {
+	TargetX       float32
+	TargetY       float32
+	ZOffset       float32
+	Rotation      float32
+	AngleOfAttack float32
+	Distance      float32
+	Roll          float32
+	FieldOfView   float32
+	FarZ          float32
+	NearZ         float32
+
+	// 1.31+
+	LocalPitch float32
+	LocalYaw   float32
+	LocalRoll  float32
+
+	Name string
+}
+
+// Cameras as found in the war3map.w3c file
+type Cameras struct {
+	FileFormat    uint32
+	LocalRotation bool
+	Cameras       []Camera
+}
+
+// Serialize encodes the cameras into their binary form
+func (c *Cameras) Serialize(buf *protocol.Buffer) error {
+	buf.WriteUInt32(c.FileFormat)
+	buf.WriteUInt32(uint32(len(c.Cameras)))
+	for i := range c.Cameras {
+		var v = &c.Cameras[i]
+		buf.WriteFloat32(v.TargetX)
+		buf.WriteFloat32(v.TargetY)
+		buf.WriteFloat32(v.ZOffset)
+		buf.WriteFloat32(v.Rotation)
+		buf.WriteFloat32(v.AngleOfAttack)
+		buf.WriteFloat32(v.Distance)
+		buf.WriteFloat32(v.Roll)
+		buf.WriteFloat32(v.FieldOfView)
+		buf.WriteFloat32(v.FarZ)
+		buf.WriteFloat32(v.NearZ)
+		if c.LocalRotation {
+			buf.WriteFloat32(v.LocalPitch)
+			buf.WriteFloat32(v.LocalYaw)
+			buf.WriteFloat32(v.LocalRoll)
+		}
+		buf.WriteCString(v.Name)
+	}
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize, c.LocalRotation must be set
+func (c *Cameras) Deserialize(buf *protocol.Buffer) error {
+	if buf.Size() < 8 {
+		return ErrBadFormat
+	}
+
+	c.FileFormat = buf.ReadUInt32()
+	if c.FileFormat != cameraVersion {
+		return ErrBadFormat
+	}
+
+	var size = 40
+	if c.LocalRotation {
+		size += 12
+	}
+
+	c.Cameras = make([]Camera, buf.ReadUInt32())
+	for i := range c.Cameras {
+		var v = &c.Cameras[i]
+		if buf.Size() < size {
+			return ErrBadFormat
+		}
+
+		v.TargetX = buf.ReadFloat32()
+		v.TargetY = buf.ReadFloat32()
+		v.ZOffset = buf.ReadFloat32()
+		v.Rotation = buf.ReadFloat32()
+		v.AngleOfAttack = buf.ReadFloat32()
+		v.Distance = buf.ReadFloat32()
+		v.Roll = buf.ReadFloat32()
+		v.FieldOfView = buf.ReadFloat32()
+		v.FarZ = buf.ReadFloat32()
+		v.NearZ = buf.ReadFloat32()
+		if c.LocalRotation {
+			v.LocalPitch = buf.ReadFloat32()
+			v.LocalYaw = buf.ReadFloat32()
+			v.LocalRoll = buf.ReadFloat32()
+		}
+
+		var err error
+		if v.Name, err = buf.ReadCString(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cameras read from war3map.w3c
+func (m *Map) Cameras() (*Cameras, error) {
+	ver, err := m.gameVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := m.readFile("war3map.w3c")
+	if err != nil {
+		return nil, err
+	}
+
+	var res = Cameras{LocalRotation: ver.AtLeast(1, 31)}
+	if err := res.Deserialize(b); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -8,6 +8,7 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math/bits"
 	"os"
@@ -26,6 +27,17 @@ func (h *Hash) String() string {
 	return fmt.Sprintf("0x%02X|%s", h.Xoro, base64.RawStdEncoding.EncodeToString(h.Sha1[:]))
 }
 
+// FileHash of the w3m/w3x file on disk
+type FileHash struct {
+	Size  uint32
+	CRC32 uint32
+	Sha1  [20]byte
+}
+
+func (h *FileHash) String() string {
+	return fmt.Sprintf("%d|0x%02X|%s", h.Size, h.CRC32, base64.RawStdEncoding.EncodeToString(h.Sha1[:]))
+}
+
 // Helper for XOR - ROTL hash function
 type xoro uint32
 
@@ -138,3 +150,41 @@ func (m *Map) Checksum(stor *fs.Storage) (*Hash, error) {
 
 	return &h, nil
 }
+
+// NoXoro is the xoro value for maps that are identified by their SHA1 hash only (version >= 1.32).
+// It is the value recorded in the GameSettings of 1.32 replays. MapCheck uses the same value, which
+// has not been verified against a captured packet.
+const NoXoro uint32 = 0xFFFFFFFF
+
+// FileChecksum returns the size, CRC32 and SHA1 hash of the map file on disk
+func (m *Map) FileChecksum() (*FileHash, error) {
+	var f, err = os.Open(m.FileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sha = sha1.New()
+	var crc = crc32.NewIEEE()
+
+	size, err := io.Copy(io.MultiWriter(sha, crc), f)
+	if err != nil {
+		return nil, err
+	}
+
+	var h = FileHash{Size: uint32(size), CRC32: crc.Sum32()}
+	copy(h.Sha1[:], sha.Sum(nil))
+
+	return &h, nil
+}
+
+// ChecksumSHA1 returns the hash that identifies the map in version >= 1.32.
+// Maps are no longer identified by their content, but by the SHA1 hash of the entire file.
+func (m *Map) ChecksumSHA1() (*Hash, error) {
+	fh, err := m.FileChecksum()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hash{Xoro: NoXoro, Sha1: fh.Sha1}, nil
+}
@@ -15,7 +15,9 @@ import (
 
 // Errors
 var (
-	ErrBadFormat = errors.New("w3m: Invalid file format")
+	ErrBadFormat       = errors.New("w3m: Invalid file format")
+	ErrUnknownFunction = errors.New("w3m: Unknown trigger function")
+	ErrInvalidPath     = errors.New("w3m: Invalid import path")
 )
 
 // Size enum
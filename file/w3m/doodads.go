@@ -0,0 +1,264 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// DoodadHeader is the magic header of war3map.doo and war3mapUnits.doo
+var DoodadHeader = protocol.DString("W3do")
+
+const doodadVersionRoc = 7
+const doodadVersionTft = 8
+const unitSubVersionTft = 11
+
+// DoodadFlags enum
+type DoodadFlags uint8
+
+// Doodad visibility and collision
+const (
+	DoodadInvisible DoodadFlags = iota // Invisible, non-solid
+	DoodadVisible                      // Visible, non-solid
+	DoodadSolid                        // Visible, solid
+)
+
+// Visible returns true if the doodad is drawn
+//
+// The editor also writes 3 for some doodads near the map edge, which is treated like DoodadSolid.
+func (f DoodadFlags) Visible() bool {
+	return f&0x03 != DoodadInvisible
+}
+
+// Solid returns true if the doodad blocks pathing
+func (f DoodadFlags) Solid() bool {
+	return f&0x02 != 0
+}
+
+func (f DoodadFlags) String() string {
+	switch f {
+	case DoodadInvisible:
+		return "Invisible"
+	case DoodadVisible:
+		return "Visible"
+	case DoodadSolid:
+		return "Solid"
+	default:
+		return fmt.Sprintf("DoodadFlags(0x%02X)", uint8(f))
+	}
+}
+
+// Placement of doodads and units on the map
+type Placement struct {
+	TypeID    protocol.DWordString
+	Variation uint32
+	X         float32
+	Y         float32
+	Z         float32
+	Angle     float32
+	ScaleX    float32
+	ScaleY    float32
+	ScaleZ    float32
+	SkinID    protocol.DWordString
+}
+
+// ItemDrop is a single item in an ItemSet
+type ItemDrop struct {
+	ItemID protocol.DWordString
+	Chance uint32
+}
+
+// ItemSet of which one item is dropped on death
+type ItemSet []ItemDrop
+
+// Doodad structure in war3map.doo file
+type Doodad struct {
+	Placement
+	Flags          DoodadFlags
+	Life           uint8
+	ItemTable      int32
+	ItemSets       []ItemSet
+	CreationNumber uint32
+}
+
+// SpecialDoodad (i.e. cliffs) structure in war3map.doo file, position in w3e coordinates
+type SpecialDoodad struct {
+	TypeID protocol.DWordString
+	Z      uint32
+	X      uint32
+	Y      uint32
+}
+
+// Doodads as found in the war3map.doo file
+type Doodads struct {
+	FileFormat uint32
+	SubVersion uint32
+	Doodads    []Doodad
+	Special    []SpecialDoodad
+}
+
+// Skin IDs were introduced in Reforged
+func (m *Map) hasSkins() (bool, error) {
+	v, err := m.gameVersion()
+	if err != nil {
+		return false, err
+	}
+	return v.AtLeast(1, 32), nil
+}
+
+func (m *Map) readDoo(fileName string) (*protocol.Buffer, uint32, uint32, error) {
+	doo, err := m.Archive.Open(fileName)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer doo.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, doo); err != nil {
+		return nil, 0, 0, err
+	}
+
+	if b.Size() < 16 || b.ReadLEDString() != DoodadHeader {
+		return nil, 0, 0, ErrBadFormat
+	}
+
+	var version = b.ReadUInt32()
+	var subversion = b.ReadUInt32()
+	switch version {
+	case doodadVersionRoc, doodadVersionTft:
+	default:
+		return nil, 0, 0, ErrBadFormat
+	}
+
+	return &b, version, subversion, nil
+}
+
+func readPlacement(b *protocol.Buffer, p *Placement, skin bool) error {
+	if b.Size() < 40 {
+		return ErrBadFormat
+	}
+
+	p.TypeID = b.ReadLEDString()
+	p.Variation = b.ReadUInt32()
+	p.X = b.ReadFloat32()
+	p.Y = b.ReadFloat32()
+	p.Z = b.ReadFloat32()
+	p.Angle = b.ReadFloat32()
+	p.ScaleX = b.ReadFloat32()
+	p.ScaleY = b.ReadFloat32()
+	p.ScaleZ = b.ReadFloat32()
+
+	if skin {
+		p.SkinID = b.ReadLEDString()
+	} else {
+		p.SkinID = p.TypeID
+	}
+
+	return nil
+}
+
+func readItemSets(b *protocol.Buffer) ([]ItemSet, error) {
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var res = make([]ItemSet, b.ReadUInt32())
+	for i := range res {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+
+		var num = int(b.ReadUInt32())
+		if b.Size() < num*8 {
+			return nil, ErrBadFormat
+		}
+
+		res[i] = make(ItemSet, num)
+		for d := range res[i] {
+			res[i][d].ItemID = b.ReadLEDString()
+			res[i][d].Chance = b.ReadUInt32()
+		}
+	}
+
+	return res, nil
+}
+
+// Doodads read from war3map.doo
+func (m *Map) Doodads() (*Doodads, error) {
+	skin, err := m.hasSkins()
+	if err != nil {
+		return nil, err
+	}
+
+	b, version, subversion, err := m.readDoo("war3map.doo")
+	if err != nil {
+		return nil, err
+	}
+
+	var res = Doodads{
+		FileFormat: version,
+		SubVersion: subversion,
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	res.Doodads = make([]Doodad, b.ReadUInt32())
+	for i := range res.Doodads {
+		var d = &res.Doodads[i]
+		if err := readPlacement(b, &d.Placement, skin); err != nil {
+			return nil, err
+		}
+
+		if b.Size() < 2 {
+			return nil, ErrBadFormat
+		}
+		d.Flags = DoodadFlags(b.ReadUInt8())
+		d.Life = b.ReadUInt8()
+		d.ItemTable = -1
+
+		if version >= doodadVersionTft {
+			if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+			d.ItemTable = int32(b.ReadUInt32())
+			if d.ItemSets, err = readItemSets(b); err != nil {
+				return nil, err
+			}
+		}
+
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		d.CreationNumber = b.ReadUInt32()
+	}
+
+	// Special doodads are optional in old maps
+	if b.Size() < 8 {
+		res.Special = []SpecialDoodad{}
+		return &res, nil
+	}
+
+	b.ReadUInt32()
+
+	var num = int(b.ReadUInt32())
+	if b.Size() < num*16 {
+		return nil, ErrBadFormat
+	}
+
+	res.Special = make([]SpecialDoodad, num)
+	for i := range res.Special {
+		res.Special[i].TypeID = b.ReadLEDString()
+		res.Special[i].Z = b.ReadUInt32()
+		res.Special[i].X = b.ReadUInt32()
+		res.Special[i].Y = b.ReadUInt32()
+	}
+
+	return &res, nil
+}
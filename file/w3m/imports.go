@@ -0,0 +1,196 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+const importVersionRoc = 0
+const importVersionTft = 1
+
+// ImportPrefix is the archive directory of imports that do not use a custom path
+const ImportPrefix = "war3mapImported\\"
+
+// ImportFlags enum
+type ImportFlags uint8
+
+// Import path flags, the editor writes different values for the same path type
+const (
+	ImportStandardPath    ImportFlags = 5
+	ImportStandardPathAlt ImportFlags = 8
+	ImportCustomPath      ImportFlags = 10
+	ImportCustomPathAlt   ImportFlags = 13
+)
+
+// Custom returns true if the import path is stored without ImportPrefix
+func (f ImportFlags) Custom() bool {
+	return f == ImportCustomPath || f == ImportCustomPathAlt
+}
+
+// Import entry in war3map.imp file
+type Import struct {
+	Flags ImportFlags
+	Name  string
+}
+
+// Path of the imported file in the map archive
+func (i *Import) Path() string {
+	if i.Flags.Custom() || strings.HasPrefix(strings.ToLower(i.Name), strings.ToLower(ImportPrefix)) {
+		return i.Name
+	}
+	return ImportPrefix + i.Name
+}
+
+// LocalPath converts Path to a relative path in the local file system, returns ErrInvalidPath if
+// the path is absolute or escapes the current directory
+func (i *Import) LocalPath() (string, error) {
+	var p = strings.ReplaceAll(i.Path(), "\\", "/")
+	if p == "" || strings.HasPrefix(p, "/") || strings.Contains(p, ":") {
+		return "", ErrInvalidPath
+	}
+
+	p = filepath.Clean(filepath.FromSlash(p))
+	if p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidPath
+	}
+
+	return p, nil
+}
+
+// Imports as found in the war3map.imp file
+type Imports struct {
+	FileFormat uint32
+	Imports    []Import
+}
+
+// Serialize encodes the imports into their binary form
+func (i *Imports) Serialize(buf *protocol.Buffer) error {
+	if i.FileFormat > importVersionTft {
+		return ErrBadFormat
+	}
+
+	buf.WriteUInt32(i.FileFormat)
+	buf.WriteUInt32(uint32(len(i.Imports)))
+	for _, v := range i.Imports {
+		buf.WriteUInt8(uint8(v.Flags))
+		buf.WriteCString(v.Name)
+	}
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize
+func (i *Imports) Deserialize(buf *protocol.Buffer) error {
+	if buf.Size() < 8 {
+		return ErrBadFormat
+	}
+
+	i.FileFormat = buf.ReadUInt32()
+	switch i.FileFormat {
+	case importVersionRoc:
+	case importVersionTft:
+	default:
+		return ErrBadFormat
+	}
+
+	var err error
+
+	i.Imports = make([]Import, buf.ReadUInt32())
+	for n := range i.Imports {
+		if buf.Size() < 2 {
+			return ErrBadFormat
+		}
+		i.Imports[n].Flags = ImportFlags(buf.ReadUInt8())
+		if i.Imports[n].Name, err = buf.ReadCString(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Imports read from war3map.imp
+func (m *Map) Imports() (*Imports, error) {
+	b, err := m.readFile("war3map.imp")
+	if err != nil {
+		return nil, err
+	}
+
+	var res Imports
+	if err := res.Deserialize(b); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ExtractImports copies each imported file from the archive to dir, maps without war3map.imp
+// have nothing to extract. Returns the local paths of the extracted files and the archive
+// paths of imports that are listed but not present in the archive.
+func (m *Map) ExtractImports(dir string) ([]string, []string, error) {
+	imp, err := m.Imports()
+	if err == os.ErrNotExist {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return m.ExtractFiles(imp, dir)
+}
+
+// ExtractFiles copies each file listed in imp from the archive to dir, files that do not
+// exist in the archive are skipped. Returns the local paths of the extracted files and the
+// archive paths of the skipped files.
+func (m *Map) ExtractFiles(imp *Imports, dir string) ([]string, []string, error) {
+	var res = make([]string, 0, len(imp.Imports))
+	var missing []string
+	for i := range imp.Imports {
+		p, err := imp.Imports[i].LocalPath()
+		if err != nil {
+			return res, missing, err
+		}
+
+		p = filepath.Join(dir, p)
+		if err := m.extractFile(imp.Imports[i].Path(), p); err == os.ErrNotExist {
+			missing = append(missing, imp.Imports[i].Path())
+			continue
+		} else if err != nil {
+			return res, missing, err
+		}
+
+		res = append(res, p)
+	}
+
+	return res, missing, nil
+}
+
+func (m *Map) extractFile(fileName string, dst string) error {
+	f, err := m.Archive.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, f); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
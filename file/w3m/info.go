@@ -5,7 +5,7 @@
 package w3m
 
 import (
-	"io"
+	"fmt"
 
 	"github.com/nielsAD/gowarcraft3/protocol"
 )
@@ -54,10 +54,16 @@ type Info struct {
 	LightEnv   Tileset
 	WaterColor uint32
 
+	// Reforged
+	SupportedModes  uint32
+	GameDataVersion uint32
+
 	Players                     []Player
 	Forces                      []Force
 	CustomUpgradeAvailabilities []CustomUpgradeAvailability
 	CustomTechAvailabilities    []CustomTechAvailability
+	RandomUnitTables            []RandomUnitTable
+	RandomItemTables            []RandomItemTable
 }
 
 // GameVersion stored in map file
@@ -68,6 +74,11 @@ type GameVersion struct {
 	Commit uint32
 }
 
+// AtLeast returns true if v is equal to or newer than major.minor
+func (v GameVersion) AtLeast(major uint32, minor uint32) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
 // Player structure in war3map.w3i file
 type Player struct {
 	ID           uint32
@@ -79,6 +90,10 @@ type Player struct {
 	StartPosY    float32
 	AllyPrioLow  protocol.BitSet32
 	AllyPrioHigh protocol.BitSet32
+
+	// Reforged
+	EnemyPrioLow  protocol.BitSet32
+	EnemyPrioHigh protocol.BitSet32
 }
 
 // Force structure in war3map.w3i file
@@ -102,37 +117,62 @@ type CustomTechAvailability struct {
 	TechID    protocol.DWordString
 }
 
-const editorVersionRoc = 18
-const editorVersionTft = 25
-const editorVersion131 = 28
-const editorVersionReforged = 31
+// RandomPosition enum
+type RandomPosition uint32
 
-// Info read from war3map.w3i
-func (m *Map) Info() (*Info, error) {
-	w3i, err := m.Archive.Open("war3map.w3i")
-	if err != nil {
-		return nil, err
-	}
-	defer w3i.Close()
+// Random table position types
+const (
+	RandomPositionUnit RandomPosition = iota
+	RandomPositionBuilding
+	RandomPositionItem
+)
 
-	if w3i.Size() < 96 {
-		return nil, ErrBadFormat
+func (p RandomPosition) String() string {
+	switch p {
+	case RandomPositionUnit:
+		return "Unit"
+	case RandomPositionBuilding:
+		return "Building"
+	case RandomPositionItem:
+		return "Item"
+	default:
+		return fmt.Sprintf("RandomPosition(0x%02X)", uint32(p))
 	}
+}
 
-	var b protocol.Buffer
-	if _, err := io.Copy(&b, w3i); err != nil {
-		return nil, err
-	}
+// RandomUnitRow is a single row in a RandomUnitTable, with one ID per position
+type RandomUnitRow struct {
+	Chance uint32
+	IDs    []protocol.DWordString
+}
 
-	var readTS = func() (string, error) {
-		s, err := b.ReadCString()
-		if err != nil {
-			return "", err
-		}
-		return m.ExpandString(s)
+// RandomUnitTable (random group) in war3map.w3i file
+type RandomUnitTable struct {
+	ID        uint32
+	Name      string
+	Positions []RandomPosition
+	Rows      []RandomUnitRow
+}
+
+// RandomItemTable in war3map.w3i file
+type RandomItemTable struct {
+	ID   uint32
+	Name string
+	Sets []ItemSet
+}
+
+const editorVersionRoc = 18
+const editorVersionTft = 25
+const editorVersion131 = 28
+const editorVersionReforged = 31
+
+// Deserialize decodes the binary data generated by Serialize, trigger strings are not expanded
+func (i *Info) Deserialize(b *protocol.Buffer) error {
+	if b.Size() < 96 {
+		return ErrBadFormat
 	}
 
-	var i = Info{
+	*i = Info{
 		FileFormat: b.ReadUInt32(),
 	}
 
@@ -142,7 +182,7 @@ func (m *Map) Info() (*Info, error) {
 	case editorVersion131:
 	case editorVersionReforged:
 	default:
-		return nil, ErrBadFormat
+		return ErrBadFormat
 	}
 
 	i.SaveCount = b.ReadUInt32()
@@ -158,14 +198,15 @@ func (m *Map) Info() (*Info, error) {
 		}
 	}
 
-	i.Name, _ = readTS()
-	i.Author, _ = readTS()
-	i.Description, _ = readTS()
-	i.SuggestedPlayers, err = readTS()
+	var err error
+	i.Name, _ = b.ReadCString()
+	i.Author, _ = b.ReadCString()
+	i.Description, _ = b.ReadCString()
+	i.SuggestedPlayers, err = b.ReadCString()
 	if err != nil {
-		return nil, err
+		return err
 	} else if b.Size() < 80 {
-		return nil, ErrBadFormat
+		return ErrBadFormat
 	}
 
 	for c := 0; c < len(i.CamBounds); c++ {
@@ -183,32 +224,32 @@ func (m *Map) Info() (*Info, error) {
 	i.LsBackground = b.ReadUInt32()
 
 	if i.FileFormat >= editorVersionTft {
-		i.LsPath, _ = readTS()
+		i.LsPath, _ = b.ReadCString()
 	}
-	i.LsText, _ = readTS()
-	i.LsTitle, _ = readTS()
-	i.LsSubTitle, err = readTS()
+	i.LsText, _ = b.ReadCString()
+	i.LsTitle, _ = b.ReadCString()
+	i.LsSubTitle, err = b.ReadCString()
 	if err != nil {
-		return nil, err
+		return err
 	} else if b.Size() < 13 {
-		return nil, ErrBadFormat
+		return ErrBadFormat
 	}
 
 	i.DataSet = b.ReadUInt32()
 
 	if i.FileFormat >= editorVersionTft {
-		i.PsPath, _ = readTS()
+		i.PsPath, _ = b.ReadCString()
 	}
-	i.PsText, _ = readTS()
-	i.PsTitle, _ = readTS()
-	i.PsSubTitle, err = readTS()
+	i.PsText, _ = b.ReadCString()
+	i.PsTitle, _ = b.ReadCString()
+	i.PsSubTitle, err = b.ReadCString()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if i.FileFormat >= editorVersionTft {
 		if b.Size() < 54 {
-			return nil, ErrBadFormat
+			return ErrBadFormat
 		}
 		i.Fog = b.ReadUInt32()
 		i.FogStart = b.ReadFloat32()
@@ -216,11 +257,11 @@ func (m *Map) Info() (*Info, error) {
 		i.FogDensity = b.ReadFloat32()
 		i.FogColor = b.ReadUInt32()
 		i.WeatherID = b.ReadLEDString()
-		i.SoundEnv, err = readTS()
+		i.SoundEnv, err = b.ReadCString()
 		if err != nil {
-			return nil, err
+			return err
 		} else if b.Size() < 12 {
-			return nil, ErrBadFormat
+			return ErrBadFormat
 		}
 		i.LightEnv = Tileset(b.ReadUInt8())
 		i.WaterColor = b.ReadUInt32()
@@ -231,13 +272,15 @@ func (m *Map) Info() (*Info, error) {
 	}
 
 	if i.FileFormat >= editorVersionReforged {
-		// TODO: two unknown reforged int's
-		b.ReadUInt32()
-		b.ReadUInt32()
+		if b.Size() < 8 {
+			return ErrBadFormat
+		}
+		i.SupportedModes = b.ReadUInt32()
+		i.GameDataVersion = b.ReadUInt32()
 	}
 
 	if b.Size() < 8 {
-		return nil, ErrBadFormat
+		return ErrBadFormat
 	}
 
 	var numPlayers = b.ReadUInt32()
@@ -245,18 +288,18 @@ func (m *Map) Info() (*Info, error) {
 
 	for p := uint32(0); p < numPlayers; p++ {
 		if b.Size() < 37 {
-			return nil, ErrBadFormat
+			return ErrBadFormat
 		}
 		i.Players[p].ID = b.ReadUInt32()
 		i.Players[p].Type = PlayerType(b.ReadUInt32())
 		i.Players[p].Race = Race(b.ReadUInt32())
 		i.Players[p].Flags = PlayerFlags(b.ReadUInt32())
 
-		i.Players[p].Name, err = readTS()
+		i.Players[p].Name, err = b.ReadCString()
 		if err != nil {
-			return nil, err
+			return err
 		} else if b.Size() < 20 {
-			return nil, ErrBadFormat
+			return ErrBadFormat
 		}
 		i.Players[p].StartPosX = b.ReadFloat32()
 		i.Players[p].StartPosY = b.ReadFloat32()
@@ -264,9 +307,11 @@ func (m *Map) Info() (*Info, error) {
 		i.Players[p].AllyPrioHigh = protocol.BitSet32(b.ReadUInt32())
 
 		if i.FileFormat >= editorVersionReforged {
-			// TODO: two unknown reforged player options
-			b.ReadUInt32()
-			b.ReadUInt32()
+			if b.Size() < 12 {
+				return ErrBadFormat
+			}
+			i.Players[p].EnemyPrioLow = protocol.BitSet32(b.ReadUInt32())
+			i.Players[p].EnemyPrioHigh = protocol.BitSet32(b.ReadUInt32())
 		}
 	}
 
@@ -275,13 +320,13 @@ func (m *Map) Info() (*Info, error) {
 
 	for f := uint32(0); f < numForces; f++ {
 		if b.Size() < 9 {
-			return nil, ErrBadFormat
+			return ErrBadFormat
 		}
 		i.Forces[f].Flags = ForceFlags(b.ReadUInt32())
 		i.Forces[f].PlayerSet = protocol.BitSet32(b.ReadUInt32())
-		i.Forces[f].Name, err = readTS()
+		i.Forces[f].Name, err = b.ReadCString()
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
@@ -291,7 +336,7 @@ func (m *Map) Info() (*Info, error) {
 
 		for u := uint32(0); u < numUpgrades; u++ {
 			if b.Size() < 24 {
-				return nil, ErrBadFormat
+				return ErrBadFormat
 			}
 			i.CustomUpgradeAvailabilities[u].PlayerSet = protocol.BitSet32(b.ReadUInt32())
 			i.CustomUpgradeAvailabilities[u].UpgradeID = b.ReadLEDString()
@@ -304,18 +349,332 @@ func (m *Map) Info() (*Info, error) {
 
 		for u := uint32(0); u < numTechs; u++ {
 			if b.Size() < 12 {
-				return nil, ErrBadFormat
+				return ErrBadFormat
 			}
 			i.CustomTechAvailabilities[u].PlayerSet = protocol.BitSet32(b.ReadUInt32())
 			i.CustomTechAvailabilities[u].TechID = b.ReadLEDString()
 		}
+	}
+
+	if b.Size() >= 4 {
+		if i.RandomUnitTables, err = readRandomUnitTables(b); err != nil {
+			return err
+		}
+	}
+
+	if i.FileFormat >= editorVersionTft && b.Size() >= 4 {
+		if i.RandomItemTables, err = readRandomItemTables(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readRandomUnitTables(b *protocol.Buffer) ([]RandomUnitTable, error) {
+	var n = b.ReadUInt32()
+	if n == 0 {
+		return nil, nil
+	}
+
+	var res = make([]RandomUnitTable, n)
+	for t := range res {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		res[t].ID = b.ReadUInt32()
+
+		var err error
+		if res[t].Name, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		var numPos = int(b.ReadUInt32())
+		if b.Size() < numPos*4+4 {
+			return nil, ErrBadFormat
+		}
+		res[t].Positions = make([]RandomPosition, numPos)
+		for p := range res[t].Positions {
+			res[t].Positions[p] = RandomPosition(b.ReadUInt32())
+		}
+
+		res[t].Rows = make([]RandomUnitRow, b.ReadUInt32())
+		for r := range res[t].Rows {
+			if b.Size() < 4+numPos*4 {
+				return nil, ErrBadFormat
+			}
+			res[t].Rows[r].Chance = b.ReadUInt32()
+			res[t].Rows[r].IDs = make([]protocol.DWordString, numPos)
+			for p := range res[t].Rows[r].IDs {
+				res[t].Rows[r].IDs[p] = b.ReadLEDString()
+			}
+		}
+	}
+	return res, nil
+}
+
+func readRandomItemTables(b *protocol.Buffer) ([]RandomItemTable, error) {
+	var n = b.ReadUInt32()
+	if n == 0 {
+		return nil, nil
+	}
+
+	var res = make([]RandomItemTable, n)
+	for t := range res {
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		res[t].ID = b.ReadUInt32()
+
+		var err error
+		if res[t].Name, err = b.ReadCString(); err != nil {
+			return nil, err
+		}
+
+		if b.Size() < 4 {
+			return nil, ErrBadFormat
+		}
+		res[t].Sets = make([]ItemSet, b.ReadUInt32())
+		for s := range res[t].Sets {
+			if b.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+			var num = int(b.ReadUInt32())
+			if b.Size() < num*8 {
+				return nil, ErrBadFormat
+			}
+			res[t].Sets[s] = make(ItemSet, num)
+			for d := range res[t].Sets[s] {
+				res[t].Sets[s][d].Chance = b.ReadUInt32()
+				res[t].Sets[s][d].ItemID = b.ReadLEDString()
+			}
+		}
+	}
+	return res, nil
+}
 
-		// TODO: RandomUnitTable and RandomItemTable
+// Serialize encodes the info into its binary form
+func (i *Info) Serialize(b *protocol.Buffer) error {
+	switch i.FileFormat {
+	case editorVersionRoc:
+	case editorVersionTft:
+	case editorVersion131:
+	case editorVersionReforged:
+	default:
+		return ErrBadFormat
+	}
+
+	b.WriteUInt32(i.FileFormat)
+	b.WriteUInt32(i.SaveCount)
+	b.WriteUInt32(i.EditorVersion)
+
+	if i.FileFormat >= editorVersion131 {
+		b.WriteUInt32(i.GameVersion.Major)
+		b.WriteUInt32(i.GameVersion.Minor)
+		b.WriteUInt32(i.GameVersion.Patch)
+		b.WriteUInt32(i.GameVersion.Commit)
+	}
+
+	b.WriteCString(i.Name)
+	b.WriteCString(i.Author)
+	b.WriteCString(i.Description)
+	b.WriteCString(i.SuggestedPlayers)
+
+	for c := 0; c < len(i.CamBounds); c++ {
+		b.WriteFloat32(i.CamBounds[c])
+	}
+	for c := 0; c < len(i.CamBoundsCompl); c++ {
+		b.WriteUInt32(i.CamBoundsCompl[c])
+	}
+
+	b.WriteUInt32(i.Width)
+	b.WriteUInt32(i.Height)
+	b.WriteUInt32(uint32(i.Flags))
+
+	b.WriteUInt8(byte(i.Tileset))
+	b.WriteUInt32(i.LsBackground)
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteCString(i.LsPath)
+	}
+	b.WriteCString(i.LsText)
+	b.WriteCString(i.LsTitle)
+	b.WriteCString(i.LsSubTitle)
+
+	b.WriteUInt32(i.DataSet)
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteCString(i.PsPath)
+	}
+	b.WriteCString(i.PsText)
+	b.WriteCString(i.PsTitle)
+	b.WriteCString(i.PsSubTitle)
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteUInt32(i.Fog)
+		b.WriteFloat32(i.FogStart)
+		b.WriteFloat32(i.FogEnd)
+		b.WriteFloat32(i.FogDensity)
+		b.WriteUInt32(i.FogColor)
+		b.WriteLEDString(i.WeatherID)
+		b.WriteCString(i.SoundEnv)
+		b.WriteUInt8(byte(i.LightEnv))
+		b.WriteUInt32(i.WaterColor)
+	}
+
+	if i.FileFormat >= editorVersion131 {
+		b.WriteUInt32(uint32(i.CodeFormat))
+	}
+
+	if i.FileFormat >= editorVersionReforged {
+		b.WriteUInt32(i.SupportedModes)
+		b.WriteUInt32(i.GameDataVersion)
+	}
+
+	b.WriteUInt32(uint32(len(i.Players)))
+	for _, p := range i.Players {
+		b.WriteUInt32(p.ID)
+		b.WriteUInt32(uint32(p.Type))
+		b.WriteUInt32(uint32(p.Race))
+		b.WriteUInt32(uint32(p.Flags))
+		b.WriteCString(p.Name)
+		b.WriteFloat32(p.StartPosX)
+		b.WriteFloat32(p.StartPosY)
+		b.WriteUInt32(uint32(p.AllyPrioLow))
+		b.WriteUInt32(uint32(p.AllyPrioHigh))
+		if i.FileFormat >= editorVersionReforged {
+			b.WriteUInt32(uint32(p.EnemyPrioLow))
+			b.WriteUInt32(uint32(p.EnemyPrioHigh))
+		}
+	}
+
+	b.WriteUInt32(uint32(len(i.Forces)))
+	for _, f := range i.Forces {
+		b.WriteUInt32(uint32(f.Flags))
+		b.WriteUInt32(uint32(f.PlayerSet))
+		b.WriteCString(f.Name)
+	}
+
+	b.WriteUInt32(uint32(len(i.CustomUpgradeAvailabilities)))
+	for _, u := range i.CustomUpgradeAvailabilities {
+		b.WriteUInt32(uint32(u.PlayerSet))
+		b.WriteLEDString(u.UpgradeID)
+		b.WriteUInt32(u.Level)
+		b.WriteUInt32(uint32(u.Availability))
+	}
+
+	b.WriteUInt32(uint32(len(i.CustomTechAvailabilities)))
+	for _, u := range i.CustomTechAvailabilities {
+		b.WriteUInt32(uint32(u.PlayerSet))
+		b.WriteLEDString(u.TechID)
+	}
+
+	b.WriteUInt32(uint32(len(i.RandomUnitTables)))
+	for _, t := range i.RandomUnitTables {
+		b.WriteUInt32(t.ID)
+		b.WriteCString(t.Name)
+		b.WriteUInt32(uint32(len(t.Positions)))
+		for _, p := range t.Positions {
+			b.WriteUInt32(uint32(p))
+		}
+		b.WriteUInt32(uint32(len(t.Rows)))
+		for _, r := range t.Rows {
+			if len(r.IDs) != len(t.Positions) {
+				return ErrBadFormat
+			}
+			b.WriteUInt32(r.Chance)
+			for _, id := range r.IDs {
+				b.WriteLEDString(id)
+			}
+		}
+	}
+
+	if i.FileFormat >= editorVersionTft {
+		b.WriteUInt32(uint32(len(i.RandomItemTables)))
+		for _, t := range i.RandomItemTables {
+			b.WriteUInt32(t.ID)
+			b.WriteCString(t.Name)
+			b.WriteUInt32(uint32(len(t.Sets)))
+			for _, s := range t.Sets {
+				b.WriteUInt32(uint32(len(s)))
+				for _, d := range s {
+					b.WriteUInt32(d.Chance)
+					b.WriteLEDString(d.ItemID)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Info read from war3map.w3i, trigger strings are expanded
+func (m *Map) Info() (*Info, error) {
+	b, err := m.readFile("war3map.w3i")
+	if err != nil {
+		return nil, err
+	}
+
+	var i Info
+	if err := i.Deserialize(b); err != nil {
+		return nil, err
+	}
+
+	ts, err := m.TriggerStrings()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range []*string{
+		&i.Name, &i.Author, &i.Description, &i.SuggestedPlayers,
+		&i.LsPath, &i.LsText, &i.LsTitle, &i.LsSubTitle,
+		&i.PsPath, &i.PsText, &i.PsTitle, &i.PsSubTitle,
+		&i.SoundEnv,
+	} {
+		*s = expandString(ts, *s)
+	}
+	for p := range i.Players {
+		i.Players[p].Name = expandString(ts, i.Players[p].Name)
+	}
+	for f := range i.Forces {
+		i.Forces[f].Name = expandString(ts, i.Forces[f].Name)
 	}
 
 	return &i, nil
 }
 
+// gameVersion reads the game version from the war3map.w3i header, without decoding the
+// rest of the file or expanding trigger strings. Zero for maps saved before 1.31.
+func (m *Map) gameVersion() (GameVersion, error) {
+	b, err := m.readFile("war3map.w3i")
+	if err != nil {
+		return GameVersion{}, err
+	}
+	if b.Size() < 12 {
+		return GameVersion{}, ErrBadFormat
+	}
+
+	var format = b.ReadUInt32()
+	if format < editorVersion131 {
+		return GameVersion{}, nil
+	}
+
+	b.Skip(8)
+	if b.Size() < 16 {
+		return GameVersion{}, ErrBadFormat
+	}
+
+	return GameVersion{
+		Major:  b.ReadUInt32(),
+		Minor:  b.ReadUInt32(),
+		Patch:  b.ReadUInt32(),
+		Commit: b.ReadUInt32(),
+	}, nil
+}
+
 // Size returns the map size category
 func (m *Info) Size() Size {
 	var s = m.Width * m.Height
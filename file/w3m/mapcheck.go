@@ -0,0 +1,82 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Maps are identified by the SHA1 hash of the map file since 1.32
+func sha1Only(enc w3gs.Encoding) bool {
+	return enc.GameVersion == 0 || enc.GameVersion >= 10032
+}
+
+// Hash returns the hash that identifies the map for the game version in enc
+func (m *Map) Hash(stor *fs.Storage, enc w3gs.Encoding) (*Hash, error) {
+	if sha1Only(enc) {
+		return m.ChecksumSHA1()
+	}
+	return m.Checksum(stor)
+}
+
+// MapCheck generates the w3gs.MapCheck packet for the map, where path is the
+// location of the map file relative to the game directory (i.e. "Maps\Download\map.w3x")
+//
+// MapXoro and MapSha1 are taken from Hash, the same values used in GameSettings. Since 1.32,
+// that makes MapXoro NoXoro. This matches the game settings recorded in 1.32 replays, but
+// it has not been verified against a captured 1.32+ MapCheck packet.
+func (m *Map) MapCheck(path string, stor *fs.Storage, enc w3gs.Encoding) (*w3gs.MapCheck, error) {
+	fh, err := m.FileChecksum()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := m.Hash(stor, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w3gs.MapCheck{
+		FilePath: path,
+		FileSize: fh.Size,
+		FileCRC:  fh.CRC32,
+		MapXoro:  h.Xoro,
+		MapSha1:  h.Sha1,
+	}, nil
+}
+
+// GameSettingFlags returns the default game settings for the map: fast speed, default terrain and
+// no observers. Teams are together and fixed if the map has fixed player settings, other lobby
+// options are not derived from the map.
+func (i *Info) GameSettingFlags() w3gs.GameSettingFlags {
+	var res = w3gs.SettingSpeedFast | w3gs.SettingTerrainDefault | w3gs.SettingObsNone
+	if i.Flags&MapFlagFixedPlayerSettings != 0 {
+		res |= w3gs.SettingTeamsTogether | w3gs.SettingTeamsFixed
+	}
+	return res
+}
+
+// GameSettings generates w3gs.GameSettings for the map, see MapCheck for a description of path
+func (m *Map) GameSettings(path string, stor *fs.Storage, enc w3gs.Encoding) (*w3gs.GameSettings, error) {
+	info, err := m.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := m.Hash(stor, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w3gs.GameSettings{
+		GameSettingFlags: info.GameSettingFlags(),
+		MapWidth:         uint16(info.Width),
+		MapHeight:        uint16(info.Height),
+		MapXoro:          hash.Xoro,
+		MapPath:          path,
+		MapSha1:          hash.Sha1,
+	}, nil
+}
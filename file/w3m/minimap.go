@@ -9,32 +9,55 @@ import (
 	"image/color"
 	"image/draw"
 	"io"
+	"os"
 
 	"github.com/ftrvxmtrx/tga"
 	"github.com/nielsAD/gowarcraft3/file/blp"
+	"github.com/nielsAD/gowarcraft3/file/dds"
 	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
-// Preview returns a preview image
-func (m *Map) Preview() (image.Image, error) {
-	f, err := m.Archive.Open("war3mapPreview.tga")
-	if err != nil {
-		return nil, err
+type imageFile struct {
+	name   string
+	decode func(io.Reader) (image.Image, error)
+}
+
+var previewFiles = []imageFile{
+	imageFile{"war3mapPreview.tga", tga.Decode},
+	imageFile{"war3mapPreview.dds", dds.Decode},
+}
+
+var minimapFiles = []imageFile{
+	imageFile{"war3mapMap.blp", blp.Decode},
+	imageFile{"war3mapMap.tga", tga.Decode},
+	imageFile{"war3mapMap.dds", dds.Decode},
+}
+
+// Decode the first image in files that exists in the archive
+func (m *Map) decodeImage(files []imageFile) (image.Image, error) {
+	for _, file := range files {
+		f, err := m.Archive.Open(file.name)
+		if err == os.ErrNotExist {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return file.decode(f)
 	}
-	defer f.Close()
 
-	return tga.Decode(f)
+	return nil, os.ErrNotExist
+}
+
+// Preview returns a preview image
+func (m *Map) Preview() (image.Image, error) {
+	return m.decodeImage(previewFiles)
 }
 
 // Minimap returns an image with the minimap
 func (m *Map) Minimap() (image.Image, error) {
-	f, err := m.Archive.Open("war3mapMap.blp")
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	return blp.Decode(f)
+	return m.decodeImage(minimapFiles)
 }
 
 // MinimapIcons returns an image with (just the) minimap icons
@@ -110,3 +133,42 @@ func (m *Map) MenuMinimap() (image.Image, error) {
 
 	return res, nil
 }
+
+// RenderMinimap renders a minimap image from terrain (for maps without embedded minimap), combined with MinimapIcons
+func (m *Map) RenderMinimap() (image.Image, error) {
+	t, err := m.Terrain()
+	if err != nil {
+		return nil, err
+	}
+
+	var ter = t.Image()
+	var res = image.NewRGBA(image.Rect(0, 0, 256, 256))
+	draw.Draw(res, res.Rect, image.Black, image.Point{}, draw.Src)
+
+	var w, h = ter.Rect.Dx(), ter.Rect.Dy()
+	if w > 0 && h > 0 {
+		// Scale to fit, preserving aspect ratio
+		var dw, dh = 256, 256
+		if w > h {
+			dh = 256 * h / w
+		} else {
+			dw = 256 * w / h
+		}
+
+		var ox, oy = (256 - dw) / 2, (256 - dh) / 2
+		for y := 0; y < dh; y++ {
+			for x := 0; x < dw; x++ {
+				res.SetRGBA(ox+x, oy+y, ter.RGBAAt(x*w/dw, y*h/dh))
+			}
+		}
+	}
+
+	icons, err := m.MinimapIcons()
+	if err == nil {
+		draw.Draw(res, res.Rect, icons, icons.Bounds().Min, draw.Over)
+	} else if err != os.ErrNotExist {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,590 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/file/slk"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// ObjectType enum
+type ObjectType uint8
+
+// Object data types
+const (
+	ObjectUnits ObjectType = iota
+	ObjectItems
+	ObjectDestructables
+	ObjectDoodads
+	ObjectAbilities
+	ObjectBuffs
+	ObjectUpgrades
+)
+
+var objectFiles = []string{"war3map.w3u", "war3map.w3t", "war3map.w3b", "war3map.w3d", "war3map.w3a", "war3map.w3h", "war3map.w3q"}
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectUnits:
+		return "Units"
+	case ObjectItems:
+		return "Items"
+	case ObjectDestructables:
+		return "Destructables"
+	case ObjectDoodads:
+		return "Doodads"
+	case ObjectAbilities:
+		return "Abilities"
+	case ObjectBuffs:
+		return "Buffs"
+	case ObjectUpgrades:
+		return "Upgrades"
+	default:
+		return fmt.Sprintf("ObjectType(0x%02X)", uint8(t))
+	}
+}
+
+// FileName of the object data file in the map archive
+func (t ObjectType) FileName() string {
+	if int(t) >= len(objectFiles) {
+		return ""
+	}
+	return objectFiles[t]
+}
+
+// Leveled object types store a level/variation and data pointer for each modification
+func (t ObjectType) Leveled() bool {
+	return t == ObjectDoodads || t == ObjectAbilities || t == ObjectUpgrades
+}
+
+// ValueType enum
+type ValueType uint32
+
+// Modification value types
+const (
+	ValueInt ValueType = iota
+	ValueReal
+	ValueUnreal
+	ValueString
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case ValueInt:
+		return "Int"
+	case ValueReal:
+		return "Real"
+	case ValueUnreal:
+		return "Unreal"
+	case ValueString:
+		return "String"
+	default:
+		return fmt.Sprintf("ValueType(0x%02X)", uint32(t))
+	}
+}
+
+// Modification of a single object field
+type Modification struct {
+	ID          protocol.DWordString
+	Level       uint32 // Level or variation (leveled object types only)
+	DataPointer uint32 // Data column, 1 = A, 2 = B, ... (leveled object types only)
+	Type        ValueType
+	Int         int32
+	Real        float32 // Real and Unreal
+	String      string
+	End         protocol.DWordString
+}
+
+// Value formatted as string
+func (m *Modification) Value() string {
+	switch m.Type {
+	case ValueInt:
+		return strconv.Itoa(int(m.Int))
+	case ValueReal, ValueUnreal:
+		return strconv.FormatFloat(float64(m.Real), 'f', -1, 32)
+	default:
+		return m.String
+	}
+}
+
+// ObjectSet is a set of modifications (format version >= 3)
+type ObjectSet struct {
+	Flags         uint32
+	Modifications []Modification
+}
+
+// Object definition in object data file
+type Object struct {
+	OriginalID protocol.DWordString
+	CustomID   protocol.DWordString
+	Sets       []ObjectSet
+}
+
+// ID of the object, CustomID for custom objects
+func (o *Object) ID() protocol.DWordString {
+	if o.CustomID != 0 {
+		return o.CustomID
+	}
+	return o.OriginalID
+}
+
+// ObjectData as found in the war3map.w3u, w3t, w3b, w3d, w3a, w3h and w3q files
+type ObjectData struct {
+	Type       ObjectType
+	FileFormat uint32
+	Original   []Object
+	Custom     []Object
+}
+
+const objectVersionSets = 3
+
+// Serialize encodes the object data into its binary form
+func (o *ObjectData) Serialize(buf *protocol.Buffer) error {
+	buf.WriteUInt32(o.FileFormat)
+	for _, tbl := range [][]Object{o.Original, o.Custom} {
+		buf.WriteUInt32(uint32(len(tbl)))
+		for _, obj := range tbl {
+			buf.WriteLEDString(obj.OriginalID)
+			buf.WriteLEDString(obj.CustomID)
+
+			var sets = obj.Sets
+			if o.FileFormat >= objectVersionSets {
+				buf.WriteUInt32(uint32(len(sets)))
+			} else if len(sets) > 1 {
+				return ErrBadFormat
+			} else if len(sets) == 0 {
+				sets = []ObjectSet{ObjectSet{}}
+			}
+
+			for _, set := range sets {
+				if o.FileFormat >= objectVersionSets {
+					buf.WriteUInt32(set.Flags)
+				}
+
+				buf.WriteUInt32(uint32(len(set.Modifications)))
+				for _, m := range set.Modifications {
+					buf.WriteLEDString(m.ID)
+					if o.Type.Leveled() {
+						buf.WriteUInt32(m.Level)
+						buf.WriteUInt32(m.DataPointer)
+					}
+					buf.WriteUInt32(uint32(m.Type))
+					switch m.Type {
+					case ValueInt:
+						buf.WriteUInt32(uint32(m.Int))
+					case ValueReal, ValueUnreal:
+						buf.WriteFloat32(m.Real)
+					case ValueString:
+						buf.WriteCString(m.String)
+					default:
+						return ErrBadFormat
+					}
+					buf.WriteLEDString(m.End)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize, o.Type must be set
+func (o *ObjectData) Deserialize(buf *protocol.Buffer) error {
+	if buf.Size() < 12 {
+		return ErrBadFormat
+	}
+
+	o.FileFormat = buf.ReadUInt32()
+	if o.FileFormat < 1 || o.FileFormat > objectVersionSets {
+		return ErrBadFormat
+	}
+
+	var err error
+	if o.Original, err = o.readTable(buf); err != nil {
+		return err
+	}
+	if o.Custom, err = o.readTable(buf); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *ObjectData) readTable(buf *protocol.Buffer) ([]Object, error) {
+	if buf.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	var res = make([]Object, buf.ReadUInt32())
+	for i := range res {
+		if buf.Size() < 12 {
+			return nil, ErrBadFormat
+		}
+
+		res[i].OriginalID = buf.ReadLEDString()
+		res[i].CustomID = buf.ReadLEDString()
+
+		var numSets = 1
+		if o.FileFormat >= objectVersionSets {
+			numSets = int(buf.ReadUInt32())
+		}
+
+		res[i].Sets = make([]ObjectSet, numSets)
+		for s := range res[i].Sets {
+			var set = &res[i].Sets[s]
+			if o.FileFormat >= objectVersionSets {
+				if buf.Size() < 8 {
+					return nil, ErrBadFormat
+				}
+				set.Flags = buf.ReadUInt32()
+			}
+
+			if buf.Size() < 4 {
+				return nil, ErrBadFormat
+			}
+
+			set.Modifications = make([]Modification, buf.ReadUInt32())
+			for m := range set.Modifications {
+				if err := o.readModification(buf, &set.Modifications[m]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (o *ObjectData) readModification(buf *protocol.Buffer, m *Modification) error {
+	if buf.Size() < 4 {
+		return ErrBadFormat
+	}
+	m.ID = buf.ReadLEDString()
+
+	if o.Type.Leveled() {
+		if buf.Size() < 8 {
+			return ErrBadFormat
+		}
+		m.Level = buf.ReadUInt32()
+		m.DataPointer = buf.ReadUInt32()
+	}
+
+	if buf.Size() < 4 {
+		return ErrBadFormat
+	}
+	m.Type = ValueType(buf.ReadUInt32())
+
+	switch m.Type {
+	case ValueInt:
+		if buf.Size() < 4 {
+			return ErrBadFormat
+		}
+		m.Int = int32(buf.ReadUInt32())
+	case ValueReal, ValueUnreal:
+		if buf.Size() < 4 {
+			return ErrBadFormat
+		}
+		m.Real = buf.ReadFloat32()
+	case ValueString:
+		var err error
+		if m.String, err = buf.ReadCString(); err != nil {
+			return ErrBadFormat
+		}
+	default:
+		return ErrBadFormat
+	}
+
+	if buf.Size() < 4 {
+		return ErrBadFormat
+	}
+	m.End = buf.ReadLEDString()
+
+	return nil
+}
+
+// ObjectData read from the object data file of type t
+func (m *Map) ObjectData(t ObjectType) (*ObjectData, error) {
+	if t.FileName() == "" {
+		return nil, ErrBadFormat
+	}
+
+	f, err := m.Archive.Open(t.FileName())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, f); err != nil {
+		return nil, err
+	}
+
+	var res = ObjectData{Type: t}
+	if err := res.Deserialize(&b); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// GameData loads the base game data from stor (see slk.Load), with TRIGSTR_ references resolved
+// through the trigger strings of m
+func (m *Map) GameData(stor *fs.Storage) (*slk.Data, error) {
+	d, err := slk.Load(stor)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := m.TriggerStrings()
+	if err != nil && err != os.ErrNotExist {
+		return nil, err
+	}
+
+	d.TriggerStrings = ts
+	return d, nil
+}
+
+// ObjectValues maps object IDs to their field values (indexed by SLK column name)
+type ObjectValues map[protocol.DWordString]map[string]string
+
+// Metadata and base SLK tables for each object type
+var objectMeta = []string{
+	"Units\\UnitMetaData.slk",
+	"Units\\UnitMetaData.slk",
+	"Units\\DestructableMetaData.slk",
+	"Doodads\\DoodadMetaData.slk",
+	"Units\\AbilityMetaData.slk",
+	"Units\\AbilityBuffMetaData.slk",
+	"Units\\UpgradeMetaData.slk",
+}
+
+// Game data category of object types with profile data
+var objectCategory = map[ObjectType]slk.Category{
+	ObjectUnits:         slk.CategoryUnit,
+	ObjectItems:         slk.CategoryItem,
+	ObjectDestructables: slk.CategoryDestructable,
+	ObjectAbilities:     slk.CategoryAbility,
+	ObjectBuffs:         slk.CategoryBuff,
+	ObjectUpgrades:      slk.CategoryUpgrade,
+}
+
+var objectSLK = map[string]string{
+	"UnitAbilities":    "Units\\UnitAbilities.slk",
+	"UnitBalance":      "Units\\UnitBalance.slk",
+	"UnitData":         "Units\\UnitData.slk",
+	"UnitUI":           "Units\\UnitUI.slk",
+	"UnitWeapons":      "Units\\UnitWeapons.slk",
+	"ItemData":         "Units\\ItemData.slk",
+	"DestructableData": "Units\\DestructableData.slk",
+	"Doodads":          "Doodads\\Doodads.slk",
+	"AbilityData":      "Units\\AbilityData.slk",
+	"AbilityBuffData":  "Units\\AbilityBuffData.slk",
+	"UpgradeData":      "Units\\UpgradeData.slk",
+}
+
+func openSLK(stor *fs.Storage, fileName string) (*slk.Table, error) {
+	f, err := stor.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return slk.Decode(f)
+}
+
+type fieldMeta struct {
+	field  string
+	slk    string
+	repeat bool
+	data   bool
+}
+
+// Column name of the modified field in the base data
+func (f *fieldMeta) column(m *Modification) string {
+	var res = f.field
+	if f.data && m.DataPointer > 0 && m.DataPointer <= 26 {
+		res += string(rune('A' + m.DataPointer - 1))
+	}
+	if f.repeat && m.Level > 0 {
+		res += strconv.Itoa(int(m.Level))
+	}
+	return res
+}
+
+// Read the base (unmodified) values of objects of type t from the SLK and profile files in stor,
+// and the metadata that maps modification IDs to fields.
+func baseObjects(stor *fs.Storage, t ObjectType) (ObjectValues, map[protocol.DWordString]*fieldMeta, error) {
+	if int(t) >= len(objectMeta) {
+		return nil, nil, ErrBadFormat
+	}
+
+	meta, err := openSLK(stor, objectMeta[t])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var colField, colSLK, colRepeat, colData = meta.Column("field"), meta.Column("slk"), meta.Column("repeat"), meta.Column("data")
+	if colField < 0 || colSLK < 0 {
+		return nil, nil, ErrBadFormat
+	}
+
+	var fields = make(map[protocol.DWordString]*fieldMeta, len(meta.Rows))
+	var tables = map[string]bool{}
+	for _, r := range meta.Rows {
+		var id protocol.DWordString
+		if err := id.UnmarshalText([]byte(r[0])); err != nil {
+			continue
+		}
+
+		var f = fieldMeta{field: r[colField], slk: r[colSLK]}
+		if colRepeat >= 0 {
+			n, _ := strconv.Atoi(r[colRepeat])
+			f.repeat = n > 0
+		}
+		if colData >= 0 {
+			n, _ := strconv.Atoi(r[colData])
+			f.data = n > 0
+		}
+
+		fields[id] = &f
+		tables[f.slk] = true
+	}
+
+	var res = ObjectValues{}
+	for name := range tables {
+		var fileName, ok = objectSLK[name]
+		if !ok {
+			continue
+		}
+
+		tbl, err := openSLK(stor, fileName)
+		if err == os.ErrNotExist {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		for _, r := range tbl.Rows {
+			var id protocol.DWordString
+			if err := id.UnmarshalText([]byte(r[0])); err != nil {
+				continue
+			}
+
+			var obj = res[id]
+			if obj == nil {
+				obj = map[string]string{}
+				res[id] = obj
+			}
+			for c, h := range tbl.Header[1:] {
+				if _, ok := obj[h]; !ok {
+					obj[h] = r[c+1]
+				}
+			}
+		}
+	}
+
+	var cat, ok = objectCategory[t]
+	if !ok || !tables["Profile"] {
+		return res, fields, nil
+	}
+
+	// Profile keys are stored in lower case, map them back to field names
+	var names = map[string]string{}
+	for _, f := range fields {
+		if f.slk == "Profile" {
+			names[strings.ToLower(f.field)] = f.field
+		}
+	}
+
+	prof, err := slk.LoadProfile(stor, cat.ProfileFiles())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for sec, kv := range prof {
+		var id protocol.DWordString
+		if len(sec) != 4 || id.UnmarshalText([]byte(sec)) != nil {
+			continue
+		}
+
+		var obj = res[id]
+		if obj == nil {
+			obj = map[string]string{}
+			res[id] = obj
+		}
+		for k, v := range kv {
+			if n, ok := names[k]; ok {
+				k = n
+			}
+			if _, ok := obj[k]; !ok {
+				obj[k] = v
+			}
+		}
+	}
+
+	return res, fields, nil
+}
+
+// Merge applies the modifications in o over the base data read from stor (SLK tables, profile
+// data and metadata), and returns the effective values for every (base and custom) object.
+//
+// Modifications of fields that are not in the metadata are returned separately in unknown,
+// indexed by modification ID.
+func (o *ObjectData) Merge(stor *fs.Storage) (values ObjectValues, unknown ObjectValues, err error) {
+	base, fields, err := baseObjects(stor, o.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unknown = ObjectValues{}
+	var apply = func(id protocol.DWordString, obj map[string]string, sets []ObjectSet) {
+		for _, s := range sets {
+			for i := range s.Modifications {
+				var m = &s.Modifications[i]
+				if f := fields[m.ID]; f != nil {
+					obj[f.column(m)] = m.Value()
+					continue
+				}
+
+				if unknown[id] == nil {
+					unknown[id] = map[string]string{}
+				}
+				unknown[id][m.ID.String()] = m.Value()
+			}
+		}
+	}
+
+	// Custom objects are derived from the unmodified base object
+	var custom = make(ObjectValues, len(o.Custom))
+	for _, obj := range o.Custom {
+		var vals = map[string]string{}
+		for k, v := range base[obj.OriginalID] {
+			vals[k] = v
+		}
+		apply(obj.ID(), vals, obj.Sets)
+		custom[obj.ID()] = vals
+	}
+
+	for _, obj := range o.Original {
+		var vals = base[obj.OriginalID]
+		if vals == nil {
+			vals = map[string]string{}
+			base[obj.OriginalID] = vals
+		}
+		apply(obj.OriginalID, vals, obj.Sets)
+	}
+
+	for id, vals := range custom {
+		base[id] = vals
+	}
+
+	return base, unknown, nil
+}
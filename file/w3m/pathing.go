@@ -0,0 +1,262 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// PathingHeader is the magic header of war3map.wpm
+var PathingHeader = protocol.DString("MP3W")
+
+// Size of a single tile in world coordinates
+const tileSize = 128
+
+// Number of pathing/shadow cells per tile (in each direction)
+const cellsPerTile = 4
+
+// PathingFlags enum
+type PathingFlags uint8
+
+// Pathing flags
+const (
+	PathingNoWalk  PathingFlags = 0x02
+	PathingNoFly   PathingFlags = 0x04
+	PathingNoBuild PathingFlags = 0x08
+	PathingBlight  PathingFlags = 0x20
+	PathingNoWater PathingFlags = 0x40
+	PathingUnknown PathingFlags = 0x80
+)
+
+// Grid of cells that divide the map (4x4 cells per tile), starting in the bottom-left corner
+type Grid struct {
+	Width  uint32
+	Height uint32
+
+	// World coordinates of the bottom-left corner (see Info.CamBounds)
+	OffsetX float32
+	OffsetY float32
+}
+
+// CellSize is the size of a single cell in world coordinates
+const CellSize = tileSize / cellsPerTile
+
+// Cell returns the cell that contains the point at world coordinates x, y
+func (g *Grid) Cell(x float32, y float32) (int, int, bool) {
+	var cx = int((x - g.OffsetX) / CellSize)
+	var cy = int((y - g.OffsetY) / CellSize)
+	if x < g.OffsetX || y < g.OffsetY || cx >= int(g.Width) || cy >= int(g.Height) {
+		return 0, 0, false
+	}
+	return cx, cy, true
+}
+
+// World returns the world coordinates of the center of cell x, y
+func (g *Grid) World(x int, y int) (float32, float32) {
+	return g.OffsetX + (float32(x)+0.5)*CellSize, g.OffsetY + (float32(y)+0.5)*CellSize
+}
+
+// Grid that covers terrain t
+func (t *Terrain) grid() Grid {
+	if t.Width < 1 || t.Height < 1 {
+		return Grid{}
+	}
+	return Grid{
+		Width:   (t.Width - 1) * cellsPerTile,
+		Height:  (t.Height - 1) * cellsPerTile,
+		OffsetX: t.OffsetX,
+		OffsetY: t.OffsetY,
+	}
+}
+
+// Grid that covers the map terrain, cached so war3map.w3e is only decoded once
+func (m *Map) terrainGrid() (Grid, error) {
+	if m.tg == nil {
+		t, err := m.Terrain()
+		if err != nil {
+			return Grid{}, err
+		}
+
+		var g = t.grid()
+		m.tg = &g
+	}
+
+	return *m.tg, nil
+}
+
+// Grid of given size, placed according to terrain (if available) or centered around the world origin
+func (m *Map) grid(w uint32, h uint32) Grid {
+	var g = Grid{
+		Width:   w,
+		Height:  h,
+		OffsetX: -float32(w) * CellSize / 2,
+		OffsetY: -float32(h) * CellSize / 2,
+	}
+
+	if tg, err := m.terrainGrid(); err == nil && tg.Width == w && tg.Height == h {
+		g = tg
+	}
+
+	return g
+}
+
+// PathingMap as found in the war3map.wpm file
+type PathingMap struct {
+	Grid
+	Cells []PathingFlags
+}
+
+// At returns the pathing flags for cell x, y (counted from the bottom)
+func (p *PathingMap) At(x int, y int) PathingFlags {
+	return p.Cells[y*int(p.Width)+x]
+}
+
+// AtWorld returns the pathing flags at world coordinates x, y
+func (p *PathingMap) AtWorld(x float32, y float32) (PathingFlags, bool) {
+	cx, cy, ok := p.Cell(x, y)
+	if !ok {
+		return 0, false
+	}
+	return p.At(cx, cy), true
+}
+
+// Image returns the pathing map with the same color coding as war3mapPath.tga
+// (red = no walk, green = no fly, blue = no build)
+func (p *PathingMap) Image() *image.RGBA {
+	var img = image.NewRGBA(image.Rect(0, 0, int(p.Width), int(p.Height)))
+	for y := 0; y < int(p.Height); y++ {
+		for x := 0; x < int(p.Width); x++ {
+			var f = p.At(x, y)
+			var c = color.RGBA{A: 0xFF}
+			if f&PathingNoWalk != 0 {
+				c.R = 0xFF
+			}
+			if f&PathingNoFly != 0 {
+				c.G = 0xFF
+			}
+			if f&PathingNoBuild != 0 {
+				c.B = 0xFF
+			}
+			img.SetRGBA(x, int(p.Height)-y-1, c)
+		}
+	}
+	return img
+}
+
+// Mask returns an image mask that is opaque for cells that have any of the given flags set
+func (p *PathingMap) Mask(flags PathingFlags) *image.Alpha {
+	var img = image.NewAlpha(image.Rect(0, 0, int(p.Width), int(p.Height)))
+	for y := 0; y < int(p.Height); y++ {
+		for x := 0; x < int(p.Width); x++ {
+			if p.At(x, y)&flags != 0 {
+				img.SetAlpha(x, int(p.Height)-y-1, color.Alpha{0xFF})
+			}
+		}
+	}
+	return img
+}
+
+// Pathing map read from war3map.wpm
+func (m *Map) Pathing() (*PathingMap, error) {
+	wpm, err := m.Archive.Open("war3map.wpm")
+	if err != nil {
+		return nil, err
+	}
+	defer wpm.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, wpm); err != nil {
+		return nil, err
+	}
+
+	if b.Size() < 16 || b.ReadLEDString() != PathingHeader || b.ReadUInt32() != 0 {
+		return nil, ErrBadFormat
+	}
+
+	var w = b.ReadUInt32()
+	var h = b.ReadUInt32()
+	if w > 0x40000 || h > 0x40000 || b.Size() != int(w)*int(h) {
+		return nil, ErrBadFormat
+	}
+
+	var res = PathingMap{
+		Grid:  m.grid(w, h),
+		Cells: make([]PathingFlags, w*h),
+	}
+	for i, v := range b.ReadBlob(len(res.Cells)) {
+		res.Cells[i] = PathingFlags(v)
+	}
+
+	return &res, nil
+}
+
+// ShadowMap as found in the war3map.shd file
+type ShadowMap struct {
+	Grid
+	Cells []bool
+}
+
+// At returns true if cell x, y (counted from the bottom) is in shadow
+func (s *ShadowMap) At(x int, y int) bool {
+	return s.Cells[y*int(s.Width)+x]
+}
+
+// AtWorld returns true if world coordinates x, y are in shadow
+func (s *ShadowMap) AtWorld(x float32, y float32) (bool, bool) {
+	cx, cy, ok := s.Cell(x, y)
+	if !ok {
+		return false, false
+	}
+	return s.At(cx, cy), true
+}
+
+// Image returns an image mask that is opaque for cells in shadow
+func (s *ShadowMap) Image() *image.Alpha {
+	var img = image.NewAlpha(image.Rect(0, 0, int(s.Width), int(s.Height)))
+	for y := 0; y < int(s.Height); y++ {
+		for x := 0; x < int(s.Width); x++ {
+			if s.At(x, y) {
+				img.SetAlpha(x, int(s.Height)-y-1, color.Alpha{0xFF})
+			}
+		}
+	}
+	return img
+}
+
+// Shadow map read from war3map.shd
+func (m *Map) Shadow() (*ShadowMap, error) {
+	tg, err := m.terrainGrid()
+	if err != nil {
+		return nil, err
+	}
+
+	shd, err := m.Archive.Open("war3map.shd")
+	if err != nil {
+		return nil, err
+	}
+	defer shd.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, shd); err != nil {
+		return nil, err
+	}
+
+	// File has no header, size is determined by the terrain
+	var res = ShadowMap{Grid: tg}
+	if b.Size() != int(res.Width)*int(res.Height) {
+		return nil, ErrBadFormat
+	}
+
+	res.Cells = make([]bool, b.Size())
+	for i, v := range b.Bytes {
+		res.Cells[i] = v != 0
+	}
+
+	return &res, nil
+}
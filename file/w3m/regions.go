@@ -0,0 +1,153 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"image/color"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+const regionVersion = 5
+
+// Region structure in war3map.w3r file
+type Region struct {
+	Left   float32
+	Bottom float32
+	Right  float32
+	Top    float32
+
+	Name           string
+	CreationNumber uint32
+
+	// Weather effect ID (0 if none)
+	Weather protocol.DWordString
+
+	// Sound variable name (see Sound.Name)
+	AmbientSound string
+
+	Color color.RGBA
+}
+
+// Contains returns true if point (x, y) lies within the region bounds
+func (r *Region) Contains(x float32, y float32) bool {
+	return x >= r.Left && x <= r.Right && y >= r.Bottom && y <= r.Top
+}
+
+// Sound looks up the ambient sound of r in s, returns nil if r has no (valid) ambient sound
+func (r *Region) Sound(s *Sounds) *Sound {
+	if r.AmbientSound == "" || s == nil {
+		return nil
+	}
+	return s.Lookup(r.AmbientSound)
+}
+
+// Regions as found in the war3map.w3r file
+type Regions struct {
+	FileFormat uint32
+	Regions    []Region
+}
+
+// Serialize encodes the regions into their binary form
+func (r *Regions) Serialize(buf *protocol.Buffer) error {
+	buf.WriteUInt32(r.FileFormat)
+	buf.WriteUInt32(uint32(len(r.Regions)))
+	for i := range r.Regions {
+		var g = &r.Regions[i]
+		buf.WriteFloat32(g.Left)
+		buf.WriteFloat32(g.Bottom)
+		buf.WriteFloat32(g.Right)
+		buf.WriteFloat32(g.Top)
+		buf.WriteCString(g.Name)
+		buf.WriteUInt32(g.CreationNumber)
+		buf.WriteLEDString(g.Weather)
+		buf.WriteCString(g.AmbientSound)
+		buf.WriteUInt8(g.Color.B)
+		buf.WriteUInt8(g.Color.G)
+		buf.WriteUInt8(g.Color.R)
+		buf.WriteUInt8(g.Color.A)
+	}
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize
+func (r *Regions) Deserialize(buf *protocol.Buffer) error {
+	if buf.Size() < 8 {
+		return ErrBadFormat
+	}
+
+	r.FileFormat = buf.ReadUInt32()
+	if r.FileFormat != regionVersion {
+		return ErrBadFormat
+	}
+
+	var err error
+
+	r.Regions = make([]Region, buf.ReadUInt32())
+	for i := range r.Regions {
+		var g = &r.Regions[i]
+		if buf.Size() < 16 {
+			return ErrBadFormat
+		}
+
+		g.Left = buf.ReadFloat32()
+		g.Bottom = buf.ReadFloat32()
+		g.Right = buf.ReadFloat32()
+		g.Top = buf.ReadFloat32()
+		if g.Name, err = buf.ReadCString(); err != nil {
+			return err
+		}
+
+		if buf.Size() < 8 {
+			return ErrBadFormat
+		}
+		g.CreationNumber = buf.ReadUInt32()
+		g.Weather = buf.ReadLEDString()
+		if g.AmbientSound, err = buf.ReadCString(); err != nil {
+			return err
+		}
+
+		if buf.Size() < 4 {
+			return ErrBadFormat
+		}
+		g.Color.B = buf.ReadUInt8()
+		g.Color.G = buf.ReadUInt8()
+		g.Color.R = buf.ReadUInt8()
+		g.Color.A = buf.ReadUInt8()
+	}
+
+	return nil
+}
+
+func (m *Map) readFile(fileName string) (*protocol.Buffer, error) {
+	f, err := m.Archive.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, f); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// Regions read from war3map.w3r
+func (m *Map) Regions() (*Regions, error) {
+	b, err := m.readFile("war3map.w3r")
+	if err != nil {
+		return nil, err
+	}
+
+	var res Regions
+	if err := res.Deserialize(b); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,25 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"os"
+
+	"github.com/nielsAD/gowarcraft3/file/jass"
+)
+
+// Script parsed from war3map.j (or scripts\war3map.j)
+func (m *Map) Script() (*jass.File, error) {
+	r, err := m.findFile(hashFiles2[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, os.ErrNotExist
+	}
+	defer r.Close()
+
+	return jass.Decode(r)
+}
@@ -0,0 +1,100 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// RacePref converts Race to w3gs.RacePref
+func (r Race) RacePref() w3gs.RacePref {
+	switch r {
+	case RaceHuman:
+		return w3gs.RaceHuman
+	case RaceOrc:
+		return w3gs.RaceOrc
+	case RaceUndead:
+		return w3gs.RaceUndead
+	case RaceNightElf:
+		return w3gs.RaceNightElf
+	default:
+		return w3gs.RaceRandom | w3gs.RaceSelectable
+	}
+}
+
+// SlotLayout derived from map flags
+func (i *Info) SlotLayout() w3gs.SlotLayout {
+	if i.Flags&MapFlagCustomForces == 0 {
+		return w3gs.LayoutMelee
+	}
+
+	var res = w3gs.LayoutCustomForces
+	if i.Flags&MapFlagFixedPlayerSettings != 0 {
+		res |= w3gs.LayoutFixedPlayerSettings
+	}
+	return res
+}
+
+// Team returns the index of the force that player pid belongs to
+func (i *Info) Team(pid uint32) uint8 {
+	if pid >= 32 {
+		return 0
+	}
+	for idx, f := range i.Forces {
+		if f.PlayerSet.Test(uint(pid) + 1) {
+			return uint8(idx)
+		}
+	}
+	return 0
+}
+
+// SlotInfo generates the initial lobby slot layout for the map, in the same way the game host does.
+//
+// Melee maps get an open slot with its own team and selectable race for each start location.
+// Maps with custom forces assign teams according to their force, and pre-fill computer slots.
+// If player settings are fixed, races are set as stored in the map and cannot be changed.
+func (i *Info) SlotInfo() *w3gs.SlotInfo {
+	var res = w3gs.SlotInfo{
+		SlotLayout: i.SlotLayout(),
+	}
+
+	var custom = res.SlotLayout&w3gs.LayoutCustomForces != 0
+	var fixed = res.SlotLayout&w3gs.LayoutFixedPlayerSettings != 0
+
+	for _, p := range i.Players {
+		if p.Type != PlayerHuman && p.Type != PlayerComputer {
+			continue
+		}
+
+		var slot = w3gs.SlotData{
+			DownloadStatus: 255,
+			SlotStatus:     w3gs.SlotOpen,
+			Team:           uint8(len(res.Slots)),
+			Color:          uint8(p.ID),
+			Race:           w3gs.RaceRandom | w3gs.RaceSelectable,
+			ComputerType:   w3gs.ComputerNormal,
+			Handicap:       100,
+		}
+
+		if custom {
+			slot.Team = i.Team(p.ID)
+
+			if p.Type == PlayerComputer {
+				slot.DownloadStatus = 100
+				slot.SlotStatus = w3gs.SlotOccupied
+				slot.Computer = true
+			}
+			if fixed {
+				slot.Race = p.Race.RacePref()
+			}
+		}
+
+		res.Slots = append(res.Slots, slot)
+	}
+
+	res.NumPlayers = uint8(len(res.Slots))
+
+	return &res
+}
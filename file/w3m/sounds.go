@@ -0,0 +1,195 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"os"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Only the classic format is supported, Reforged (version 2+) appends undocumented fields
+const soundVersion = 1
+
+// SoundFlags enum
+type SoundFlags uint32
+
+// Sound flags
+const (
+	SoundFlagLooping        SoundFlags = 0x01
+	SoundFlag3D             SoundFlags = 0x02
+	SoundFlagStopOutOfRange SoundFlags = 0x04
+	SoundFlagMusic          SoundFlags = 0x08
+)
+
+// Sound definition structure in war3map.w3s file
+type Sound struct {
+	// Variable name (i.e. "gg_snd_Rain")
+	Name      string
+	File      string
+	EAXEffect string
+
+	Flags       SoundFlags
+	FadeInRate  uint32
+	FadeOutRate uint32
+	Volume      int32
+	Pitch       float32
+	Unknown1    float32
+	Unknown2    int32
+	Channel     int32
+
+	MinDistance    float32
+	MaxDistance    float32
+	DistanceCutoff float32
+
+	ConeInside        float32
+	ConeOutside       float32
+	ConeOutsideVolume int32
+	ConeOrientationX  float32
+	ConeOrientationY  float32
+	ConeOrientationZ  float32
+}
+
+// Sounds as found in the war3map.w3s file
+type Sounds struct {
+	FileFormat uint32
+	Sounds     []Sound
+}
+
+// Lookup sound by variable name, returns nil if not found
+func (s *Sounds) Lookup(name string) *Sound {
+	for i := range s.Sounds {
+		if s.Sounds[i].Name == name {
+			return &s.Sounds[i]
+		}
+	}
+	return nil
+}
+
+// Serialize encodes the sounds into their binary form
+func (s *Sounds) Serialize(buf *protocol.Buffer) error {
+	if s.FileFormat != soundVersion {
+		return ErrBadFormat
+	}
+
+	buf.WriteUInt32(s.FileFormat)
+	buf.WriteUInt32(uint32(len(s.Sounds)))
+	for i := range s.Sounds {
+		var v = &s.Sounds[i]
+		buf.WriteCString(v.Name)
+		buf.WriteCString(v.File)
+		buf.WriteCString(v.EAXEffect)
+		buf.WriteUInt32(uint32(v.Flags))
+		buf.WriteUInt32(v.FadeInRate)
+		buf.WriteUInt32(v.FadeOutRate)
+		buf.WriteUInt32(uint32(v.Volume))
+		buf.WriteFloat32(v.Pitch)
+		buf.WriteFloat32(v.Unknown1)
+		buf.WriteUInt32(uint32(v.Unknown2))
+		buf.WriteUInt32(uint32(v.Channel))
+		buf.WriteFloat32(v.MinDistance)
+		buf.WriteFloat32(v.MaxDistance)
+		buf.WriteFloat32(v.DistanceCutoff)
+		buf.WriteFloat32(v.ConeInside)
+		buf.WriteFloat32(v.ConeOutside)
+		buf.WriteUInt32(uint32(v.ConeOutsideVolume))
+		buf.WriteFloat32(v.ConeOrientationX)
+		buf.WriteFloat32(v.ConeOrientationY)
+		buf.WriteFloat32(v.ConeOrientationZ)
+	}
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize
+func (s *Sounds) Deserialize(buf *protocol.Buffer) error {
+	if buf.Size() < 8 {
+		return ErrBadFormat
+	}
+
+	s.FileFormat = buf.ReadUInt32()
+	if s.FileFormat != soundVersion {
+		return ErrBadFormat
+	}
+
+	var err error
+
+	s.Sounds = make([]Sound, buf.ReadUInt32())
+	for i := range s.Sounds {
+		var v = &s.Sounds[i]
+		if v.Name, err = buf.ReadCString(); err != nil {
+			return err
+		}
+		if v.File, err = buf.ReadCString(); err != nil {
+			return err
+		}
+		if v.EAXEffect, err = buf.ReadCString(); err != nil {
+			return err
+		}
+
+		if buf.Size() < 68 {
+			return ErrBadFormat
+		}
+
+		v.Flags = SoundFlags(buf.ReadUInt32())
+		v.FadeInRate = buf.ReadUInt32()
+		v.FadeOutRate = buf.ReadUInt32()
+		v.Volume = int32(buf.ReadUInt32())
+		v.Pitch = buf.ReadFloat32()
+		v.Unknown1 = buf.ReadFloat32()
+		v.Unknown2 = int32(buf.ReadUInt32())
+		v.Channel = int32(buf.ReadUInt32())
+		v.MinDistance = buf.ReadFloat32()
+		v.MaxDistance = buf.ReadFloat32()
+		v.DistanceCutoff = buf.ReadFloat32()
+		v.ConeInside = buf.ReadFloat32()
+		v.ConeOutside = buf.ReadFloat32()
+		v.ConeOutsideVolume = int32(buf.ReadUInt32())
+		v.ConeOrientationX = buf.ReadFloat32()
+		v.ConeOrientationY = buf.ReadFloat32()
+		v.ConeOrientationZ = buf.ReadFloat32()
+	}
+
+	return nil
+}
+
+// Sounds read from war3map.w3s
+func (m *Map) Sounds() (*Sounds, error) {
+	b, err := m.readFile("war3map.w3s")
+	if err != nil {
+		return nil, err
+	}
+
+	var res Sounds
+	if err := res.Deserialize(b); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// RegionSounds maps region names to their ambient sound, regions without (valid) ambient sound are omitted
+func (m *Map) RegionSounds() (map[string]*Sound, error) {
+	regions, err := m.Regions()
+	if err != nil {
+		return nil, err
+	}
+
+	var res = map[string]*Sound{}
+
+	sounds, err := m.Sounds()
+	if err == os.ErrNotExist {
+		return res, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i := range regions.Regions {
+		if s := regions.Regions[i].Sound(sounds); s != nil {
+			res[regions.Regions[i].Name] = s
+		}
+	}
+
+	return res, nil
+}
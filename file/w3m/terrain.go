@@ -0,0 +1,331 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// TerrainHeader is the magic header of war3map.w3e
+var TerrainHeader = protocol.DString("W3E!")
+
+// TileFlags enum
+type TileFlags uint16
+
+// Tile flags
+const (
+	TileFlagRamp     TileFlags = 0x0001
+	TileFlagBlight   TileFlags = 0x0002
+	TileFlagWater    TileFlags = 0x0004
+	TileFlagBoundary TileFlags = 0x0008
+	TileFlagMapEdge  TileFlags = 0x0010
+)
+
+// Zero level for ground height and water level
+const tileZero = 0x2000
+
+// Number of height units per cliff layer
+const tileLayer = 512
+
+// Water surface is drawn 89.6 world units (128 per cliff layer) below its level
+const tileWaterOffset = 89.6 / 128
+
+// TilePoint is a single corner in the terrain grid
+type TilePoint struct {
+	GroundHeight  int16
+	WaterLevel    int16
+	Flags         TileFlags
+	GroundTexture uint8
+	Variation     uint8
+	CliffTexture  uint8
+	LayerHeight   uint8
+}
+
+// Height of the ground (including cliff layers) in cliff layer units
+func (p *TilePoint) Height() float32 {
+	return float32(int(p.GroundHeight)-tileZero)/tileLayer + float32(p.LayerHeight) - 2
+}
+
+// WaterHeight in cliff layer units
+func (p *TilePoint) WaterHeight() float32 {
+	return float32(int(p.WaterLevel)-tileZero)/tileLayer - tileWaterOffset
+}
+
+// Submerged returns true if the tile point is covered by water
+func (p *TilePoint) Submerged() bool {
+	return p.Flags&TileFlagWater != 0 && p.WaterHeight() > p.Height()
+}
+
+// Terrain information as found in the war3map.w3e file
+//
+// The terrain is a grid of Width*Height corners (one more than the number of tiles in each direction),
+// starting in the bottom-left corner of the map.
+type Terrain struct {
+	FileFormat     uint32
+	Tileset        Tileset
+	CustomTilesets bool
+	GroundTiles    []protocol.DWordString
+	CliffTiles     []protocol.DWordString
+	Width          uint32
+	Height         uint32
+	OffsetX        float32
+	OffsetY        float32
+	Points         []TilePoint
+}
+
+// At returns the tile point at column x, row y (counted from the bottom)
+func (t *Terrain) At(x int, y int) *TilePoint {
+	return &t.Points[y*int(t.Width)+x]
+}
+
+const terrainVersionRoc = 11
+const terrainVersionReforged = 12
+
+// Terrain read from war3map.w3e
+func (m *Map) Terrain() (*Terrain, error) {
+	w3e, err := m.Archive.Open("war3map.w3e")
+	if err != nil {
+		return nil, err
+	}
+	defer w3e.Close()
+
+	var b protocol.Buffer
+	if _, err := io.Copy(&b, w3e); err != nil {
+		return nil, err
+	}
+
+	if b.Size() < 37 || b.ReadLEDString() != TerrainHeader {
+		return nil, ErrBadFormat
+	}
+
+	var t = Terrain{
+		FileFormat: b.ReadUInt32(),
+	}
+
+	switch t.FileFormat {
+	case terrainVersionRoc, terrainVersionReforged:
+	default:
+		return nil, ErrBadFormat
+	}
+
+	t.Tileset = Tileset(b.ReadUInt8())
+	t.CustomTilesets = b.ReadBool32()
+
+	var numGround = int(b.ReadUInt32())
+	if b.Size() < numGround*4+4 {
+		return nil, ErrBadFormat
+	}
+	t.GroundTiles = make([]protocol.DWordString, numGround)
+	for i := range t.GroundTiles {
+		t.GroundTiles[i] = b.ReadLEDString()
+	}
+
+	var numCliff = int(b.ReadUInt32())
+	if b.Size() < numCliff*4+16 {
+		return nil, ErrBadFormat
+	}
+	t.CliffTiles = make([]protocol.DWordString, numCliff)
+	for i := range t.CliffTiles {
+		t.CliffTiles[i] = b.ReadLEDString()
+	}
+
+	t.Width = b.ReadUInt32()
+	t.Height = b.ReadUInt32()
+	t.OffsetX = b.ReadFloat32()
+	t.OffsetY = b.ReadFloat32()
+
+	var pointSize = 7
+	if t.FileFormat >= terrainVersionReforged {
+		pointSize = 8
+	}
+
+	var num = int(t.Width) * int(t.Height)
+	if t.Width > 0x10000 || t.Height > 0x10000 || b.Size() < num*pointSize {
+		return nil, ErrBadFormat
+	}
+
+	t.Points = make([]TilePoint, num)
+	for i := range t.Points {
+		var p = &t.Points[i]
+		p.GroundHeight = int16(b.ReadUInt16())
+
+		var water = b.ReadUInt16()
+		p.WaterLevel = int16(water & 0x3FFF)
+		if water&0x4000 != 0 {
+			p.Flags |= TileFlagMapEdge
+		}
+
+		if t.FileFormat >= terrainVersionReforged {
+			var tex = b.ReadUInt16()
+			p.GroundTexture = uint8(tex & 0x3F)
+			p.Flags |= TileFlags(tex>>6) & (TileFlagRamp | TileFlagBlight | TileFlagWater | TileFlagBoundary)
+		} else {
+			var tex = b.ReadUInt8()
+			p.GroundTexture = tex & 0x0F
+			p.Flags |= TileFlags(tex>>4) & (TileFlagRamp | TileFlagBlight | TileFlagWater | TileFlagBoundary)
+		}
+
+		p.Variation = b.ReadUInt8()
+
+		var cliff = b.ReadUInt8()
+		p.CliffTexture = cliff >> 4
+		p.LayerHeight = cliff & 0x0F
+	}
+
+	return &t, nil
+}
+
+var (
+	colorGrass  = color.RGBA{72, 104, 40, 255}
+	colorDirt   = color.RGBA{112, 88, 56, 255}
+	colorSand   = color.RGBA{176, 152, 104, 255}
+	colorRock   = color.RGBA{104, 100, 92, 255}
+	colorTiles  = color.RGBA{136, 132, 124, 255}
+	colorSnow   = color.RGBA{216, 224, 232, 255}
+	colorLava   = color.RGBA{168, 56, 16, 255}
+	colorBlight = color.RGBA{72, 48, 80, 255}
+	colorWater  = color.RGBA{24, 64, 128, 255}
+)
+
+// Approximate average color for ground texture types (i.e. "Lgrs", "Bdsr")
+var textureColors = []struct {
+	key string
+	col color.RGBA
+}{
+	{"snw", colorSnow}, {"ice", colorSnow}, {"sn", colorSnow},
+	{"lav", colorLava}, {"lv", colorLava},
+	{"gr", colorGrass}, {"gs", colorGrass}, {"vin", colorGrass}, {"lf", colorGrass},
+	{"ds", colorSand}, {"sa", colorSand}, {"sd", colorSand}, {"bh", colorSand},
+	{"dr", colorDirt}, {"di", colorDirt}, {"mu", colorDirt}, {"hd", colorDirt},
+	{"rk", colorRock}, {"ro", colorRock}, {"cl", colorRock}, {"dk", colorRock},
+	{"sq", colorTiles}, {"br", colorTiles}, {"ti", colorTiles}, {"rb", colorTiles}, {"st", colorTiles},
+}
+
+// Fallback colors per tileset
+var tilesetColors = map[Tileset]color.RGBA{
+	TileAshenvale:       colorGrass,
+	TileBarrens:         colorSand,
+	TileFelwood:         colorBlight,
+	TileDungeon:         colorRock,
+	TileLordaeronFall:   colorDirt,
+	TileUnderground:     colorRock,
+	TileLordaeronSummer: colorGrass,
+	TileNorthrend:       colorSnow,
+	TileVillageFall:     colorDirt,
+	TileVillage:         colorGrass,
+	TileLordaeronWinter: colorSnow,
+	TileDalaran:         colorGrass,
+	TileCityscape:       colorTiles,
+	TileSunkenRuins:     colorGrass,
+	TileIcecrown:        colorSnow,
+	TileDalaranRuins:    colorGrass,
+	TileOutland:         colorDirt,
+	TileBlackCitadel:    colorRock,
+}
+
+func textureColor(id protocol.DWordString, ts Tileset) color.RGBA {
+	var s = strings.ToLower(id.String())
+	if len(s) == 4 {
+		// First character denotes the tileset
+		if _, ok := tilesetColors[Tileset(id&0xFF)]; ok {
+			ts = Tileset(id & 0xFF)
+		}
+		for _, t := range textureColors {
+			if strings.Contains(s[1:], t.key) {
+				return t.col
+			}
+		}
+	}
+	if c, ok := tilesetColors[ts]; ok {
+		return c
+	}
+	return colorDirt
+}
+
+func shade(c color.RGBA, f float32) color.RGBA {
+	var mul = func(v uint8) uint8 {
+		var r = float32(v) * f
+		if r > 255 {
+			return 255
+		} else if r < 0 {
+			return 0
+		}
+		return uint8(r)
+	}
+	return color.RGBA{mul(c.R), mul(c.G), mul(c.B), c.A}
+}
+
+func blend(a color.RGBA, b color.RGBA, f float32) color.RGBA {
+	var mix = func(x uint8, y uint8) uint8 {
+		return uint8(float32(x)*(1-f) + float32(y)*f)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// Image renders the terrain with one pixel per tile, with height and cliff shading and water
+func (t *Terrain) Image() *image.RGBA {
+	if t.Width < 2 || t.Height < 2 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	var w = int(t.Width) - 1
+	var h = int(t.Height) - 1
+
+	var img = image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var bl, br, tl, tr = t.At(x, y), t.At(x+1, y), t.At(x, y+1), t.At(x+1, y+1)
+
+			var col = textureColor(0, t.Tileset)
+			if int(bl.GroundTexture) < len(t.GroundTiles) {
+				col = textureColor(t.GroundTiles[bl.GroundTexture], t.Tileset)
+			}
+			if bl.Flags&TileFlagBlight != 0 {
+				col = colorBlight
+			}
+
+			// Light from the top-left, higher terrain is brighter
+			var height = (bl.Height() + br.Height() + tl.Height() + tr.Height()) / 4
+			var slope = (bl.Height() + tl.Height() - br.Height() - tr.Height()) + (tl.Height() + tr.Height() - bl.Height() - br.Height())
+			var light = 1 + height*0.08 + slope*0.5
+			if light < 0.5 {
+				light = 0.5
+			} else if light > 1.5 {
+				light = 1.5
+			}
+			col = shade(col, light)
+
+			// Cliffs are drawn darker
+			if (bl.LayerHeight != br.LayerHeight || bl.LayerHeight != tl.LayerHeight || bl.LayerHeight != tr.LayerHeight) &&
+				(bl.Flags|br.Flags|tl.Flags|tr.Flags)&TileFlagRamp == 0 {
+				col = shade(blend(col, colorRock, 0.5), 0.6)
+			}
+
+			if bl.Submerged() || br.Submerged() || tl.Submerged() || tr.Submerged() {
+				var depth = (bl.WaterHeight()+br.WaterHeight()+tl.WaterHeight()+tr.WaterHeight())/4 - height
+				var f = 0.5 + depth*0.3
+				if f < 0.5 {
+					f = 0.5
+				} else if f > 0.9 {
+					f = 0.9
+				}
+				col = blend(col, colorWater, f)
+			}
+
+			if (bl.Flags|br.Flags|tl.Flags|tr.Flags)&TileFlagBoundary != 0 {
+				col = shade(col, 0.5)
+			}
+
+			img.SetRGBA(x, h-y-1, col)
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,721 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/file/slk"
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// TriggerHeader is the magic header of war3map.wtg
+var TriggerHeader = protocol.DString("WTG!")
+
+const triggerVersionRoc = 4
+const triggerVersionTft = 7
+const triggerVersion131 = 0x80000004
+
+// ECAType enum
+type ECAType uint32
+
+// ECA function types
+const (
+	ECAEvent ECAType = iota
+	ECACondition
+	ECAAction
+	ECACall
+)
+
+func (t ECAType) String() string {
+	switch t {
+	case ECAEvent:
+		return "Event"
+	case ECACondition:
+		return "Condition"
+	case ECAAction:
+		return "Action"
+	case ECACall:
+		return "Call"
+	default:
+		return fmt.Sprintf("ECAType(0x%02X)", uint32(t))
+	}
+}
+
+// ParameterType enum
+type ParameterType uint32
+
+// Parameter types
+const (
+	ParameterPreset ParameterType = iota
+	ParameterVariable
+	ParameterFunction
+	ParameterString
+	ParameterInvalid ParameterType = 0xFFFFFFFF
+)
+
+func (t ParameterType) String() string {
+	switch t {
+	case ParameterPreset:
+		return "Preset"
+	case ParameterVariable:
+		return "Variable"
+	case ParameterFunction:
+		return "Function"
+	case ParameterString:
+		return "String"
+	case ParameterInvalid:
+		return "Invalid"
+	default:
+		return fmt.Sprintf("ParameterType(0x%02X)", uint32(t))
+	}
+}
+
+// TriggerElement enum (element types in 1.31+ trigger files)
+type TriggerElement uint32
+
+// Trigger element types
+const (
+	TriggerElementMap      TriggerElement = 1
+	TriggerElementLibrary  TriggerElement = 2
+	TriggerElementCategory TriggerElement = 4
+	TriggerElementGUI      TriggerElement = 8
+	TriggerElementComment  TriggerElement = 16
+	TriggerElementScript   TriggerElement = 32
+	TriggerElementVariable TriggerElement = 64
+)
+
+func (e TriggerElement) String() string {
+	switch e {
+	case TriggerElementMap:
+		return "Map"
+	case TriggerElementLibrary:
+		return "Library"
+	case TriggerElementCategory:
+		return "Category"
+	case TriggerElementGUI:
+		return "GUI"
+	case TriggerElementComment:
+		return "Comment"
+	case TriggerElementScript:
+		return "Script"
+	case TriggerElementVariable:
+		return "Variable"
+	default:
+		return fmt.Sprintf("TriggerElement(0x%02X)", uint32(e))
+	}
+}
+
+// FunctionCall is a trigger function used as parameter value
+type FunctionCall struct {
+	Type ECAType
+	Name string
+	Args []Parameter
+}
+
+// Parameter of an ECA function
+type Parameter struct {
+	Type  ParameterType
+	Value string
+	Call  *FunctionCall
+	Index *Parameter
+}
+
+// ECA is an Event, Condition or Action function in a trigger
+type ECA struct {
+	Type       ECAType
+	Group      uint32
+	Name       string
+	Enabled    bool
+	Parameters []Parameter
+	Children   []ECA
+}
+
+// TriggerCategory structure in war3map.wtg file
+type TriggerCategory struct {
+	Type     TriggerElement
+	ID       uint32
+	Name     string
+	Comment  bool
+	Expanded bool
+	ParentID uint32
+}
+
+// TriggerVariable structure in war3map.wtg file
+type TriggerVariable struct {
+	ID           uint32
+	Name         string
+	Type         string
+	Array        bool
+	ArraySize    uint32
+	Initialized  bool
+	InitialValue string
+	CategoryID   uint32
+}
+
+// Trigger structure in war3map.wtg file, ID is only set in 1.31+ files
+type Trigger struct {
+	Type         TriggerElement
+	ID           uint32
+	Name         string
+	Description  string
+	Comment      bool
+	Enabled      bool
+	CustomText   bool
+	InitiallyOff bool
+	RunOnMapInit bool
+	CategoryID   uint32
+	ECAs         []ECA
+}
+
+// Triggers as found in the war3map.wtg file
+type Triggers struct {
+	FileFormat uint32
+	SubVersion uint32
+	Categories []TriggerCategory
+	Variables  []TriggerVariable
+	Triggers   []Trigger
+}
+
+// TriggerData contains the number of arguments for each trigger function (from UI\TriggerData.txt), keys are stored in lower case
+type TriggerData map[string]int
+
+var triggerDataSections = map[string]int{
+	"TriggerEvents":     1,
+	"TriggerConditions": 1,
+	"TriggerActions":    1,
+	"TriggerCalls":      3,
+}
+
+// LoadTriggerData reads the trigger function definitions from UI\TriggerData.txt in stor
+func LoadTriggerData(stor *fs.Storage) (TriggerData, error) {
+	f, err := stor.Open("UI\\TriggerData.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := slk.DecodeProfile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = TriggerData{}
+	for sec, skip := range triggerDataSections {
+		for name, v := range p[sec] {
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+
+			var args = strings.Split(v, ",")
+			if len(args) < skip {
+				continue
+			}
+
+			var n = 0
+			for _, a := range args[skip:] {
+				if a = strings.TrimSpace(a); a != "" && a != "nothing" {
+					n++
+				}
+			}
+			res[name] = n
+		}
+	}
+
+	return res, nil
+}
+
+type wtgReader struct {
+	b       *protocol.Buffer
+	data    TriggerData
+	version uint32
+}
+
+func (r *wtgReader) args(name string) ([]Parameter, error) {
+	n, ok := r.data[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrUnknownFunction
+	}
+
+	var res = make([]Parameter, n)
+	for i := range res {
+		if err := r.parameter(&res[i]); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (r *wtgReader) parameter(p *Parameter) error {
+	var err error
+
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+	p.Type = ParameterType(r.b.ReadUInt32())
+	if p.Value, err = r.b.ReadCString(); err != nil {
+		return err
+	}
+
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+	var call = r.b.ReadBool32()
+	if call {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		p.Call = &FunctionCall{Type: ECAType(r.b.ReadUInt32())}
+		if p.Call.Name, err = r.b.ReadCString(); err != nil {
+			return err
+		}
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		if r.b.ReadBool32() {
+			if p.Call.Args, err = r.args(p.Call.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	var array = false
+	if r.version == triggerVersionRoc {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		if p.Type == ParameterFunction {
+			r.b.ReadUInt32()
+		} else {
+			array = r.b.ReadBool32()
+		}
+	} else {
+		if call {
+			if r.b.Size() < 4 {
+				return ErrBadFormat
+			}
+			r.b.ReadUInt32()
+		}
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		array = r.b.ReadBool32()
+	}
+
+	if array {
+		p.Index = &Parameter{}
+		return r.parameter(p.Index)
+	}
+
+	return nil
+}
+
+func (r *wtgReader) eca(e *ECA, child bool) error {
+	var err error
+
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+	e.Type = ECAType(r.b.ReadUInt32())
+	if child {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		e.Group = r.b.ReadUInt32()
+	}
+
+	if e.Name, err = r.b.ReadCString(); err != nil {
+		return err
+	}
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+	e.Enabled = r.b.ReadBool32()
+
+	if e.Parameters, err = r.args(e.Name); err != nil {
+		return err
+	}
+
+	if r.version == triggerVersionTft {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		e.Children = make([]ECA, r.b.ReadUInt32())
+		for i := range e.Children {
+			if err := r.eca(&e.Children[i], true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *wtgReader) trigger(t *Trigger, reforged bool) error {
+	var err error
+	if t.Name, err = r.b.ReadCString(); err != nil {
+		return err
+	}
+	if t.Description, err = r.b.ReadCString(); err != nil {
+		return err
+	}
+
+	if r.version == triggerVersionTft {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		t.Comment = r.b.ReadBool32()
+	}
+	if reforged {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		t.ID = r.b.ReadUInt32()
+	}
+
+	if r.b.Size() < 24 {
+		return ErrBadFormat
+	}
+	t.Enabled = r.b.ReadBool32()
+	t.CustomText = r.b.ReadBool32()
+	t.InitiallyOff = r.b.ReadBool32()
+	t.RunOnMapInit = r.b.ReadBool32()
+	t.CategoryID = r.b.ReadUInt32()
+
+	switch {
+	case t.Comment:
+		t.Type = TriggerElementComment
+	case t.CustomText:
+		t.Type = TriggerElementScript
+	default:
+		t.Type = TriggerElementGUI
+	}
+
+	t.ECAs = make([]ECA, r.b.ReadUInt32())
+	for i := range t.ECAs {
+		if err := r.eca(&t.ECAs[i], false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *wtgReader) variable(v *TriggerVariable, reforged bool) error {
+	var err error
+	if v.Name, err = r.b.ReadCString(); err != nil {
+		return err
+	}
+	if v.Type, err = r.b.ReadCString(); err != nil {
+		return err
+	}
+
+	if r.b.Size() < 8 {
+		return ErrBadFormat
+	}
+	r.b.ReadUInt32()
+	v.Array = r.b.ReadBool32()
+
+	if r.version == triggerVersionTft {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		v.ArraySize = r.b.ReadUInt32()
+	}
+
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+	v.Initialized = r.b.ReadBool32()
+	if v.InitialValue, err = r.b.ReadCString(); err != nil {
+		return err
+	}
+
+	if reforged {
+		if r.b.Size() < 8 {
+			return ErrBadFormat
+		}
+		v.ID = r.b.ReadUInt32()
+		v.CategoryID = r.b.ReadUInt32()
+	}
+
+	return nil
+}
+
+func (r *wtgReader) classic(res *Triggers) error {
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+
+	res.Categories = make([]TriggerCategory, r.b.ReadUInt32())
+	for i := range res.Categories {
+		var c = &res.Categories[i]
+		c.Type = TriggerElementCategory
+
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+		c.ID = r.b.ReadUInt32()
+
+		var err error
+		if c.Name, err = r.b.ReadCString(); err != nil {
+			return err
+		}
+
+		if r.version == triggerVersionTft {
+			if r.b.Size() < 4 {
+				return ErrBadFormat
+			}
+			c.Comment = r.b.ReadBool32()
+		}
+	}
+
+	// Trigger data version
+	if r.b.Size() < 8 {
+		return ErrBadFormat
+	}
+	r.b.ReadUInt32()
+
+	res.Variables = make([]TriggerVariable, r.b.ReadUInt32())
+	for i := range res.Variables {
+		if err := r.variable(&res.Variables[i], false); err != nil {
+			return err
+		}
+	}
+
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+
+	res.Triggers = make([]Trigger, r.b.ReadUInt32())
+	for i := range res.Triggers {
+		if err := r.trigger(&res.Triggers[i], false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *wtgReader) reforged(res *Triggers) error {
+	// Element counts followed by a list of deleted element IDs (for each element type)
+	for i := 0; i < 7; i++ {
+		if r.b.Size() < 8 {
+			return ErrBadFormat
+		}
+		r.b.ReadUInt32()
+
+		var num = int(r.b.ReadUInt32())
+		if r.b.Size() < num*4 {
+			return ErrBadFormat
+		}
+		r.b.Skip(num * 4)
+	}
+
+	// Unknown (2x) and trigger data version
+	if r.b.Size() < 16 {
+		return ErrBadFormat
+	}
+	r.b.Skip(12)
+
+	res.Variables = make([]TriggerVariable, r.b.ReadUInt32())
+	for i := range res.Variables {
+		if err := r.variable(&res.Variables[i], true); err != nil {
+			return err
+		}
+	}
+
+	if r.b.Size() < 4 {
+		return ErrBadFormat
+	}
+
+	res.Categories = []TriggerCategory{}
+	res.Triggers = []Trigger{}
+
+	var num = r.b.ReadUInt32()
+	for i := uint32(0); i < num; i++ {
+		if r.b.Size() < 4 {
+			return ErrBadFormat
+		}
+
+		var err error
+		var typ = TriggerElement(r.b.ReadUInt32())
+		switch typ {
+		case TriggerElementMap, TriggerElementLibrary, TriggerElementCategory:
+			if r.b.Size() < 4 {
+				return ErrBadFormat
+			}
+
+			var c = TriggerCategory{Type: typ, ID: r.b.ReadUInt32()}
+			if c.Name, err = r.b.ReadCString(); err != nil {
+				return err
+			}
+			if r.version == triggerVersionTft {
+				if r.b.Size() < 4 {
+					return ErrBadFormat
+				}
+				c.Comment = r.b.ReadBool32()
+			}
+			if r.b.Size() < 8 {
+				return ErrBadFormat
+			}
+			c.Expanded = r.b.ReadBool32()
+			c.ParentID = r.b.ReadUInt32()
+
+			res.Categories = append(res.Categories, c)
+		case TriggerElementGUI, TriggerElementComment, TriggerElementScript:
+			var t Trigger
+			if err := r.trigger(&t, true); err != nil {
+				return err
+			}
+			t.Type = typ
+			res.Triggers = append(res.Triggers, t)
+		case TriggerElementVariable:
+			// Variables are listed again as elements (to define their order), but only contain ID/name/parent
+			if r.b.Size() < 4 {
+				return ErrBadFormat
+			}
+			r.b.ReadUInt32()
+			if _, err := r.b.ReadCString(); err != nil {
+				return err
+			}
+			if r.b.Size() < 4 {
+				return ErrBadFormat
+			}
+			r.b.ReadUInt32()
+		default:
+			return ErrBadFormat
+		}
+	}
+
+	return nil
+}
+
+// Deserialize decodes the binary data generated by the world editor, data is used to look up the number of parameters of trigger functions
+func (t *Triggers) Deserialize(buf *protocol.Buffer, data TriggerData) error {
+	if buf.Size() < 8 || buf.ReadLEDString() != TriggerHeader {
+		return ErrBadFormat
+	}
+
+	*t = Triggers{FileFormat: buf.ReadUInt32()}
+	var r = wtgReader{b: buf, data: data, version: t.FileFormat}
+
+	switch t.FileFormat {
+	case triggerVersionRoc, triggerVersionTft:
+		return r.classic(t)
+	case triggerVersion131:
+		if buf.Size() < 4 {
+			return ErrBadFormat
+		}
+		t.SubVersion = buf.ReadUInt32()
+		r.version = t.SubVersion
+		if r.version != triggerVersionRoc && r.version != triggerVersionTft {
+			return ErrBadFormat
+		}
+		return r.reforged(t)
+	default:
+		return ErrBadFormat
+	}
+}
+
+// Triggers read from war3map.wtg, data is used to look up the number of parameters of trigger functions
+func (m *Map) Triggers(data TriggerData) (*Triggers, error) {
+	b, err := m.readFile("war3map.wtg")
+	if err != nil {
+		return nil, err
+	}
+
+	var res Triggers
+	if err := res.Deserialize(b, data); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// CustomScripts as found in the war3map.wct file
+type CustomScripts struct {
+	FileFormat uint32
+	SubVersion uint32
+
+	// Map header script (TFT+)
+	Comment string
+	Header  string
+
+	// Custom text of each trigger, in the same order as Triggers.Triggers (empty for GUI triggers)
+	Scripts []string
+}
+
+func readSizedString(b *protocol.Buffer) (string, error) {
+	if b.Size() < 4 {
+		return "", ErrBadFormat
+	}
+	var size = int(b.ReadUInt32())
+	if b.Size() < size {
+		return "", ErrBadFormat
+	}
+	return strings.TrimRight(string(b.ReadBlob(size)), "\x00"), nil
+}
+
+// Deserialize decodes the binary data generated by the world editor
+func (s *CustomScripts) Deserialize(buf *protocol.Buffer) error {
+	if buf.Size() < 4 {
+		return ErrBadFormat
+	}
+
+	*s = CustomScripts{FileFormat: buf.ReadUInt32()}
+	var version = s.FileFormat
+	if version == triggerVersion131 {
+		if buf.Size() < 4 {
+			return ErrBadFormat
+		}
+		s.SubVersion = buf.ReadUInt32()
+		version = s.SubVersion
+	}
+
+	var err error
+	switch version {
+	case 0:
+	case 1:
+		if s.Comment, err = buf.ReadCString(); err != nil {
+			return err
+		}
+		if s.Header, err = readSizedString(buf); err != nil {
+			return err
+		}
+	default:
+		return ErrBadFormat
+	}
+
+	if buf.Size() < 4 {
+		return ErrBadFormat
+	}
+
+	s.Scripts = make([]string, buf.ReadUInt32())
+	for i := range s.Scripts {
+		if s.Scripts[i], err = readSizedString(buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CustomScripts read from war3map.wct
+func (m *Map) CustomScripts() (*CustomScripts, error) {
+	b, err := m.readFile("war3map.wct")
+	if err != nil {
+		return nil, err
+	}
+
+	var res CustomScripts
+	if err := res.Deserialize(b); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
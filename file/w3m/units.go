@@ -0,0 +1,305 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3m
+
+import (
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Special unit IDs
+var (
+	UnitGoldMine      = protocol.DString("ngol")
+	UnitStartLocation = protocol.DString("sloc")
+	UnitRandom        = protocol.DString("uDNR")
+	ItemRandom        = protocol.DString("iDNR")
+)
+
+// RandomType enum
+type RandomType uint32
+
+// Random unit/item types
+const (
+	RandomAny RandomType = iota
+	RandomGroup
+	RandomTable
+)
+
+// InventoryItem structure in war3mapUnits.doo file
+type InventoryItem struct {
+	Slot   uint32
+	ItemID protocol.DWordString
+}
+
+// AbilityModification structure in war3mapUnits.doo file
+type AbilityModification struct {
+	AbilityID protocol.DWordString
+	Autocast  bool
+	Level     uint32
+}
+
+// RandomChoice in a custom random unit table
+type RandomChoice struct {
+	ID     protocol.DWordString
+	Chance uint32
+}
+
+// Random unit/item definition (for uDNR units and iDNR items)
+type Random struct {
+	Type RandomType
+
+	// RandomAny
+	Level     int32
+	ItemClass uint8
+
+	// RandomGroup
+	Group    uint32
+	Position uint32
+
+	// RandomTable
+	Table []RandomChoice
+}
+
+// Unit (or item) structure in war3mapUnits.doo file
+type Unit struct {
+	Placement
+	Flags             DoodadFlags
+	Owner             uint32
+	HitPoints         int32
+	ManaPoints        int32
+	ItemTable         int32
+	ItemSets          []ItemSet
+	Gold              uint32
+	TargetAcquisition float32
+	HeroLevel         uint32
+	Strength          uint32
+	Agility           uint32
+	Intelligence      uint32
+	Inventory         []InventoryItem
+	Abilities         []AbilityModification
+	Random            Random
+	CustomColor       int32
+	Waygate           int32
+	CreationNumber    uint32
+}
+
+// Units as found in the war3mapUnits.doo file
+type Units struct {
+	FileFormat uint32
+	SubVersion uint32
+	Units      []Unit
+}
+
+// Units read from war3mapUnits.doo
+func (m *Map) Units() (*Units, error) {
+	skin, err := m.hasSkins()
+	if err != nil {
+		return nil, err
+	}
+
+	b, version, subversion, err := m.readDoo("war3mapUnits.doo")
+	if err != nil {
+		return nil, err
+	}
+
+	var res = Units{
+		FileFormat: version,
+		SubVersion: subversion,
+	}
+
+	if b.Size() < 4 {
+		return nil, ErrBadFormat
+	}
+
+	res.Units = make([]Unit, b.ReadUInt32())
+	for i := range res.Units {
+		if err := readUnit(b, &res.Units[i], skin, subversion >= unitSubVersionTft); err != nil {
+			return nil, err
+		}
+	}
+
+	return &res, nil
+}
+
+func readUnit(b *protocol.Buffer, u *Unit, skin bool, tft bool) error {
+	if err := readPlacement(b, &u.Placement, skin); err != nil {
+		return err
+	}
+
+	if b.Size() < 15 {
+		return ErrBadFormat
+	}
+
+	u.Flags = DoodadFlags(b.ReadUInt8())
+	u.Owner = b.ReadUInt32()
+	b.ReadUInt8()
+	b.ReadUInt8()
+	u.HitPoints = int32(b.ReadUInt32())
+	u.ManaPoints = int32(b.ReadUInt32())
+	u.ItemTable = -1
+
+	if tft {
+		if b.Size() < 4 {
+			return ErrBadFormat
+		}
+		u.ItemTable = int32(b.ReadUInt32())
+	}
+
+	var err error
+	if u.ItemSets, err = readItemSets(b); err != nil {
+		return err
+	}
+
+	if b.Size() < 12 {
+		return ErrBadFormat
+	}
+
+	u.Gold = b.ReadUInt32()
+	u.TargetAcquisition = b.ReadFloat32()
+	u.HeroLevel = b.ReadUInt32()
+
+	if tft {
+		if b.Size() < 12 {
+			return ErrBadFormat
+		}
+		u.Strength = b.ReadUInt32()
+		u.Agility = b.ReadUInt32()
+		u.Intelligence = b.ReadUInt32()
+	}
+
+	if b.Size() < 4 {
+		return ErrBadFormat
+	}
+
+	var numItems = int(b.ReadUInt32())
+	if b.Size() < numItems*8+4 {
+		return ErrBadFormat
+	}
+
+	u.Inventory = make([]InventoryItem, numItems)
+	for i := range u.Inventory {
+		u.Inventory[i].Slot = b.ReadUInt32()
+		u.Inventory[i].ItemID = b.ReadLEDString()
+	}
+
+	var numAbilities = int(b.ReadUInt32())
+	if b.Size() < numAbilities*12+4 {
+		return ErrBadFormat
+	}
+
+	u.Abilities = make([]AbilityModification, numAbilities)
+	for i := range u.Abilities {
+		u.Abilities[i].AbilityID = b.ReadLEDString()
+		u.Abilities[i].Autocast = b.ReadBool32()
+		u.Abilities[i].Level = b.ReadUInt32()
+	}
+
+	u.Random.Type = RandomType(b.ReadUInt32())
+	switch u.Random.Type {
+	case RandomAny:
+		if b.Size() < 4 {
+			return ErrBadFormat
+		}
+		var lvl = b.ReadUInt32()
+		u.Random.Level = int32(lvl<<8) >> 8
+		u.Random.ItemClass = uint8(lvl >> 24)
+	case RandomGroup:
+		if b.Size() < 8 {
+			return ErrBadFormat
+		}
+		u.Random.Group = b.ReadUInt32()
+		u.Random.Position = b.ReadUInt32()
+	case RandomTable:
+		if b.Size() < 4 {
+			return ErrBadFormat
+		}
+		var num = int(b.ReadUInt32())
+		if b.Size() < num*8 {
+			return ErrBadFormat
+		}
+		u.Random.Table = make([]RandomChoice, num)
+		for i := range u.Random.Table {
+			u.Random.Table[i].ID = b.ReadLEDString()
+			u.Random.Table[i].Chance = b.ReadUInt32()
+		}
+	default:
+		return ErrBadFormat
+	}
+
+	if b.Size() < 12 {
+		return ErrBadFormat
+	}
+
+	u.CustomColor = int32(b.ReadUInt32())
+	u.Waygate = int32(b.ReadUInt32())
+	u.CreationNumber = b.ReadUInt32()
+
+	return nil
+}
+
+// NeutralHostile returns the player number of neutral hostile units (creeps)
+func (i *Info) NeutralHostile() uint32 {
+	if i.FileFormat >= editorVersion131 {
+		return 24
+	}
+	return 12
+}
+
+// Count the number of units with the given type ID
+func (u *Units) Count(typeID protocol.DWordString) int {
+	var n = 0
+	for i := range u.Units {
+		if u.Units[i].TypeID == typeID {
+			n++
+		}
+	}
+	return n
+}
+
+// CampDistance is the maximum distance between two units in the same creep camp
+const CampDistance = 512
+
+// CreepCamps groups units owned by owner (see Info.NeutralHostile) that are within CampDistance of each other
+func (u *Units) CreepCamps(owner uint32) [][]*Unit {
+	var creeps []*Unit
+	for i := range u.Units {
+		if u.Units[i].Owner == owner {
+			creeps = append(creeps, &u.Units[i])
+		}
+	}
+
+	var camp = make([]int, len(creeps))
+	for i := range camp {
+		camp[i] = -1
+	}
+
+	var res [][]*Unit
+	for i := range creeps {
+		if camp[i] >= 0 {
+			continue
+		}
+
+		camp[i] = len(res)
+		var c = []*Unit{creeps[i]}
+
+		// Flood fill all creeps within range of any creep in the camp
+		for q := 0; q < len(c); q++ {
+			for j := range creeps {
+				if camp[j] >= 0 {
+					continue
+				}
+				var dx = c[q].X - creeps[j].X
+				var dy = c[q].Y - creeps[j].Y
+				if dx*dx+dy*dy <= CampDistance*CampDistance {
+					camp[j] = camp[i]
+					c = append(c, creeps[j])
+				}
+			}
+		}
+
+		res = append(res, c)
+	}
+
+	return res
+}
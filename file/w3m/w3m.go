@@ -11,8 +11,10 @@ import (
 
 // Map refers to an w3m/w3x map (MPQ archive)
 type Map struct {
-	Archive *mpq.Archive
-	ts      map[int]string
+	Archive  *mpq.Archive
+	FileName string
+	ts       map[int]string
+	tg       *Grid
 }
 
 // Open a w3m/w3x map file
@@ -21,7 +23,7 @@ func Open(fileName string) (*Map, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Map{Archive: archive}, nil
+	return &Map{Archive: archive, FileName: fileName}, nil
 }
 
 // Close a w3m/w3x map file
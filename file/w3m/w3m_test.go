@@ -10,11 +10,21 @@ import (
 	"encoding/base64"
 	"fmt"
 	"image"
+	"image/color"
 	"image/png"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/file/jass"
+	"github.com/nielsAD/gowarcraft3/file/w3g"
 	"github.com/nielsAD/gowarcraft3/file/w3m"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
 func Example() {
@@ -69,7 +79,7 @@ func TestFiles(t *testing.T) {
 				FileFormat:       18,
 				SaveCount:        2,
 				EditorVersion:    6059,
-				Name:             "",
+				Name:             "Smallest Map",
 				Author:           "DragonX",
 				Description:      "Smallest map in W3",
 				SuggestedPlayers: "Any",
@@ -131,6 +141,7 @@ func TestFiles(t *testing.T) {
 						Type:      w3m.PlayerHuman,
 						Race:      w3m.RaceHuman,
 						Flags:     w3m.PlayerFlagFixedPos,
+						Name:      "Player 1",
 						StartPosX: -1664,
 						StartPosY: 1152,
 					},
@@ -206,3 +217,1189 @@ func TestFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestMapCheck(t *testing.T) {
+	m, err := w3m.Open("./test_tft.w3x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	fh, err := m.FileChecksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fh.String() != "15148|0x7B626511|U9VogKXMd+rhZaLG/LnOmhSlzqc" {
+		t.Fatal("File checksum mismatch", fh)
+	}
+
+	var path = "Maps\\Download\\test_tft.w3x"
+
+	legacy, err := m.MapCheck(path, nil, w3gs.Encoding{GameVersion: 10030})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := m.Checksum(nil)
+	if legacy.FilePath != path || legacy.FileSize != fh.Size || legacy.FileCRC != fh.CRC32 || legacy.MapXoro != hash.Xoro || legacy.MapSha1 != hash.Sha1 {
+		t.Fatal("MapCheck (1.30) mismatch", legacy)
+	}
+
+	modern, err := m.MapCheck(path, nil, w3gs.Encoding{GameVersion: 10032})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modern.FileSize != fh.Size || modern.FileCRC != fh.CRC32 || modern.MapXoro != w3m.NoXoro || modern.MapSha1 != fh.Sha1 {
+		t.Fatal("MapCheck (1.32) mismatch", modern)
+	}
+
+	settings, err := m.GameSettings(path, nil, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = w3gs.GameSettings{
+		GameSettingFlags: w3gs.SettingSpeedFast | w3gs.SettingTerrainDefault,
+		MapWidth:         30,
+		MapHeight:        26,
+		MapXoro:          w3m.NoXoro,
+		MapPath:          path,
+		MapSha1:          fh.Sha1,
+	}
+	if *settings != expected {
+		t.Fatal("GameSettings mismatch", settings)
+	}
+	if settings.MapXoro != modern.MapXoro || settings.MapSha1 != modern.MapSha1 {
+		t.Fatal("MapCheck and GameSettings hash mismatch", modern, settings)
+	}
+
+	// Game info recorded by a 1.32 client
+	rep, err := w3g.Open("../w3g/test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.GameSettings.MapXoro != w3m.NoXoro {
+		t.Fatalf("Expected NoXoro in 1.32 replay, got 0x%08X", rep.GameSettings.MapXoro)
+	}
+}
+
+func TestSlotInfo(t *testing.T) {
+	var players = []w3m.Player{
+		w3m.Player{ID: 0, Type: w3m.PlayerHuman, Race: w3m.RaceOrc},
+		w3m.Player{ID: 1, Type: w3m.PlayerComputer, Race: w3m.RaceNightElf},
+		w3m.Player{ID: 2, Type: w3m.PlayerNeutral},
+		w3m.Player{ID: 3, Type: w3m.PlayerHuman, Race: w3m.RaceSelectable},
+	}
+	var forces = []w3m.Force{
+		w3m.Force{PlayerSet: 0x1},
+		w3m.Force{PlayerSet: 0xA},
+	}
+
+	var open = func(team uint8, color uint8, race w3gs.RacePref) w3gs.SlotData {
+		return w3gs.SlotData{DownloadStatus: 255, SlotStatus: w3gs.SlotOpen, Team: team, Color: color, Race: race, ComputerType: w3gs.ComputerNormal, Handicap: 100}
+	}
+	var comp = func(team uint8, color uint8, race w3gs.RacePref) w3gs.SlotData {
+		return w3gs.SlotData{DownloadStatus: 100, SlotStatus: w3gs.SlotOccupied, Computer: true, Team: team, Color: color, Race: race, ComputerType: w3gs.ComputerNormal, Handicap: 100}
+	}
+	var random = w3gs.RaceRandom | w3gs.RaceSelectable
+
+	var infos = []struct {
+		flags    w3m.MapFlags
+		expected w3gs.SlotInfo
+	}{
+		{
+			w3m.MapFlagMelee,
+			w3gs.SlotInfo{
+				SlotLayout: w3gs.LayoutMelee,
+				NumPlayers: 3,
+				Slots:      []w3gs.SlotData{open(0, 0, random), open(1, 1, random), open(2, 3, random)},
+			},
+		},
+		{
+			w3m.MapFlagCustomForces,
+			w3gs.SlotInfo{
+				SlotLayout: w3gs.LayoutCustomForces,
+				NumPlayers: 3,
+				Slots:      []w3gs.SlotData{open(0, 0, random), comp(1, 1, random), open(1, 3, random)},
+			},
+		},
+		{
+			w3m.MapFlagCustomForces | w3m.MapFlagFixedPlayerSettings,
+			w3gs.SlotInfo{
+				SlotLayout: w3gs.LayoutCustomForces | w3gs.LayoutFixedPlayerSettings,
+				NumPlayers: 3,
+				Slots:      []w3gs.SlotData{open(0, 0, w3gs.RaceOrc), comp(1, 1, w3gs.RaceNightElf), open(1, 3, random)},
+			},
+		},
+	}
+
+	for _, i := range infos {
+		var info = w3m.Info{Flags: i.flags, Players: players, Forces: forces}
+		if s := info.SlotInfo(); !reflect.DeepEqual(s, &i.expected) {
+			t.Fatalf("SlotInfo mismatch for %v: %+v\n", i.flags, s)
+		}
+	}
+}
+
+func TestTerrain(t *testing.T) {
+	m, err := w3m.Open("./test_tft.w3x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ter, err := m.Terrain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ter.FileFormat != 11 || ter.Tileset != w3m.TileLordaeronSummer || ter.Width != 33 || ter.Height != 33 || ter.OffsetX != -2048 || ter.OffsetY != -2048 {
+		t.Fatalf("Terrain header mismatch %+v\n", ter)
+	}
+	if len(ter.GroundTiles) != 6 || ter.GroundTiles[4].String() != "Lgrs" || len(ter.CliffTiles) != 2 || ter.CliffTiles[0].String() != "CLdi" {
+		t.Fatal("Terrain tiles mismatch", ter.GroundTiles, ter.CliffTiles)
+	}
+	if len(ter.Points) != 33*33 {
+		t.Fatal("Expected 33*33 tile points, got", len(ter.Points))
+	}
+
+	var p = ter.At(0, 0)
+	if p.Flags != w3m.TileFlagMapEdge || p.GroundTexture != 0 || p.LayerHeight != 2 || p.Height() != 0 || p.Submerged() {
+		t.Fatalf("TilePoint mismatch %+v\n", p)
+	}
+
+	if img := ter.Image(); img.Bounds() != image.Rect(0, 0, 32, 32) {
+		t.Fatal("Terrain image bounds mismatch", img.Bounds())
+	}
+
+	img, err := m.RenderMinimap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 256, 256) {
+		t.Fatal("Minimap bounds mismatch", img.Bounds())
+	}
+}
+
+func TestSubmerged(t *testing.T) {
+	var tiles = []struct {
+		point     w3m.TilePoint
+		submerged bool
+	}{
+		{w3m.TilePoint{GroundHeight: 0x2000, WaterLevel: 0x2000 + 358, Flags: w3m.TileFlagWater, LayerHeight: 2}, false},
+		{w3m.TilePoint{GroundHeight: 0x2000, WaterLevel: 0x2000 + 359, Flags: w3m.TileFlagWater, LayerHeight: 2}, true},
+		{w3m.TilePoint{GroundHeight: 0x2000, WaterLevel: 0x2000 + 359, LayerHeight: 2}, false},
+		{w3m.TilePoint{GroundHeight: 0x2000, WaterLevel: 0x2000 + 512 + 358, Flags: w3m.TileFlagWater, LayerHeight: 3}, false},
+		{w3m.TilePoint{GroundHeight: 0x2000, WaterLevel: 0x2000 + 512 + 359, Flags: w3m.TileFlagWater, LayerHeight: 3}, true},
+	}
+
+	for _, tile := range tiles {
+		if s := tile.point.Submerged(); s != tile.submerged {
+			t.Fatalf("Submerged mismatch for %+v (water %v, ground %v)\n", tile.point, tile.point.WaterHeight(), tile.point.Height())
+		}
+	}
+
+	var p = w3m.TilePoint{WaterLevel: 0x2000}
+	if h := p.WaterHeight(); h != -0.7 {
+		t.Fatal("Expected water height -0.7, got", h)
+	}
+}
+
+func TestPathing(t *testing.T) {
+	m, err := w3m.Open("./test_tft.w3x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	p, err := m.Pathing()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Width != 128 || p.Height != 128 || p.OffsetX != -2048 || p.OffsetY != -2048 || len(p.Cells) != 128*128 {
+		t.Fatalf("Pathing grid mismatch %+v\n", p.Grid)
+	}
+
+	var boundary = w3m.PathingNoWalk | w3m.PathingNoFly | w3m.PathingNoBuild | w3m.PathingNoWater | w3m.PathingUnknown
+	if f := p.At(0, 0); f != boundary {
+		t.Fatal("Expected boundary at (0, 0), got", f)
+	}
+	if f, ok := p.AtWorld(-2048, -2048); !ok || f != boundary {
+		t.Fatal("Expected boundary at world (-2048, -2048), got", f, ok)
+	}
+	if f, ok := p.AtWorld(0, 0); !ok || f != w3m.PathingNoWater {
+		t.Fatal("Expected walkable at world (0, 0), got", f, ok)
+	}
+	if _, ok := p.AtWorld(2048, 0); ok {
+		t.Fatal("Expected out of bounds")
+	}
+	if x, y := p.World(64, 64); x != 16 || y != 16 {
+		t.Fatal("World coordinates mismatch", x, y)
+	}
+
+	var img = p.Image()
+	if img.Bounds() != image.Rect(0, 0, 128, 128) {
+		t.Fatal("Pathing image bounds mismatch", img.Bounds())
+	}
+	if c := img.RGBAAt(0, 127); c != (color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Fatal("Pathing image color mismatch", c)
+	}
+	if a := p.Mask(w3m.PathingNoWalk).AlphaAt(64, 64); a.A != 0 {
+		t.Fatal("Pathing mask mismatch", a)
+	}
+
+	s, err := m.Shadow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Grid != p.Grid || len(s.Cells) != 128*128 {
+		t.Fatalf("Shadow grid mismatch %+v\n", s.Grid)
+	}
+	if sh, ok := s.AtWorld(0, 0); !ok || sh {
+		t.Fatal("Expected no shadow at world (0, 0)")
+	}
+}
+
+func TestDoodads(t *testing.T) {
+	var files = []struct {
+		file      string
+		version   uint32
+		doodads   int
+		units     int
+		goldMines int
+		startLocs int
+		camps     int
+		last      w3m.Unit
+	}{
+		{
+			"test_roc.w3m", 7, 84, 14, 4, 4, 5,
+			w3m.Unit{
+				Placement: w3m.Placement{
+					TypeID: protocol.DString("nogr"),
+					X:      -1178.0961,
+					Y:      -1182.1201,
+					Angle:  0.93863314,
+					ScaleX: 1,
+					ScaleY: 1,
+					ScaleZ: 1,
+					SkinID: protocol.DString("nogr"),
+				},
+				Flags:             w3m.DoodadSolid,
+				Owner:             12,
+				HitPoints:         -1,
+				ManaPoints:        -1,
+				ItemTable:         -1,
+				ItemSets:          []w3m.ItemSet{},
+				Gold:              12500,
+				TargetAcquisition: -1,
+				HeroLevel:         1,
+				Inventory:         []w3m.InventoryItem{},
+				Abilities:         []w3m.AbilityModification{},
+				Random:            w3m.Random{Level: 1},
+				CustomColor:       -1,
+				Waygate:           -1,
+				CreationNumber:    13,
+			},
+		},
+		{
+			"test_tft.w3x", 8, 120, 126, 2, 2, 0,
+			w3m.Unit{
+				Placement: w3m.Placement{
+					TypeID: protocol.DString("hpea"),
+					X:      -1350.3624,
+					Y:      1003.27966,
+					Angle:  4.4918246,
+					ScaleX: 1,
+					ScaleY: 1,
+					ScaleZ: 1,
+					SkinID: protocol.DString("hpea"),
+				},
+				Flags:             w3m.DoodadSolid,
+				HitPoints:         -1,
+				ManaPoints:        -1,
+				ItemTable:         -1,
+				ItemSets:          []w3m.ItemSet{},
+				Gold:              12500,
+				TargetAcquisition: -1,
+				HeroLevel:         1,
+				Inventory:         []w3m.InventoryItem{},
+				Abilities:         []w3m.AbilityModification{},
+				Random:            w3m.Random{Level: 1},
+				CustomColor:       -1,
+				Waygate:           -1,
+				CreationNumber:    125,
+			},
+		},
+	}
+
+	for _, f := range files {
+		m, err := w3m.Open("./" + f.file)
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+
+		info, err := m.Info()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+
+		doo, err := m.Doodads()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if doo.FileFormat != f.version || len(doo.Doodads) != f.doodads {
+			t.Fatalf("%v doodads mismatch %v %v\n", f.file, doo.FileFormat, len(doo.Doodads))
+		}
+		for _, d := range doo.Doodads {
+			if d.Flags > 3 || d.Flags.Solid() != (d.Flags >= w3m.DoodadSolid) || d.Flags.Visible() != (d.Flags != w3m.DoodadInvisible) {
+				t.Fatalf("%v unexpected doodad flags %v\n", f.file, d.Flags)
+			}
+		}
+
+		units, err := m.Units()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if units.FileFormat != f.version || len(units.Units) != f.units {
+			t.Fatalf("%v units mismatch %v %v\n", f.file, units.FileFormat, len(units.Units))
+		}
+		if !reflect.DeepEqual(units.Units[len(units.Units)-1], f.last) {
+			t.Fatalf("%v unit mismatch %+v\n", f.file, units.Units[len(units.Units)-1])
+		}
+		if last := units.Units[len(units.Units)-1]; !last.Flags.Visible() || !last.Flags.Solid() {
+			t.Fatalf("%v expected visible and solid unit, got %v\n", f.file, last.Flags)
+		}
+
+		if n := units.Count(w3m.UnitGoldMine); n != f.goldMines {
+			t.Fatalf("%v expected %v gold mines, got %v\n", f.file, f.goldMines, n)
+		}
+		if n := units.Count(w3m.UnitStartLocation); n != f.startLocs {
+			t.Fatalf("%v expected %v start locations, got %v\n", f.file, f.startLocs, n)
+		}
+		if n := len(units.CreepCamps(info.NeutralHostile())); n != f.camps {
+			t.Fatalf("%v expected %v creep camps, got %v\n", f.file, f.camps, n)
+		}
+
+		m.Close()
+	}
+}
+
+func TestObjectData(t *testing.T) {
+	var files = []w3m.ObjectData{
+		w3m.ObjectData{
+			Type:       w3m.ObjectUnits,
+			FileFormat: 2,
+			Original: []w3m.Object{
+				w3m.Object{
+					OriginalID: protocol.DString("hfoo"),
+					Sets: []w3m.ObjectSet{{Modifications: []w3m.Modification{
+						{ID: protocol.DString("uhpm"), Type: w3m.ValueInt, Int: 500},
+						{ID: protocol.DString("unam"), Type: w3m.ValueString, String: "Knight"},
+					}}},
+				},
+			},
+			Custom: []w3m.Object{
+				w3m.Object{
+					OriginalID: protocol.DString("hfoo"),
+					CustomID:   protocol.DString("h000"),
+					Sets: []w3m.ObjectSet{{Modifications: []w3m.Modification{
+						{ID: protocol.DString("ugol"), Type: w3m.ValueInt, Int: 200, End: protocol.DString("h000")},
+						{ID: protocol.DString("umvs"), Type: w3m.ValueReal, Real: 1.5},
+					}}},
+				},
+			},
+		},
+		w3m.ObjectData{
+			Type:       w3m.ObjectAbilities,
+			FileFormat: 3,
+			Original:   []w3m.Object{},
+			Custom: []w3m.Object{
+				w3m.Object{
+					OriginalID: protocol.DString("AHbz"),
+					CustomID:   protocol.DString("A000"),
+					Sets: []w3m.ObjectSet{
+						{Modifications: []w3m.Modification{
+							{ID: protocol.DString("Hbz1"), Level: 2, DataPointer: 1, Type: w3m.ValueUnreal, Real: 0.25},
+						}},
+						{Flags: 1, Modifications: []w3m.Modification{}},
+					},
+				},
+			},
+		},
+		w3m.ObjectData{
+			Type:       w3m.ObjectItems,
+			FileFormat: 2,
+			Original:   []w3m.Object{},
+			Custom: []w3m.Object{
+				w3m.Object{
+					OriginalID: protocol.DString("ratc"),
+					CustomID:   protocol.DString("I000"),
+					Sets: []w3m.ObjectSet{{Modifications: []w3m.Modification{
+						{ID: protocol.DString("igol"), Type: w3m.ValueInt, Int: 50},
+						{ID: protocol.DString("unam"), Type: w3m.ValueString, String: ""},
+					}}},
+				},
+			},
+		},
+	}
+
+	for _, f := range files {
+		var buf protocol.Buffer
+		if err := f.Serialize(&buf); err != nil {
+			t.Fatal(f.Type, err)
+		}
+
+		var obj = w3m.ObjectData{Type: f.Type}
+		if err := obj.Deserialize(&protocol.Buffer{Bytes: append([]byte{}, buf.Bytes...)}); err != nil {
+			t.Fatal(f.Type, err)
+		}
+		if !reflect.DeepEqual(obj, f) {
+			t.Fatalf("%v: object data not deep equal %+v\n", f.Type, obj)
+		}
+
+		for i := 1; i < buf.Size(); i++ {
+			var obj = w3m.ObjectData{Type: f.Type}
+			if err := obj.Deserialize(&protocol.Buffer{Bytes: buf.Bytes[:i]}); err != w3m.ErrBadFormat {
+				t.Fatalf("%v: expected ErrBadFormat for size %v, got %v\n", f.Type, i, err)
+			}
+		}
+	}
+
+	var dir = t.TempDir()
+	os.Mkdir(filepath.Join(dir, "Units"), 0755)
+	os.WriteFile(filepath.Join(dir, "Units", "UnitMetaData.slk"), []byte(
+		"ID;PWXL;N;E\nC;Y1;X1;K\"ID\"\nC;X2;K\"field\"\nC;X3;K\"slk\"\n"+
+			"C;Y2;X1;K\"uhpm\"\nC;X2;K\"HP\"\nC;X3;K\"UnitBalance\"\n"+
+			"C;Y3;X1;K\"ugol\"\nC;X2;K\"goldcost\"\nC;X3;K\"UnitBalance\"\n"+
+			"C;Y4;X1;K\"unam\"\nC;X2;K\"Name\"\nC;X3;K\"Profile\"\n"+
+			"C;Y5;X1;K\"uhot\"\nC;X2;K\"Hotkey\"\nC;X3;K\"Profile\"\nE\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "Units", "UnitBalance.slk"), []byte(
+		"ID;PWXL;N;E\nC;Y1;X1;K\"unitBalanceID\"\nC;X2;K\"goldcost\"\nC;X3;K\"HP\"\n"+
+			"C;Y2;X1;K\"hfoo\"\nC;X2;K135\nC;X3;K420\n"+
+			"C;Y3;X1;K\"hpea\"\nC;X2;K75\nC;X3;K220\nE\n"), 0644)
+
+	os.WriteFile(filepath.Join(dir, "Units", "HumanUnitStrings.txt"), []byte(
+		"[hfoo]\nName=Footman\nHotkey=F\nTip=Train Footman\n[hpea]\nName=TRIGSTR_001\n"), 0644)
+
+	var stor = fs.Open(dir)
+	defer stor.Close()
+
+	vals, unknown, err := files[0].Merge(stor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = w3m.ObjectValues{
+		protocol.DString("hfoo"): {"goldcost": "135", "HP": "500", "Name": "Knight", "Hotkey": "F", "tip": "Train Footman"},
+		protocol.DString("hpea"): {"goldcost": "75", "HP": "220", "Name": "TRIGSTR_001"},
+		protocol.DString("h000"): {"goldcost": "200", "HP": "420", "Name": "Footman", "Hotkey": "F", "tip": "Train Footman"},
+	}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Fatal("Merge mismatch", vals)
+	}
+	if !reflect.DeepEqual(unknown, w3m.ObjectValues{protocol.DString("h000"): {"umvs": "1.5"}}) {
+		t.Fatal("Unknown mismatch", unknown)
+	}
+
+	m, err := w3m.Open("./test_roc.w3m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	data, err := m.GameData(stor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := data.Name(protocol.DString("hpea")); n != "Smallest Map" {
+		t.Fatal("GameData name mismatch", n)
+	}
+}
+
+func TestScript(t *testing.T) {
+	var files = []struct {
+		file     string
+		decls    int
+		rawcodes int
+	}{
+		{"test_roc.w3m", 15, 4},
+		{"test_tft.w3x", 14, 36},
+	}
+
+	for _, f := range files {
+		m, err := w3m.Open("./" + f.file)
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+
+		s, err := m.Script()
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if len(s.Decls) != f.decls || len(s.Rawcodes()) != f.rawcodes {
+			t.Fatalf("%v script mismatch %v %v\n", f.file, len(s.Decls), s.Rawcodes())
+		}
+		if s.Functions()["main"] == nil || s.Functions()["config"] == nil {
+			t.Fatal(f.file, "main or config missing")
+		}
+
+		var src = s.String()
+		s2, err := jass.Parse([]byte(src))
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if s2.String() != src {
+			t.Fatal(f.file, "round trip mismatch")
+		}
+
+		m.Close()
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	var dir = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "UI"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "UI", "TriggerData.txt"), []byte(
+		"[TriggerEvents]\nMapInitializationEvent=0\nTriggerRegisterTimerEventPeriodic=0,real\n"+
+			"[TriggerActions]\nMeleeStartingVisibility=0,nothing\nMeleeStartingHeroLimit=0\nMeleeGrantHeroItems=0\n"+
+			"MeleeStartingResources=0\nMeleeClearExcessUnits=0\nMeleeStartingUnits=0\nMeleeStartingAI=0\nMeleeInitVictoryDefeat=0\n"+
+			"_MeleeStartingAI_Category=TC_MELEE\n"+
+			"[TriggerCalls]\nGetUnitName=0,1,string,unit\n",
+	), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stor = fs.Open(dir)
+	defer stor.Close()
+
+	data, err := w3m.LoadTriggerData(stor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 11 || data["triggerregistertimereventperiodic"] != 1 || data["getunitname"] != 1 || data["meleestartingvisibility"] != 0 {
+		t.Fatal("TriggerData mismatch", data)
+	}
+
+	m, err := w3m.Open("./test_roc.w3m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, err := m.Triggers(w3m.TriggerData{}); err != w3m.ErrUnknownFunction {
+		t.Fatal("Expected ErrUnknownFunction, got", err)
+	}
+
+	trg, err := m.Triggers(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trg.FileFormat != 4 || len(trg.Categories) != 1 || trg.Categories[0].Name != "Initialization" || len(trg.Variables) != 0 || len(trg.Triggers) != 1 {
+		t.Fatalf("Triggers mismatch %+v\n", trg)
+	}
+
+	var melee = trg.Triggers[0]
+	if melee.Name != "Melee Initialization" || melee.Type != w3m.TriggerElementGUI || !melee.Enabled || melee.CategoryID != trg.Categories[0].ID || len(melee.ECAs) != 9 {
+		t.Fatalf("Trigger mismatch %+v\n", melee)
+	}
+	if melee.ECAs[0].Type != w3m.ECAEvent || melee.ECAs[0].Name != "MapInitializationEvent" || melee.ECAs[8].Type != w3m.ECAAction || melee.ECAs[8].Name != "MeleeInitVictoryDefeat" {
+		t.Fatalf("ECA mismatch %+v\n", melee.ECAs)
+	}
+
+	wct, err := m.CustomScripts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wct.FileFormat != 0 || !reflect.DeepEqual(wct.Scripts, []string{""}) {
+		t.Fatalf("CustomScripts mismatch %+v\n", wct)
+	}
+
+	m2, err := w3m.Open("./test_tft.w3x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m2.Close()
+
+	trg, err = m2.Triggers(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trg.FileFormat != 7 || len(trg.Categories) != 0 || len(trg.Triggers) != 0 {
+		t.Fatalf("Triggers mismatch %+v\n", trg)
+	}
+
+	wct, err = m2.CustomScripts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wct.FileFormat != 1 || !strings.HasPrefix(wct.Comment, "Enter map-specific custom script code below.") || wct.Header != "" || len(wct.Scripts) != 0 {
+		t.Fatalf("CustomScripts mismatch %+v\n", wct)
+	}
+}
+
+// Write dwords, bool32s and C strings in order
+func writeFields(b *protocol.Buffer, fields ...interface{}) {
+	for _, f := range fields {
+		switch v := f.(type) {
+		case uint32:
+			b.WriteUInt32(v)
+		case int:
+			b.WriteUInt32(uint32(v))
+		case bool:
+			b.WriteBool32(v)
+		case string:
+			b.WriteCString(v)
+		case protocol.DWordString:
+			b.WriteLEDString(v)
+		default:
+			panic(f)
+		}
+	}
+}
+
+func TestTriggerFixtures(t *testing.T) {
+	var data = w3m.TriggerData{
+		"triggerregistertimereventperiodic": 1,
+		"ifthenelsemultiple":                0,
+		"operatorcomparestring":             3,
+		"getunitname":                       1,
+		"donothing":                         0,
+	}
+
+	// Shared between fixtures: an if/then/else action with a condition that compares GetUnitName(udg_units[1])
+	var ecas = []w3m.ECA{
+		{Type: w3m.ECAEvent, Name: "TriggerRegisterTimerEventPeriodic", Enabled: true, Parameters: []w3m.Parameter{
+			{Type: w3m.ParameterString, Value: "2.00"},
+		}},
+		{Type: w3m.ECAAction, Name: "IfThenElseMultiple", Enabled: true, Parameters: []w3m.Parameter{}, Children: []w3m.ECA{
+			{Type: w3m.ECACondition, Group: 0, Name: "OperatorCompareString", Enabled: true, Parameters: []w3m.Parameter{
+				{Type: w3m.ParameterFunction, Call: &w3m.FunctionCall{Type: w3m.ECACall, Name: "GetUnitName", Args: []w3m.Parameter{
+					{Type: w3m.ParameterVariable, Value: "udg_units", Index: &w3m.Parameter{Type: w3m.ParameterString, Value: "1"}},
+				}}},
+				{Type: w3m.ParameterPreset, Value: "OperatorEqualENE"},
+				{Type: w3m.ParameterString, Value: "Footman"},
+			}, Children: []w3m.ECA{}},
+			{Type: w3m.ECAAction, Group: 1, Name: "DoNothing", Enabled: true, Parameters: []w3m.Parameter{}, Children: []w3m.ECA{}},
+		}},
+	}
+	ecas[0].Children = []w3m.ECA{}
+
+	var writeECAs = func(b *protocol.Buffer) {
+		writeFields(b, 2)
+		writeFields(b, 0, "TriggerRegisterTimerEventPeriodic", true)
+		writeFields(b, 3, "2.00", false, false)
+		writeFields(b, 0)
+
+		writeFields(b, 2, "IfThenElseMultiple", true)
+		writeFields(b, 2)
+		writeFields(b, 1, 0, "OperatorCompareString", true)
+		writeFields(b, 2, "", true, 3, "GetUnitName", true)
+		writeFields(b, 1, "udg_units", false, true)
+		writeFields(b, 3, "1", false, false)
+		writeFields(b, 0, false)
+		writeFields(b, 0, "OperatorEqualENE", false, false)
+		writeFields(b, 3, "Footman", false, false)
+		writeFields(b, 0)
+		writeFields(b, 2, 1, "DoNothing", true)
+		writeFields(b, 0)
+	}
+
+	// TFT (version 7)
+	var tft protocol.Buffer
+	writeFields(&tft, w3m.TriggerHeader, 7)
+	writeFields(&tft, 1, 0, "Initialization", false)
+	writeFields(&tft, 2)
+	writeFields(&tft, 1, "udg_units", "unit", 1, true, 12, false, "")
+	writeFields(&tft, 1, "Compare", "Checks the unit name", false, true, false, false, false, 0)
+	writeECAs(&tft)
+
+	var trg w3m.Triggers
+	if err := trg.Deserialize(&protocol.Buffer{Bytes: tft.Bytes}, data); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = w3m.Triggers{
+		FileFormat: 7,
+		Categories: []w3m.TriggerCategory{{Type: w3m.TriggerElementCategory, ID: 0, Name: "Initialization"}},
+		Variables:  []w3m.TriggerVariable{{Name: "udg_units", Type: "unit", Array: true, ArraySize: 12}},
+		Triggers: []w3m.Trigger{
+			{Type: w3m.TriggerElementGUI, Name: "Compare", Description: "Checks the unit name", Enabled: true, ECAs: ecas},
+		},
+	}
+	if !reflect.DeepEqual(trg, expected) {
+		t.Fatalf("TFT triggers mismatch %+v != %+v\n", trg, expected)
+	}
+	if err := trg.Deserialize(&protocol.Buffer{Bytes: tft.Bytes[:len(tft.Bytes)-1]}, data); err == nil {
+		t.Fatal("Expected error for truncated input")
+	}
+
+	// 1.31+ (version 0x80000004, sub version 7)
+	var ref protocol.Buffer
+	writeFields(&ref, w3m.TriggerHeader, 0x80000004, 7)
+	for i := 0; i < 7; i++ {
+		if i == 2 {
+			writeFields(&ref, 3, 2, 5, 6)
+		} else {
+			writeFields(&ref, 0, 0)
+		}
+	}
+	writeFields(&ref, 0, 0, 2)
+	writeFields(&ref, 1, "udg_units", "unit", 1, true, 12, false, "", 0x06000001, 0)
+	writeFields(&ref, 5)
+	writeFields(&ref, 1, 0, "test.w3x", false, true, 0xFFFFFFFF)
+	writeFields(&ref, 4, 1, "Initialization", false, true, 0)
+	writeFields(&ref, 64, 0x06000001, "udg_units", 0)
+	writeFields(&ref, 8, "Compare", "Checks the unit name", false, 0x03000002, true, false, false, false, 1)
+	writeECAs(&ref)
+	writeFields(&ref, 32, "Script", "", false, 0x03000003, true, true, false, false, 1, 0)
+
+	if err := trg.Deserialize(&protocol.Buffer{Bytes: ref.Bytes}, data); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = w3m.Triggers{
+		FileFormat: 0x80000004,
+		SubVersion: 7,
+		Categories: []w3m.TriggerCategory{
+			{Type: w3m.TriggerElementMap, ID: 0, Name: "test.w3x", Expanded: true, ParentID: 0xFFFFFFFF},
+			{Type: w3m.TriggerElementCategory, ID: 1, Name: "Initialization", Expanded: true, ParentID: 0},
+		},
+		Variables: []w3m.TriggerVariable{{ID: 0x06000001, Name: "udg_units", Type: "unit", Array: true, ArraySize: 12}},
+		Triggers: []w3m.Trigger{
+			{Type: w3m.TriggerElementGUI, ID: 0x03000002, Name: "Compare", Description: "Checks the unit name", Enabled: true, CategoryID: 1, ECAs: ecas},
+			{Type: w3m.TriggerElementScript, ID: 0x03000003, Name: "Script", Enabled: true, CustomText: true, CategoryID: 1, ECAs: []w3m.ECA{}},
+		},
+	}
+	if !reflect.DeepEqual(trg, expected) {
+		t.Fatalf("1.31 triggers mismatch %+v != %+v\n", trg, expected)
+	}
+	if err := trg.Deserialize(&protocol.Buffer{Bytes: ref.Bytes[:len(ref.Bytes)-1]}, data); err == nil {
+		t.Fatal("Expected error for truncated input")
+	}
+
+	// 1.31+ custom scripts, one per trigger
+	var wct protocol.Buffer
+	writeFields(&wct, 0x80000004, 1, "Header comment", 11)
+	wct.WriteBlob([]byte("// header\n\x00"))
+	writeFields(&wct, 2, 0, 17)
+	wct.WriteBlob([]byte("call DoNothing()\x00"))
+
+	var scr w3m.CustomScripts
+	if err := scr.Deserialize(&protocol.Buffer{Bytes: wct.Bytes}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scr, w3m.CustomScripts{FileFormat: 0x80000004, SubVersion: 1, Comment: "Header comment", Header: "// header\n", Scripts: []string{"", "call DoNothing()"}}) {
+		t.Fatalf("1.31 custom scripts mismatch %+v\n", scr)
+	}
+	if err := scr.Deserialize(&protocol.Buffer{Bytes: wct.Bytes[:len(wct.Bytes)-1]}); err == nil {
+		t.Fatal("Expected error for truncated input")
+	}
+}
+
+func TestRegions(t *testing.T) {
+	for _, file := range []string{"test_roc.w3m", "test_tft.w3x"} {
+		m, err := w3m.Open("./" + file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		regions, err := m.Regions()
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if regions.FileFormat != 5 || len(regions.Regions) != 0 {
+			t.Fatalf("%v regions mismatch %+v\n", file, regions)
+		}
+
+		cameras, err := m.Cameras()
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if cameras.FileFormat != 0 || cameras.LocalRotation || len(cameras.Cameras) != 0 {
+			t.Fatalf("%v cameras mismatch %+v\n", file, cameras)
+		}
+
+		if _, err := m.Sounds(); err != os.ErrNotExist {
+			t.Fatal(file, "Expected ErrNotExist, got", err)
+		}
+		if s, err := m.RegionSounds(); err != nil || len(s) != 0 {
+			t.Fatal(file, "RegionSounds mismatch", s, err)
+		}
+
+		m.Close()
+	}
+
+	var regions = w3m.Regions{
+		FileFormat: 5,
+		Regions: []w3m.Region{
+			{Left: -512, Bottom: -256, Right: 512, Top: 256, Name: "Center", CreationNumber: 0, Weather: protocol.DString("RAhr"), AmbientSound: "gg_snd_Rain", Color: color.RGBA{R: 255, G: 128, B: 64, A: 255}},
+			{Left: 0, Bottom: 0, Right: 128, Top: 128, Name: "Corner", CreationNumber: 1, Color: color.RGBA{A: 255}},
+		},
+	}
+	var sounds = w3m.Sounds{
+		FileFormat: 1,
+		Sounds: []w3m.Sound{
+			{Name: "gg_snd_Rain", File: "Sound\\Ambient\\Rain.wav", EAXEffect: "DefaultEAXON", Flags: w3m.SoundFlagLooping | w3m.SoundFlag3D, FadeInRate: 10, FadeOutRate: 10, Volume: -1, Pitch: 1, Unknown2: -1, Channel: 5, MinDistance: 600, MaxDistance: 10000, DistanceCutoff: 3000, ConeInside: 360, ConeOutside: 360, ConeOutsideVolume: 127, ConeOrientationZ: 1},
+		},
+	}
+	var cameras = w3m.Cameras{
+		LocalRotation: true,
+		Cameras: []w3m.Camera{
+			{TargetX: 100, TargetY: -100, Rotation: 90, AngleOfAttack: 304, Distance: 1650, FieldOfView: 70, FarZ: 5000, NearZ: 100, LocalPitch: 1, LocalYaw: 2, LocalRoll: 3, Name: "Camera 001"},
+		},
+	}
+
+	var buf protocol.Buffer
+	for _, v := range []interface {
+		Serialize(*protocol.Buffer) error
+		Deserialize(*protocol.Buffer) error
+	}{&regions, &sounds, &cameras} {
+		buf.Truncate()
+		if err := v.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		var res = reflect.New(reflect.TypeOf(v).Elem()).Interface().(interface {
+			Deserialize(*protocol.Buffer) error
+		})
+		if c, ok := res.(*w3m.Cameras); ok {
+			c.LocalRotation = true
+		}
+
+		var b = buf.Bytes
+		if err := res.Deserialize(&protocol.Buffer{Bytes: b}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res, v) {
+			t.Fatalf("Round trip mismatch %+v != %+v\n", res, v)
+		}
+		if err := res.Deserialize(&protocol.Buffer{Bytes: b[:len(b)-1]}); err == nil {
+			t.Fatal("Expected error for truncated input")
+		}
+	}
+
+	for _, ver := range []uint32{2, 3} {
+		var b protocol.Buffer
+		b.WriteUInt32(ver)
+		b.WriteUInt32(0)
+		if err := (&w3m.Sounds{}).Deserialize(&b); err != w3m.ErrBadFormat {
+			t.Fatal("Expected ErrBadFormat for sound format", ver, err)
+		}
+	}
+
+	if s := regions.Regions[0].Sound(&sounds); s != &sounds.Sounds[0] {
+		t.Fatal("Region sound mismatch", s)
+	}
+	if s := regions.Regions[1].Sound(&sounds); s != nil {
+		t.Fatal("Expected no region sound, got", s)
+	}
+	if !regions.Regions[0].Contains(0, 0) || regions.Regions[1].Contains(-1, 64) {
+		t.Fatal("Contains mismatch")
+	}
+}
+
+func TestTriggerStrings(t *testing.T) {
+	m, err := w3m.Open("./test_roc.w3m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ts, err := m.TriggerStrings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts[1] != "Smallest Map" || ts[2] != "Any" || len(ts) != 6 {
+		t.Fatal("TriggerStrings mismatch", ts)
+	}
+
+	var exp = map[int]string{1: "First", 2: "Second"}
+	for _, bom := range []string{"", "\ufeff"} {
+		var wts = bom + "STRING 1\r\n{\r\nFirst\r\n}\r\n\r\nSTRING 2\r\n// Comment\r\n{\r\nSecond\r\n}\r\n"
+		res, err := w3m.DecodeTriggerStrings(strings.NewReader(wts))
+		if err != nil || !reflect.DeepEqual(res, exp) {
+			t.Fatal("wts mismatch", res, err)
+		}
+	}
+}
+
+func TestInfoWrite(t *testing.T) {
+	for _, file := range []string{"test_roc.w3m", "test_tft.w3x"} {
+		m, err := w3m.Open("./" + file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		f, err := m.Archive.Open("war3map.w3i")
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		var raw protocol.Buffer
+		if _, err := raw.ReadFrom(f); err != nil {
+			t.Fatal(file, err)
+		}
+		f.Close()
+
+		var info w3m.Info
+		if err := info.Deserialize(&protocol.Buffer{Bytes: raw.Bytes}); err != nil {
+			t.Fatal(file, err)
+		}
+		if !strings.HasPrefix(info.Name, "TRIGSTR_") {
+			t.Fatal(file, "Expected unexpanded name, got", info.Name)
+		}
+
+		var buf protocol.Buffer
+		if err := info.Serialize(&buf); err != nil {
+			t.Fatal(file, err)
+		}
+		if !bytes.Equal(buf.Bytes, raw.Bytes) {
+			t.Fatal(file, "w3i round trip mismatch")
+		}
+
+		f, err = m.Archive.Open("war3map.wts")
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		raw.Truncate()
+		if _, err := raw.ReadFrom(f); err != nil {
+			t.Fatal(file, err)
+		}
+		f.Close()
+
+		ts, err := w3m.DecodeTriggerStrings(bytes.NewReader(raw.Bytes))
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var wts bytes.Buffer
+		if err := w3m.EncodeTriggerStrings(&wts, ts); err != nil {
+			t.Fatal(file, err)
+		}
+		if !bytes.Equal(wts.Bytes(), raw.Bytes) {
+			t.Fatal(file, "wts round trip mismatch")
+		}
+
+		m.Close()
+	}
+
+	var ts = map[int]string{3: "Multi\nLine", 1: "Name", 10: ""}
+	var wts bytes.Buffer
+	if err := w3m.EncodeTriggerStrings(&wts, ts); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := w3m.DecodeTriggerStrings(&wts); err != nil || !reflect.DeepEqual(res, ts) {
+		t.Fatal("wts mismatch", res, err)
+	}
+
+	var commented = "\ufeffSTRING 1\r\n// Units: h000 (Footman), Name (Name)\r\n{\r\nFootman\r\n}\r\n\r\n" +
+		"STRING 2\r\n{\r\nNo comment\r\n}\r\n\r\n" +
+		"STRING 3\r\n// Line 1\r\n// Line 2\r\n{\r\nMulti\r\nLine\r\n}\r\n\r\n"
+	ts, comments, err := w3m.DecodeTriggerStringsWithComments(strings.NewReader(commented))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(comments, map[int]string{1: "// Units: h000 (Footman), Name (Name)", 3: "// Line 1\n// Line 2"}) {
+		t.Fatal("wts comments mismatch", comments)
+	}
+	wts.Reset()
+	if err := w3m.EncodeTriggerStringsWithComments(&wts, ts, comments); err != nil {
+		t.Fatal(err)
+	}
+	if wts.String() != commented {
+		t.Fatalf("wts round trip with comments mismatch %q\n", wts.String())
+	}
+
+	for _, format := range []uint32{18, 25, 28, 31} {
+		var info = w3m.Info{
+			FileFormat:    format,
+			SaveCount:     7,
+			EditorVersion: 6105,
+			Name:          "TRIGSTR_001",
+			Players: []w3m.Player{
+				{ID: 0, Type: w3m.PlayerHuman, Race: w3m.RaceOrc, Name: "TRIGSTR_002", AllyPrioLow: 2},
+			},
+			Forces: []w3m.Force{
+				{PlayerSet: 1, Name: "TRIGSTR_003"},
+			},
+			CustomUpgradeAvailabilities: []w3m.CustomUpgradeAvailability{
+				{PlayerSet: 1, UpgradeID: protocol.DString("Rhme"), Level: 1, Availability: w3m.UpgradeAvailable},
+			},
+			CustomTechAvailabilities: []w3m.CustomTechAvailability{
+				{PlayerSet: 1, TechID: protocol.DString("hfoo")},
+			},
+			RandomUnitTables: []w3m.RandomUnitTable{
+				{
+					ID:        0,
+					Name:      "Creeps",
+					Positions: []w3m.RandomPosition{w3m.RandomPositionUnit, w3m.RandomPositionItem},
+					Rows: []w3m.RandomUnitRow{
+						{Chance: 60, IDs: []protocol.DWordString{protocol.DString("nfor"), protocol.DString("ratc")}},
+						{Chance: 40, IDs: []protocol.DWordString{protocol.DString("ngnb"), 0}},
+					},
+				},
+			},
+		}
+		if format >= 25 {
+			info.LsPath = "Path"
+			info.SoundEnv = "Default"
+			info.WeatherID = protocol.DString("RAhr")
+			info.RandomItemTables = []w3m.RandomItemTable{
+				{ID: 1, Name: "Drops", Sets: []w3m.ItemSet{{{ItemID: protocol.DString("ratc"), Chance: 100}}}},
+			}
+		} else {
+			info.RandomItemTables = nil
+		}
+		if format >= 28 {
+			info.GameVersion = w3m.GameVersion{Major: 1, Minor: 31, Patch: 1, Commit: 12173}
+			info.CodeFormat = w3m.GameCodeFormatJASS
+		}
+		if format >= 31 {
+			info.SupportedModes = 3
+			info.GameDataVersion = 1
+			info.Players[0].EnemyPrioHigh = 4
+		}
+
+		var buf protocol.Buffer
+		if err := info.Serialize(&buf); err != nil {
+			t.Fatal(format, err)
+		}
+
+		var b = append([]byte{}, buf.Bytes...)
+		var res w3m.Info
+		if err := res.Deserialize(&buf); err != nil {
+			t.Fatal(format, err)
+		}
+		if !reflect.DeepEqual(res, info) {
+			t.Fatalf("%v info mismatch %+v\n", format, res)
+		}
+
+		buf.Truncate()
+		if err := res.Serialize(&buf); err != nil || !bytes.Equal(buf.Bytes, b) {
+			t.Fatal(format, "Serialize mismatch", err)
+		}
+
+		for i := 0; i < len(b); i++ {
+			res.Deserialize(&protocol.Buffer{Bytes: b[:i]})
+		}
+	}
+
+	if err := (&w3m.Info{FileFormat: 1}).Serialize(&protocol.Buffer{}); err != w3m.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+}
+
+func TestImports(t *testing.T) {
+	for _, file := range []string{"test_roc.w3m", "test_tft.w3x"} {
+		m, err := w3m.Open("./" + file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		if _, err := m.Imports(); err != os.ErrNotExist {
+			t.Fatal(file, "Expected ErrNotExist, got", err)
+		}
+		if p, missing, err := m.ExtractImports(t.TempDir()); err != nil || len(p) != 0 || len(missing) != 0 {
+			t.Fatal(file, "ExtractImports mismatch", p, missing, err)
+		}
+
+		m.Close()
+	}
+
+	m, err := w3m.Open("./test_tft.w3x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var dir = t.TempDir()
+	var files = w3m.Imports{
+		FileFormat: 1,
+		Imports: []w3m.Import{
+			{Flags: w3m.ImportCustomPath, Name: "war3map.j"},
+			{Flags: w3m.ImportStandardPath, Name: "Missing.mdx"},
+			{Flags: w3m.ImportCustomPath, Name: "war3map.w3i"},
+		},
+	}
+	p, missing, err := m.ExtractFiles(&files, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, []string{filepath.Join(dir, "war3map.j"), filepath.Join(dir, "war3map.w3i")}) {
+		t.Fatal("Extracted paths mismatch", p)
+	}
+	if !reflect.DeepEqual(missing, []string{"war3mapImported\\Missing.mdx"}) {
+		t.Fatal("Missing paths mismatch", missing)
+	}
+	for i, name := range []string{"war3map.j", "war3map.w3i"} {
+		f, err := m.Archive.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := os.ReadFile(p[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actual) == 0 || !bytes.Equal(actual, expected) {
+			t.Fatal("Extracted content mismatch", name)
+		}
+	}
+
+	var imp = w3m.Imports{
+		FileFormat: 1,
+		Imports: []w3m.Import{
+			{Flags: w3m.ImportStandardPath, Name: "Model.mdx"},
+			{Flags: w3m.ImportStandardPathAlt, Name: "war3mapImported\\Texture.blp"},
+			{Flags: w3m.ImportCustomPath, Name: "Units\\Human\\Footman\\Footman.mdx"},
+			{Flags: w3m.ImportCustomPathAlt, Name: "..\\..\\evil.dll"},
+		},
+	}
+
+	var buf protocol.Buffer
+	if err := imp.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var b = append([]byte{}, buf.Bytes...)
+	var res w3m.Imports
+	if err := res.Deserialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, imp) {
+		t.Fatalf("Imports mismatch %+v\n", res)
+	}
+	for i := 0; i < len(b); i++ {
+		if err := res.Deserialize(&protocol.Buffer{Bytes: b[:i]}); err == nil {
+			t.Fatal("Expected error for truncated input at", i)
+		}
+	}
+
+	var paths = []string{
+		"war3mapImported\\Model.mdx",
+		"war3mapImported\\Texture.blp",
+		"Units\\Human\\Footman\\Footman.mdx",
+		"..\\..\\evil.dll",
+	}
+	for i := range imp.Imports {
+		if p := imp.Imports[i].Path(); p != paths[i] {
+			t.Fatal("Path mismatch", p, paths[i])
+		}
+	}
+
+	if p, err := imp.Imports[2].LocalPath(); err != nil || p != filepath.Join("Units", "Human", "Footman", "Footman.mdx") {
+		t.Fatal("LocalPath mismatch", p, err)
+	}
+	if _, err := imp.Imports[3].LocalPath(); err != w3m.ErrInvalidPath {
+		t.Fatal("Expected ErrInvalidPath, got", err)
+	}
+
+	if err := (&w3m.Imports{FileFormat: 2}).Serialize(&protocol.Buffer{}); err != w3m.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat, got", err)
+	}
+}
@@ -6,79 +6,135 @@ package w3m
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// Byte order mark at the start of war3map.wts
+const bom = "\ufeff"
+
 // TriggerString recognition
 var reWTS = regexp.MustCompile(`^STRING (\d+)$`)
 var reTS = regexp.MustCompile(`^TRIGSTR_(\d+)$`)
 
-// TriggerStrings from war3map.wts
-func (m *Map) TriggerStrings() (map[int]string, error) {
-	if m.ts == nil {
-		wts, err := m.Archive.Open("war3map.wts")
-		if err != nil {
-			return nil, err
+// DecodeTriggerStrings decodes the contents of a war3map.wts file, comments are dropped
+func DecodeTriggerStrings(r io.Reader) (map[int]string, error) {
+	ts, _, err := DecodeTriggerStringsWithComments(r)
+	return ts, err
+}
+
+// DecodeTriggerStringsWithComments decodes the contents of a war3map.wts file, including the
+// "//" comment lines between each STRING header and its opening brace (joined by "\n", keyed
+// by string ID). Any other content outside STRING blocks is dropped.
+func DecodeTriggerStringsWithComments(r io.Reader) (map[int]string, map[int]string, error) {
+	var buf = bufio.NewReader(r)
+	var ts = make(map[int]string)
+	var comments = make(map[int]string)
+	for {
+		l, err := buf.ReadString('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
 		}
-		defer wts.Close()
 
-		buf := bufio.NewReader(wts)
+		match := reWTS.FindStringSubmatch(strings.TrimSpace(strings.TrimPrefix(l, bom)))
+		if len(match) < 2 {
+			continue
+		}
 
-		if _, err := buf.Discard(1); err != nil && err != io.EOF {
-			return nil, err
+		id, err := strconv.ParseInt(match[1], 10, 0)
+		if err != nil {
+			continue
 		}
 
-		var ts = make(map[int]string)
+		var cb strings.Builder
 		for {
-			l, err := buf.ReadString('\n')
-			if err == io.EOF {
+			p1, err := buf.ReadString('\n')
+			if err != nil {
+				return nil, nil, err
+			}
+			if strings.TrimSpace(p1) == "{" {
 				break
-			} else if err != nil {
-				return nil, err
+			} else if !strings.HasPrefix(p1, "//") {
+				return nil, nil, ErrBadFormat
 			}
 
-			match := reWTS.FindStringSubmatch(strings.TrimSpace(l))
-			if len(match) < 2 {
-				continue
+			if cb.Len() > 0 {
+				cb.WriteByte('\n')
 			}
+			cb.WriteString(strings.TrimRight(p1, "\r\n"))
+		}
+		if cb.Len() > 0 {
+			comments[int(id)] = cb.String()
+		}
 
-			id, err := strconv.ParseInt(match[1], 10, 0)
+		var sb strings.Builder
+		for {
+			l, err := buf.ReadString('\n')
 			if err != nil {
-				continue
+				return nil, nil, err
 			}
-
-			for {
-				p1, err := buf.ReadString('\n')
-				if err != nil {
-					return nil, err
-				}
-				if strings.TrimSpace(p1) == "{" {
-					break
-				} else if !strings.HasPrefix(p1, "//") {
-					return nil, ErrBadFormat
-				}
+			if strings.TrimSpace(l) == "}" {
+				break
 			}
 
-			var sb strings.Builder
-			for {
-				l, err := buf.ReadString('\n')
-				if err != nil {
-					return nil, err
-				}
-				if strings.TrimSpace(l) == "}" {
-					break
-				}
-
-				if sb.Len() > 0 {
-					sb.WriteByte('\n')
-				}
-				sb.WriteString(strings.TrimRight(l, "\r\n"))
+			if sb.Len() > 0 {
+				sb.WriteByte('\n')
 			}
+			sb.WriteString(strings.TrimRight(l, "\r\n"))
+		}
 
-			ts[int(id)] = sb.String()
+		ts[int(id)] = sb.String()
+	}
+
+	return ts, comments, nil
+}
+
+// EncodeTriggerStrings encodes ts in the war3map.wts format, ordered by id
+func EncodeTriggerStrings(w io.Writer, ts map[int]string) error {
+	return EncodeTriggerStringsWithComments(w, ts, nil)
+}
+
+// EncodeTriggerStringsWithComments encodes ts in the war3map.wts format, ordered by id. Comments (as
+// returned by DecodeTriggerStringsWithComments) are written between the STRING header and opening brace.
+func EncodeTriggerStringsWithComments(w io.Writer, ts map[int]string, comments map[int]string) error {
+	var ids = make([]int, 0, len(ts))
+	for id := range ts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var buf = bufio.NewWriter(w)
+	buf.WriteString(bom)
+	for _, id := range ids {
+		fmt.Fprintf(buf, "STRING %d\r\n", id)
+		if c, ok := comments[id]; ok {
+			buf.WriteString(strings.ReplaceAll(c, "\n", "\r\n"))
+			buf.WriteString("\r\n")
+		}
+		fmt.Fprintf(buf, "{\r\n%s\r\n}\r\n\r\n", strings.ReplaceAll(ts[id], "\n", "\r\n"))
+	}
+
+	return buf.Flush()
+}
+
+// TriggerStrings from war3map.wts
+func (m *Map) TriggerStrings() (map[int]string, error) {
+	if m.ts == nil {
+		wts, err := m.Archive.Open("war3map.wts")
+		if err != nil {
+			return nil, err
+		}
+		defer wts.Close()
+
+		ts, err := DecodeTriggerStrings(wts)
+		if err != nil {
+			return nil, err
 		}
 
 		m.ts = ts
@@ -87,22 +143,26 @@ func (m *Map) TriggerStrings() (map[int]string, error) {
 	return m.ts, nil
 }
 
-// ExpandString expands trigger strings in s and returns the expanded string
-func (m *Map) ExpandString(s string) (string, error) {
-	ts, err := m.TriggerStrings()
-	if err != nil {
-		return "", err
-	}
-
+func expandString(ts map[int]string, s string) string {
 	match := reTS.FindStringSubmatch(s)
 	if ts == nil || len(match) == 0 {
-		return s, nil
+		return s
 	}
 
 	id, err := strconv.ParseInt(match[1], 10, 0)
+	if err != nil {
+		return s
+	}
+
+	return ts[int(id)]
+}
+
+// ExpandString expands trigger strings in s and returns the expanded string
+func (m *Map) ExpandString(s string) (string, error) {
+	ts, err := m.TriggerStrings()
 	if err != nil {
 		return "", err
 	}
 
-	return ts[int(id)], nil
+	return expandString(ts, s), nil
 }
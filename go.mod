@@ -15,6 +15,7 @@ require (
 	golang.org/x/net v0.8.0
 	golang.org/x/sys v0.6.0
 	golang.org/x/term v0.6.0
+	gopkg.in/yaml.v3 v3.0.1
 	honnef.co/go/tools v0.4.3
 )
 
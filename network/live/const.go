@@ -0,0 +1,17 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package live
+
+import "errors"
+
+// Errors
+var (
+	ErrClosed = errors.New("live: Stream closed")
+)
+
+// Message types that do not hold a record
+const (
+	TypeHeader = "Header"
+)
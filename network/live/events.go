@@ -0,0 +1,19 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package live
+
+import "net"
+
+// ViewerJoined event
+type ViewerJoined struct {
+	Addr net.Addr
+	Raw  bool
+}
+
+// ViewerLeft event
+type ViewerLeft struct {
+	Addr net.Addr
+	Raw  bool
+}
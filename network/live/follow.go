@@ -0,0 +1,161 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package live
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/lobby"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// FollowGame records the action stream of g, the stream is closed when the game is done
+// The lobby does not know the game name, flags and map dimensions, so those are left empty in GameInfo.
+func (s *Server) FollowGame(g *lobby.Game) {
+	var write = func(src string, recs ...w3g.Record) {
+		if err := s.WriteRecords(recs...); err != nil && err != ErrClosed {
+			s.Fire(&network.AsyncError{Src: "FollowGame[" + src + "]", Err: err})
+		}
+	}
+
+	g.On(&lobby.StageChanged{}, func(ev *network.Event) {
+		switch ev.Arg.(*lobby.StageChanged).New {
+		case lobby.StagePlaying:
+			var slots = g.SlotInfo()
+			var info = w3g.GameInfo{
+				GameSettings: w3gs.GameSettings{
+					MapXoro: g.MapCheck.MapXoro,
+					MapPath: g.MapCheck.FilePath,
+					MapSha1: g.MapCheck.MapSha1,
+				},
+				NumSlots: uint32(len(slots.Slots)),
+			}
+
+			var players []w3g.Record
+			for _, slot := range slots.Slots {
+				if slot.SlotStatus != w3gs.SlotOccupied || slot.Computer {
+					continue
+				}
+				var p = g.Player(slot.PlayerID)
+				if p == nil {
+					continue
+				}
+
+				var rec = w3g.PlayerInfo{
+					ID:          p.PlayerInfo.PlayerID,
+					Name:        p.PlayerInfo.PlayerName,
+					Race:        slot.Race,
+					JoinCounter: p.PlayerInfo.JoinCounter,
+				}
+
+				// Lobby has no host player, use the first player instead
+				if info.HostPlayer.ID == 0 {
+					info.HostPlayer = rec
+					info.GameSettings.HostName = rec.Name
+					continue
+				}
+				players = append(players, &rec)
+			}
+
+			write("GameInfo", &info)
+			write("PlayerInfo", players...)
+			write("Start", &w3g.SlotInfo{SlotInfo: *slots}, &w3g.CountDownStart{}, &w3g.CountDownEnd{}, &w3g.GameStart{})
+		case lobby.StageDone:
+			if err := s.Close(); err != nil {
+				s.Fire(&network.AsyncError{Src: "FollowGame[Close]", Err: err})
+			}
+		}
+	})
+	g.On(&w3gs.TimeSlot{}, func(ev *network.Event) {
+		write("TimeSlot", &w3g.TimeSlot{TimeSlot: *ev.Arg.(*w3gs.TimeSlot)})
+	})
+	g.On(&lobby.PlayerChat{}, func(ev *network.Event) {
+		var chat = ev.Arg.(*lobby.PlayerChat)
+		if g.Stage() != lobby.StagePlaying {
+			return
+		}
+		write("PlayerChat", &w3g.ChatMessage{Message: *chat.Message})
+	})
+	g.On(&lobby.PlayerLeft{}, func(ev *network.Event) {
+		var p = ev.Arg.(*lobby.PlayerLeft).Player
+		if g.Stage() != lobby.StagePlaying {
+			return
+		}
+		write("PlayerLeft", &w3g.PlayerLeft{
+			PlayerID: p.PlayerInfo.PlayerID,
+			Reason:   p.LeaveReason(),
+		})
+	})
+}
+
+// FollowReplay streams the records in r, which may be a replay that is still being written by w3g.Encoder
+// The header is skipped (it is only written when the Encoder closes), records are decoded using s.Header.
+func (s *Server) FollowReplay(r io.Reader) error {
+	var hdr [68]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return err
+	}
+
+	var data = w3g.NewDecompressor(r, s.Header.Encoding(), nil, math.MaxUint32, math.MaxUint32)
+	return data.ForEach(s.WriteRecord)
+}
+
+// TailReader is an io.Reader that waits for more data at the end of a growing file
+type TailReader struct {
+	R       io.Reader
+	Poll    time.Duration
+	Timeout time.Duration // Close after not receiving new data for this long (0 to wait indefinitely)
+
+	once sync.Once
+	done sync.Once
+	stop chan struct{}
+}
+
+func (t *TailReader) init() {
+	t.once.Do(func() {
+		t.stop = make(chan struct{})
+	})
+}
+
+// Read implements the io.Reader interface.
+func (t *TailReader) Read(b []byte) (int, error) {
+	t.init()
+
+	var poll = t.Poll
+	if poll <= 0 {
+		poll = 100 * time.Millisecond
+	}
+
+	var start = time.Now()
+	for {
+		n, err := t.R.Read(b)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if t.Timeout > 0 && time.Since(start) >= t.Timeout {
+			t.Close()
+		}
+
+		select {
+		case <-t.stop:
+			return 0, io.EOF
+		case <-time.After(poll):
+		}
+	}
+}
+
+// Close stops waiting for data, any blocked or future Read returns io.EOF
+func (t *TailReader) Close() error {
+	t.init()
+	t.done.Do(func() {
+		close(t.stop)
+	})
+	return nil
+}
@@ -0,0 +1,294 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package live implements a server that streams a game in progress to WebSocket viewers.
+//
+// Viewers receive the full history of the game on connect and then follow it live.
+// By default, every record is sent as a JSON text message (see Message). Viewers that
+// connect with ?format=raw receive the Header message followed by compressed w3g data
+// blocks as binary messages instead.
+package live
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/network"
+)
+
+// Message sent to viewers
+type Message struct {
+	Seq    int         `json:"seq"`
+	TimeMS uint32      `json:"time"`
+	Type   string      `json:"type"`
+	Record interface{} `json:"record"`
+}
+
+// Server streams records to WebSocket viewers, implements http.Handler
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Server struct {
+	network.EventEmitter
+
+	// Set once before ServeHTTP(), read-only after that
+	Header        w3g.Header
+	Upgrader      websocket.Upgrader
+	WriteTimeout  time.Duration
+	FlushInterval time.Duration
+
+	hdr []byte // Header message, immutable after NewServer()
+
+	mut     sync.Mutex
+	comp    *w3g.Compressor
+	msgs    [][]byte
+	blocks  [][]byte
+	time    uint32
+	flushed uint32
+	closed  bool
+	viewers map[*viewer]struct{}
+}
+
+type viewer struct {
+	conn *websocket.Conn
+	raw  bool
+	wake chan struct{}
+	stop chan struct{}
+}
+
+type blockWriter struct {
+	s *Server
+}
+
+// Write implements the io.Writer interface, s.mut should be locked
+func (w blockWriter) Write(b []byte) (int, error) {
+	w.s.blocks = append(w.s.blocks, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+// NewServer initializes a new Server struct
+func NewServer(hdr *w3g.Header) *Server {
+	var s = Server{
+		Header:        *hdr,
+		WriteTimeout:  10 * time.Second,
+		FlushInterval: 2 * time.Second,
+		viewers:       make(map[*viewer]struct{}),
+	}
+
+	s.comp = w3g.NewCompressor(blockWriter{&s}, hdr.Encoding())
+
+	s.hdr, _ = s.marshal(TypeHeader, &s.Header)
+	s.msgs = append(s.msgs, s.hdr)
+
+	return &s
+}
+
+// mut should be locked
+func (s *Server) marshal(t string, rec interface{}) ([]byte, error) {
+	return json.Marshal(&Message{
+		Seq:    len(s.msgs),
+		TimeMS: s.time,
+		Type:   t,
+		Record: rec,
+	})
+}
+
+// mut should be locked
+func (s *Server) notify() {
+	for v := range s.viewers {
+		select {
+		case v.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Time of the stream in game time
+func (s *Server) Time() time.Duration {
+	s.mut.Lock()
+	var t = s.time
+	s.mut.Unlock()
+	return time.Duration(t) * time.Millisecond
+}
+
+// NumViewers currently connected
+func (s *Server) NumViewers() int {
+	s.mut.Lock()
+	var n = len(s.viewers)
+	s.mut.Unlock()
+	return n
+}
+
+// WriteRecord appends rec to the stream
+func (s *Server) WriteRecord(rec w3g.Record) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if ts, ok := rec.(*w3g.TimeSlot); ok {
+		s.time += uint32(ts.TimeIncrementMS)
+	}
+
+	msg, err := s.marshal(w3g.RecordType(rec), rec)
+	if err != nil {
+		return err
+	}
+
+	s.msgs = append(s.msgs, msg)
+	if _, err := s.comp.WriteRecord(rec); err != nil {
+		return err
+	}
+
+	if time.Duration(s.time-s.flushed)*time.Millisecond >= s.FlushInterval {
+		s.flushed = s.time
+		if err := s.comp.Flush(); err != nil {
+			return err
+		}
+	}
+
+	s.notify()
+	return nil
+}
+
+// WriteRecords appends all of recs to the stream
+func (s *Server) WriteRecords(recs ...w3g.Record) error {
+	for _, r := range recs {
+		if err := s.WriteRecord(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush buffered data to raw viewers
+func (s *Server) Flush() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	s.flushed = s.time
+	var err = s.comp.Flush()
+
+	s.notify()
+	return err
+}
+
+// Close ends the stream, viewers are disconnected after receiving the remaining records
+func (s *Server) Close() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+	var err = s.comp.Close()
+
+	s.notify()
+	return err
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and streams the game until it is closed
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.Fire(&network.AsyncError{Src: "ServeHTTP[Upgrade]", Err: err})
+		return
+	}
+	defer conn.Close()
+
+	var v = viewer{
+		conn: conn,
+		raw:  r.URL.Query().Get("format") == "raw",
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+	}
+
+	s.mut.Lock()
+	s.viewers[&v] = struct{}{}
+	s.mut.Unlock()
+
+	s.Fire(&ViewerJoined{Addr: conn.RemoteAddr(), Raw: v.raw})
+
+	// Discard incoming messages, stop when connection is closed
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				close(v.stop)
+				return
+			}
+		}
+	}()
+
+	if err := s.serve(&v); err != nil && !network.IsCloseError(err) {
+		s.Fire(&network.AsyncError{Src: "ServeHTTP[serve]", Err: err})
+	}
+
+	s.mut.Lock()
+	delete(s.viewers, &v)
+	s.mut.Unlock()
+
+	s.Fire(&ViewerLeft{Addr: conn.RemoteAddr(), Raw: v.raw})
+}
+
+func (s *Server) write(v *viewer, t int, b []byte) error {
+	if s.WriteTimeout >= 0 {
+		if err := v.conn.SetWriteDeadline(network.Deadline(s.WriteTimeout)); err != nil {
+			return err
+		}
+	}
+	return v.conn.WriteMessage(t, b)
+}
+
+func (s *Server) serve(v *viewer) error {
+	var idx = 0
+	var typ = websocket.TextMessage
+	if v.raw {
+		if err := s.write(v, websocket.TextMessage, s.hdr); err != nil {
+			return err
+		}
+		typ = websocket.BinaryMessage
+	}
+
+	for {
+		s.mut.Lock()
+		var pending = s.msgs[idx:]
+		if v.raw {
+			pending = s.blocks[idx:]
+		}
+		var closed = s.closed
+		s.mut.Unlock()
+
+		for _, b := range pending {
+			if err := s.write(v, typ, b); err != nil {
+				return err
+			}
+			idx++
+		}
+
+		if len(pending) > 0 {
+			continue
+		}
+		if closed {
+			var msg = websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			return v.conn.WriteControl(websocket.CloseMessage, msg, network.Deadline(s.WriteTimeout))
+		}
+
+		select {
+		case <-v.wake:
+		case <-v.stop:
+			return nil
+		}
+	}
+}
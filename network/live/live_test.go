@@ -0,0 +1,258 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package live_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/crc32"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/network/live"
+	"github.com/nielsAD/gowarcraft3/network/lobby"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func collect(t *testing.T, conn *websocket.Conn) ([]live.Message, []byte) {
+	var msgs []live.Message
+	var data []byte
+	for {
+		typ, b, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Fatal(err)
+			}
+			return msgs, data
+		}
+
+		switch typ {
+		case websocket.TextMessage:
+			var m live.Message
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Fatal(err)
+			}
+			if m.Seq != len(msgs) {
+				t.Fatalf("Expected seq %d, got %d", len(msgs), m.Seq)
+			}
+			msgs = append(msgs, m)
+		case websocket.BinaryMessage:
+			data = append(data, b...)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	rep, err := w3g.Open("../../file/w3g/test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s = live.NewServer(&rep.Header)
+	var srv = httptest.NewServer(s)
+	defer srv.Close()
+
+	var half = len(rep.Records) / 2
+	if err := s.WriteRecords(rep.Records[:half]...); err != nil {
+		t.Fatal(err)
+	}
+
+	var jsonConn = dial(t, srv.URL)
+	var rawConn = dial(t, srv.URL+"?format=raw")
+	defer jsonConn.Close()
+	defer rawConn.Close()
+
+	var done = make(chan struct{})
+	var msgs []live.Message
+	var raw []live.Message
+	var data []byte
+	go func() {
+		raw, data = collect(t, rawConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		msgs, _ = collect(t, jsonConn)
+		done <- struct{}{}
+	}()
+
+	if err := s.WriteRecords(rep.Records[half:]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteRecord(&w3g.GameStart{}); err != live.ErrClosed {
+		t.Fatal("ErrClosed expected, got", err)
+	}
+
+	<-done
+	<-done
+
+	if len(msgs) != len(rep.Records)+1 {
+		t.Fatalf("Expected %d messages, got %d", len(rep.Records)+1, len(msgs))
+	}
+	if msgs[0].Type != live.TypeHeader || msgs[1].Type != "TimeSlot" {
+		t.Fatal("Type mismatch", msgs[0].Type, msgs[1].Type)
+	}
+	if last := msgs[len(msgs)-1]; last.TimeMS != w3g.Duration(rep.Records) {
+		t.Fatal("Time mismatch", last.TimeMS)
+	}
+
+	if len(raw) != 1 || raw[0].Type != live.TypeHeader {
+		t.Fatal("Expected header message for raw viewer")
+	}
+
+	var n = 0
+	var dec = w3g.NewDecompressor(bytes.NewReader(data), rep.Encoding(), nil, math.MaxUint32, math.MaxUint32)
+	if err := dec.ForEach(func(r w3g.Record) error {
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(rep.Records) {
+		t.Fatalf("Expected %d raw records, got %d", len(rep.Records), n)
+	}
+}
+
+func TestFollowReplay(t *testing.T) {
+	rep, err := w3g.Open("../../file/w3g/test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name = filepath.Join(t.TempDir(), "live.w3g")
+	if err := rep.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var s = live.NewServer(&rep.Header)
+	var tail = live.TailReader{R: f, Poll: time.Millisecond, Timeout: 50 * time.Millisecond}
+	if err := s.FollowReplay(&tail); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if s.Time() != time.Duration(w3g.Duration(rep.Records))*time.Millisecond {
+		t.Fatal("Time mismatch", s.Time())
+	}
+
+	var srv = httptest.NewServer(s)
+	defer srv.Close()
+
+	var conn = dial(t, srv.URL)
+	defer conn.Close()
+
+	// GameInfo, PlayerInfo, PlayerExtra, SlotInfo, CountDownStart, CountDownEnd, GameStart
+	var setup = 5 + len(rep.PlayerInfo) - 1 + len(rep.PlayerExtra)
+	if msgs, _ := collect(t, conn); len(msgs) != 1+setup+len(rep.Records) {
+		t.Fatalf("Expected %d messages, got %d", 1+setup+len(rep.Records), len(msgs))
+	}
+}
+
+// rawReplay prepends a w3g header to the compressed data blocks received by a raw viewer
+func rawReplay(hdr *w3g.Header, data []byte) []byte {
+	var numBlocks uint32
+	var sizeTotal uint32
+	for pos := 0; pos+12 <= len(data); numBlocks++ {
+		var blk = protocol.Buffer{Bytes: data[pos : pos+8]}
+		var lenDeflate = blk.ReadUInt32()
+		sizeTotal += blk.ReadUInt32()
+		pos += 12 + int(lenDeflate)
+	}
+
+	var buf protocol.Buffer
+	buf.WriteCString(w3g.Signature)
+	buf.WriteUInt32(68)
+	buf.WriteUInt32(uint32(len(data) + 68))
+	buf.WriteUInt32(1)
+	buf.WriteUInt32(sizeTotal)
+	buf.WriteUInt32(numBlocks)
+	hdr.GameVersion.SerializeContent(&buf, &w3gs.Encoding{})
+	buf.WriteUInt16(hdr.BuildNumber)
+	buf.WriteUInt16(0x8000)
+	buf.WriteUInt32(hdr.DurationMS)
+	buf.WriteUInt32(0)
+	buf.WriteUInt32At(64, crc32.ChecksumIEEE(buf.Bytes))
+
+	return append(buf.Bytes, data...)
+}
+
+func TestFollowGame(t *testing.T) {
+	var mapInfo = w3gs.MapCheck{FilePath: "Maps/Test.w3x", MapXoro: 0x01020304}
+	var g = lobby.NewGame(w3gs.Encoding{GameVersion: w3gs.CurrentGameVersion}, w3gs.SlotInfo{}, mapInfo)
+	var hdr = w3g.Header{GameVersion: w3gs.GameVersion{Product: w3gs.ProductTFT, Version: w3gs.CurrentGameVersion}}
+	var s = live.NewServer(&hdr)
+	s.FollowGame(g)
+
+	g.Fire(&lobby.StageChanged{Old: lobby.StageLoading, New: lobby.StagePlaying})
+	for i := 0; i < 3; i++ {
+		g.Fire(&w3gs.TimeSlot{
+			TimeIncrementMS: 100,
+			Actions:         []w3gs.PlayerAction{{PlayerID: 1, Data: []byte{0x01}}},
+		})
+	}
+	g.Fire(&lobby.StageChanged{Old: lobby.StagePlaying, New: lobby.StageDone})
+
+	if err := s.WriteRecord(&w3g.GameStart{}); err != live.ErrClosed {
+		t.Fatal("ErrClosed expected, got", err)
+	}
+	if s.Time() != 300*time.Millisecond {
+		t.Fatal("Time mismatch", s.Time())
+	}
+
+	var srv = httptest.NewServer(s)
+	defer srv.Close()
+
+	var conn = dial(t, srv.URL)
+	defer conn.Close()
+
+	var msgs, _ = collect(t, conn)
+	var types []string
+	for _, m := range msgs {
+		types = append(types, m.Type)
+	}
+	if strings.Join(types, ",") != "Header,GameInfo,SlotInfo,CountDownStart,CountDownEnd,GameStart,TimeSlot,TimeSlot,TimeSlot" {
+		t.Fatal("Unexpected messages", types)
+	}
+
+	var rawConn = dial(t, srv.URL+"?format=raw")
+	defer rawConn.Close()
+
+	var _, data = collect(t, rawConn)
+	rep, err := w3g.Decode(bytes.NewReader(rawReplay(&hdr, data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.GameSettings.MapPath != mapInfo.FilePath || rep.GameSettings.MapXoro != mapInfo.MapXoro {
+		t.Fatal("GameInfo mismatch", rep.GameInfo)
+	}
+	if len(rep.Records) != 3 || w3g.Duration(rep.Records) != 300 {
+		t.Fatal("Records mismatch", rep.Records)
+	}
+}
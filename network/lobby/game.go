@@ -223,17 +223,36 @@ func (g *Game) gameloop() {
 
 		var newTick = atomic.AddUint32(&g.tick, 1)
 
+		// Keep a copy of sent timeslots so that the action stream can be observed
+		var sent []w3gs.TimeSlot
+
 		pkt.TimeIncrementMS = uint16(inc.Milliseconds())
 		for send := true; send; send = len(g.actions) > 0 {
 			pkt.Actions, pkt.Fragment = g.splitActions()
 			g.SendToAll(&pkt)
+			sent = append(sent, copyTimeSlot(&pkt))
 		}
 
 		g.actmut.Unlock()
+		for i := range sent {
+			g.Fire(&sent[i])
+		}
 		g.Fire(Tick(newTick))
 	}
 }
 
+func copyTimeSlot(pkt *w3gs.TimeSlot) w3gs.TimeSlot {
+	var res = *pkt
+	res.Actions = make([]w3gs.PlayerAction, len(pkt.Actions))
+	for i, a := range pkt.Actions {
+		res.Actions[i] = w3gs.PlayerAction{
+			PlayerID: a.PlayerID,
+			Data:     append([]byte(nil), a.Data...),
+		}
+	}
+	return res
+}
+
 // actmut should be locked
 func (g *Game) incLaggers(inc uint32) {
 	if len(g.laggers.Players) > 0 && g.laggers.Players[0].LagDurationMS == 0 {
@@ -0,0 +1,13 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package playback
+
+import "errors"
+
+// Errors
+var (
+	ErrRunning      = errors.New("playback: Already running")
+	ErrInvalidSpeed = errors.New("playback: Invalid speed")
+)
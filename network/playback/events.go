@@ -0,0 +1,32 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package playback
+
+// Paused event
+type Paused struct{}
+
+// Resumed event
+type Resumed struct{}
+
+// Restart event, fired before playback restarts from the beginning after seeking backwards
+// Clients cannot rewind a game, so handlers should reload them (i.e. make them rejoin) before returning.
+type Restart struct {
+	Target uint32
+}
+
+// Finished event, fired after the last record has been played
+type Finished struct{}
+
+// SpeedChanged event
+type SpeedChanged struct {
+	Old float64
+	New float64
+}
+
+// SinkError event, fired when a sink fails and is removed
+type SinkError struct {
+	Sink Sink
+	Err  error
+}
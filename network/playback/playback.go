@@ -0,0 +1,316 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package playback implements a replay player that streams a w3g replay to game clients as w3gs packets.
+package playback
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Sink receives the packets of a playback (i.e. *network.W3GSConn or *lobby.Player)
+type Sink interface {
+	Send(pkt w3gs.Packet) (int, error)
+}
+
+// Playback plays a replay as a timed stream of w3gs packets to all connected sinks
+// Every packet is fired as event before it is sent, handlers can call PreventNext to drop it.
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type Playback struct {
+	network.EventEmitter
+
+	// Set once before Run(), read-only after that
+	Replay   *w3g.Replay
+	ViewerID uint8
+
+	wake chan struct{}
+
+	mut     sync.Mutex
+	sinks   []Sink
+	running bool
+	closed  bool
+	paused  bool
+	restart bool
+	speed   float64
+	time    uint32
+	target  uint32
+}
+
+// NewPlayback initializes a new Playback struct
+// Clients join the replay as viewerID, whose leave record is therefore not played.
+func NewPlayback(replay *w3g.Replay, viewerID uint8) *Playback {
+	return &Playback{
+		Replay:   replay,
+		ViewerID: viewerID,
+		wake:     make(chan struct{}, 1),
+		speed:    1,
+	}
+}
+
+func (p *Playback) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// AddSink starts sending packets to s
+func (p *Playback) AddSink(s Sink) {
+	p.mut.Lock()
+	p.sinks = append(p.sinks, s)
+	p.mut.Unlock()
+}
+
+// RemoveSink stops sending packets to s
+func (p *Playback) RemoveSink(s Sink) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	for i, v := range p.sinks {
+		if v != s {
+			continue
+		}
+		p.sinks = append(p.sinks[:i:i], p.sinks[i+1:]...)
+		return true
+	}
+
+	return false
+}
+
+// NumSinks currently connected
+func (p *Playback) NumSinks() int {
+	p.mut.Lock()
+	var n = len(p.sinks)
+	p.mut.Unlock()
+	return n
+}
+
+// SendToAll sends pkt to all sinks, removing the ones that fail
+func (p *Playback) SendToAll(pkt w3gs.Packet) {
+	p.mut.Lock()
+	var sinks = p.sinks
+	p.mut.Unlock()
+
+	for _, s := range sinks {
+		if _, err := s.Send(pkt); err != nil {
+			p.RemoveSink(s)
+			p.Fire(&SinkError{Sink: s, Err: err})
+		}
+	}
+}
+
+// Time of playback in game time
+func (p *Playback) Time() time.Duration {
+	p.mut.Lock()
+	var t = p.time
+	p.mut.Unlock()
+	return time.Duration(t) * time.Millisecond
+}
+
+// Speed multiplier of playback
+func (p *Playback) Speed() float64 {
+	p.mut.Lock()
+	var s = p.speed
+	p.mut.Unlock()
+	return s
+}
+
+// SetSpeed multiplier of playback (i.e. 2 for double speed, 0.5 for half speed)
+func (p *Playback) SetSpeed(speed float64) error {
+	if !(speed > 0) || math.IsInf(speed, 0) {
+		return ErrInvalidSpeed
+	}
+
+	p.mut.Lock()
+	var old = p.speed
+	p.speed = speed
+	p.mut.Unlock()
+
+	p.notify()
+	if old != speed {
+		p.Fire(&SpeedChanged{Old: old, New: speed})
+	}
+	return nil
+}
+
+// Paused playback
+func (p *Playback) Paused() bool {
+	p.mut.Lock()
+	var paused = p.paused
+	p.mut.Unlock()
+	return paused
+}
+
+// Pause playback
+func (p *Playback) Pause() {
+	p.mut.Lock()
+	var changed = !p.paused
+	p.paused = true
+	p.mut.Unlock()
+
+	p.notify()
+	if changed {
+		p.Fire(&Paused{})
+	}
+}
+
+// Resume playback
+func (p *Playback) Resume() {
+	p.mut.Lock()
+	var changed = p.paused
+	p.paused = false
+	p.mut.Unlock()
+
+	p.notify()
+	if changed {
+		p.Fire(&Resumed{})
+	}
+}
+
+// Seek to game time t, skipping ahead without delay
+// Seeking backwards restarts playback from the beginning (see Restart event).
+func (p *Playback) Seek(t time.Duration) {
+	var ms = uint32(t / time.Millisecond)
+	if t < 0 {
+		ms = 0
+	}
+
+	p.mut.Lock()
+	if ms < p.time {
+		p.restart = true
+	}
+	p.target = ms
+	p.mut.Unlock()
+
+	p.notify()
+}
+
+// Close stops playback
+func (p *Playback) Close() {
+	p.mut.Lock()
+	p.closed = true
+	p.mut.Unlock()
+
+	p.notify()
+}
+
+// sleep for ms game time, returns false if interrupted by Close or Seek
+func (p *Playback) sleep(ms uint32) bool {
+	var rem = time.Duration(ms) * time.Millisecond
+	for rem > 0 {
+		p.mut.Lock()
+		var interrupt = p.closed || p.restart
+		var forward = p.target > p.time
+		var paused = p.paused
+		var speed = p.speed
+		p.mut.Unlock()
+
+		switch {
+		case interrupt:
+			return false
+		case forward:
+			return true
+		case paused:
+			<-p.wake
+			continue
+		}
+
+		var start = time.Now()
+		var timer = time.NewTimer(time.Duration(float64(rem) / speed))
+		select {
+		case <-timer.C:
+			return true
+		case <-p.wake:
+			timer.Stop()
+			rem -= time.Duration(float64(time.Since(start)) * speed)
+		}
+	}
+
+	return true
+}
+
+func (p *Playback) play(pkt w3gs.Packet) {
+	if p.Fire(pkt) {
+		return
+	}
+	p.SendToAll(pkt)
+}
+
+// Run plays the replay, blocks until finished or closed
+func (p *Playback) Run() error {
+	p.mut.Lock()
+	if p.running {
+		p.mut.Unlock()
+		return ErrRunning
+	}
+	p.running = true
+	p.time = 0
+	p.mut.Unlock()
+
+	defer func() {
+		p.mut.Lock()
+		p.running = false
+		p.mut.Unlock()
+	}()
+
+	var records = p.Replay.Records
+	for i := 0; i < len(records); {
+		p.mut.Lock()
+		if p.closed {
+			p.mut.Unlock()
+			return nil
+		}
+		if p.restart {
+			var target = p.target
+			p.restart = false
+			p.time = 0
+			p.mut.Unlock()
+
+			i = 0
+			p.Fire(&Restart{Target: target})
+			continue
+		}
+		p.mut.Unlock()
+
+		var pkt w3gs.Packet
+		switch v := records[i].(type) {
+		case *w3g.PlayerLeft:
+			if v.PlayerID == p.ViewerID {
+				break
+			}
+			pkt = &w3gs.PlayerLeft{
+				PlayerID: v.PlayerID,
+				Reason:   v.Reason,
+			}
+		case *w3g.TimeSlot:
+			if !p.sleep(uint32(v.TimeIncrementMS)) {
+				continue
+			}
+
+			p.mut.Lock()
+			p.time += uint32(v.TimeIncrementMS)
+			p.mut.Unlock()
+
+			pkt = &v.TimeSlot
+		case *w3g.Desync:
+			pkt = &v.Desync
+		case *w3g.ChatMessage:
+			pkt = &w3gs.MessageRelay{Message: v.Message}
+		}
+
+		i++
+		if pkt != nil {
+			p.play(pkt)
+		}
+	}
+
+	p.Fire(&Finished{})
+	return nil
+}
@@ -0,0 +1,237 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package playback_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/playback"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+type sink struct {
+	mut  sync.Mutex
+	pkts []w3gs.Packet
+	err  error
+}
+
+func (s *sink) Send(pkt w3gs.Packet) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.pkts = append(s.pkts, pkt)
+	return 0, nil
+}
+
+func (s *sink) count() (int, int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var slots = 0
+	for _, p := range s.pkts {
+		if _, ok := p.(*w3gs.TimeSlot); ok {
+			slots++
+		}
+	}
+	return len(s.pkts), slots
+}
+
+func replay() *w3g.Replay {
+	var res = w3g.Replay{}
+	for i := 0; i < 10; i++ {
+		res.Records = append(res.Records, &w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{TimeIncrementMS: 100}})
+		if i == 4 {
+			res.Records = append(res.Records, &w3g.ChatMessage{Message: w3gs.Message{SenderID: 2, Content: "gg"}})
+		}
+	}
+	res.Records = append(res.Records,
+		&w3g.PlayerLeft{PlayerID: 2, Reason: w3gs.LeaveLost},
+		&w3g.PlayerLeft{PlayerID: 1, Reason: w3gs.LeaveLost},
+	)
+	return &res
+}
+
+func TestPlayback(t *testing.T) {
+	var p = playback.NewPlayback(replay(), 1)
+	var s sink
+	p.AddSink(&s)
+
+	if err := p.SetSpeed(0); err != playback.ErrInvalidSpeed {
+		t.Fatal("ErrInvalidSpeed expected, got", err)
+	}
+	if err := p.SetSpeed(100); err != nil {
+		t.Fatal(err)
+	}
+
+	var finished = 0
+	p.On(&playback.Finished{}, func(ev *network.Event) {
+		finished++
+	})
+
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if finished != 1 {
+		t.Fatal("Finished expected")
+	}
+	if p.Time() != time.Second {
+		t.Fatal("Time mismatch", p.Time())
+	}
+
+	if n, slots := s.count(); n != 12 || slots != 10 {
+		t.Fatalf("Expected 12 packets (10 slots), got %d (%d slots)", n, slots)
+	}
+	if m, ok := s.pkts[5].(*w3gs.MessageRelay); !ok || m.Content != "gg" {
+		t.Fatal("MessageRelay expected", s.pkts[5])
+	}
+	if l, ok := s.pkts[11].(*w3gs.PlayerLeft); !ok || l.PlayerID != 2 {
+		t.Fatal("PlayerLeft expected", s.pkts[11])
+	}
+
+	// Drop chat
+	s.pkts = nil
+	p.On(&w3gs.MessageRelay{}, func(ev *network.Event) {
+		ev.PreventNext()
+	})
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.count(); n != 11 {
+		t.Fatal("Expected 11 packets, got", n)
+	}
+}
+
+func TestPause(t *testing.T) {
+	var p = playback.NewPlayback(replay(), 1)
+	var s sink
+	p.AddSink(&s)
+	p.SetSpeed(100)
+	p.Pause()
+
+	var done = make(chan error)
+	go func() { done <- p.Run() }()
+
+	time.Sleep(50 * time.Millisecond)
+	if n, _ := s.count(); n != 0 {
+		t.Fatal("Expected no packets while paused, got", n)
+	}
+
+	p.Resume()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout")
+	}
+
+	if n, _ := s.count(); n != 12 {
+		t.Fatal("Expected 12 packets, got", n)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	var p = playback.NewPlayback(replay(), 1)
+	var s sink
+	p.AddSink(&s)
+	p.SetSpeed(5)
+	p.Seek(800 * time.Millisecond)
+
+	var restart = 0
+	p.On(&playback.Restart{}, func(ev *network.Event) {
+		restart++
+		if ev.Arg.(*playback.Restart).Target != 200 {
+			t.Fatal("Restart target mismatch")
+		}
+	})
+	p.On(&w3gs.TimeSlot{}, func(ev *network.Event) {
+		if restart == 0 && p.Time() == 900*time.Millisecond {
+			p.Seek(200 * time.Millisecond)
+		}
+	})
+
+	var start = time.Now()
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatal("Seek did not fast-forward", d)
+	}
+	if restart != 1 {
+		t.Fatal("Expected 1 restart, got", restart)
+	}
+	if _, slots := s.count(); slots != 19 {
+		t.Fatal("Expected 19 slots, got", slots)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var p = playback.NewPlayback(replay(), 1)
+
+	var s1 sink
+	var s2 = sink{err: errors.New("test")}
+	p.AddSink(&s1)
+	p.AddSink(&s2)
+
+	var sinkErr = 0
+	p.On(&playback.SinkError{}, func(ev *network.Event) {
+		sinkErr++
+		if ev.Arg.(*playback.SinkError).Sink != &s2 {
+			t.Fatal("Sink mismatch")
+		}
+	})
+	p.On(&w3gs.TimeSlot{}, func(ev *network.Event) {
+		if p.Time() == 300*time.Millisecond {
+			p.Close()
+		}
+	})
+
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if sinkErr != 1 || p.NumSinks() != 1 {
+		t.Fatal("Expected failing sink to be removed")
+	}
+	if _, slots := s1.count(); slots != 3 {
+		t.Fatal("Expected 3 slots, got", slots)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	rep, err := w3g.Open("../../file/w3g/test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var slots = 0
+	for _, r := range rep.Records {
+		if _, ok := r.(*w3g.TimeSlot); ok {
+			slots++
+		}
+	}
+
+	var p = playback.NewPlayback(rep, rep.HostPlayer.ID)
+	var s sink
+	p.AddSink(&s)
+	p.Seek(time.Hour)
+
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, n := s.count(); n != slots {
+		t.Fatalf("Expected %d slots, got %d", slots, n)
+	}
+	if p.Time() != time.Duration(w3g.Duration(rep.Records))*time.Millisecond {
+		t.Fatal("Time mismatch", p.Time())
+	}
+}
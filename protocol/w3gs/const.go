@@ -574,14 +574,19 @@ type PlayerExtraType uint8
 
 // PlayerExtra record type
 const (
-	PlayerExtra2  PlayerExtraType = 0x02
+	PlayerUnits   PlayerExtraType = 0x02
 	PlayerProfile PlayerExtraType = 0x03
 	PlayerSkins   PlayerExtraType = 0x04
 	PlayerExtra5  PlayerExtraType = 0x05
+
+	// Deprecated: use PlayerUnits
+	PlayerExtra2 = PlayerUnits
 )
 
 func (t PlayerExtraType) String() string {
 	switch t {
+	case PlayerUnits:
+		return "Units"
 	case PlayerProfile:
 		return "BNetProfile"
 	case PlayerSkins:
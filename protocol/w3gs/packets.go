@@ -405,10 +405,10 @@ func (pkt *SlotInfoJoin) Deserialize(buf *protocol.Buffer, enc *Encoding) error
 //	   (UINT8) Computer type
 //	   (UINT8) Handicap
 type SlotInfo struct {
-	Slots      []SlotData
-	RandomSeed uint32
-	SlotLayout SlotLayout
-	NumPlayers uint8
+	Slots      []SlotData `json:"Slots"`
+	RandomSeed uint32     `json:"RandomSeed"`
+	SlotLayout SlotLayout `json:"SlotLayout"`
+	NumPlayers uint8      `json:"NumPlayers"`
 }
 
 // SlotData stores the info for a single game slot.
@@ -455,15 +455,15 @@ type SlotInfo struct {
 //	  (UINT8) Computer type
 //	  (UINT8) Handicap
 type SlotData struct {
-	PlayerID       uint8
-	DownloadStatus uint8
-	SlotStatus     SlotStatus
-	Computer       bool
-	Team           uint8
-	Color          uint8
-	Race           RacePref
-	ComputerType   AI
-	Handicap       uint8
+	PlayerID       uint8      `json:"PlayerID"`
+	DownloadStatus uint8      `json:"DownloadStatus"`
+	SlotStatus     SlotStatus `json:"SlotStatus"`
+	Computer       bool       `json:"Computer"`
+	Team           uint8      `json:"Team"`
+	Color          uint8      `json:"Color"`
+	Race           RacePref   `json:"Race"`
+	ComputerType   AI         `json:"ComputerType"`
+	Handicap       uint8      `json:"Handicap"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -1141,9 +1141,9 @@ func (pkt *GameAction) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //	   (UINT16) Length of action data
 //	     (VOID) Action data
 type TimeSlot struct {
-	Fragment        bool
-	TimeIncrementMS uint16
-	Actions         []PlayerAction
+	Fragment        bool           `json:"Fragment"`
+	TimeIncrementMS uint16         `json:"TimeIncrementMS"`
+	Actions         []PlayerAction `json:"Actions"`
 }
 
 // PlayerAction stores the data for a single game action.
@@ -1152,8 +1152,8 @@ type TimeSlot struct {
 //	(UINT16) Length of action data
 //	(VOID) Action data
 type PlayerAction struct {
-	PlayerID uint8
-	Data     []byte
+	PlayerID uint8  `json:"PlayerID"`
+	Data     []byte `json:"Data"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -1289,9 +1289,9 @@ func (pkt *TimeSlotAck) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //	(UINT8)   NumPlayersInState
 //	(UINT8)[] Player numbers
 type Desync struct {
-	Unknown1       uint32
-	Checksum       uint32
-	PlayersInState []uint8
+	Unknown1       uint32  `json:"Unknown1"`
+	Checksum       uint32  `json:"Checksum"`
+	PlayersInState []uint8 `json:"PlayersInState"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -1371,12 +1371,12 @@ func (pkt *Desync) DeserializeContent(buf *protocol.Buffer, enc *Encoding) error
 //	   (UINT32) Message scope (0x00 all, 0x01 allies, 0x02 observers, else directed to N-0x03)
 //	   (STRING) Message
 type Message struct {
-	RecipientIDs []uint8
-	SenderID     uint8
-	Type         MessageType
-	Scope        MessageScope
-	NewVal       uint8
-	Content      string
+	RecipientIDs []uint8      `json:"RecipientIDs"`
+	SenderID     uint8        `json:"SenderID"`
+	Type         MessageType  `json:"Type"`
+	Scope        MessageScope `json:"Scope"`
+	NewVal       uint8        `json:"NewVal"`
+	Content      string       `json:"Content"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -1581,8 +1581,8 @@ func (pkt *SearchGame) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //	  (UINT32) Product
 //	  (UINT32) Version
 type GameVersion struct {
-	Product protocol.DWordString
-	Version uint32
+	Product protocol.DWordString `json:"Product"`
+	Version uint32               `json:"Version"`
 }
 
 // SerializeContent encodes the struct into its binary form without packet ID.
@@ -1636,13 +1636,13 @@ func (gv *GameVersion) DeserializeContent(buf *protocol.Buffer, enc *Encoding) {
 // incremented by 1. So all encoded bytes are odd. A control-byte stores
 // the transformations for the next 7 bytes.
 type GameSettings struct {
-	GameSettingFlags GameSettingFlags
-	MapWidth         uint16
-	MapHeight        uint16
-	MapXoro          uint32
-	MapPath          string
-	HostName         string
-	MapSha1          [20]byte
+	GameSettingFlags GameSettingFlags `json:"GameSettingFlags"`
+	MapWidth         uint16           `json:"MapWidth"`
+	MapHeight        uint16           `json:"MapHeight"`
+	MapXoro          uint32           `json:"MapXoro"`
+	MapPath          string           `json:"MapPath"`
+	HostName         string           `json:"HostName"`
+	MapSha1          [20]byte         `json:"MapSha1"`
 }
 
 // Size of Serialize()
@@ -2345,13 +2345,25 @@ func (pkt *MapPartError) Deserialize(buf *protocol.Buffer, enc *Encoding) error
 // Format:
 //
 //	   (UINT8)    Sub type (0x03)
-//	                0x02   Unknown, found when leaving LAN game
+//	                0x02   Unit counts, found when leaving LAN game
 //	                0x03   Battle.net profile data
 //	                0x04   In-game skins
 //	                0x05   Unknown, found when joining LAN game
 //	  (UINT32)    Number of bytes following
 //	   (UINT8)[n] Protobuf encoded struct
 //
+//	For each player (sub type 0x02, encoded with protobuf):
+//	   (UINT8) Player ID
+//	   (UINT8) Race
+//	   For each unit type:
+//	  (UINT32) Unit ID
+//	  (UINT32) Unknown
+//	  (UINT32) Count
+//	   Unknown (field 5):
+//	  (UINT32) Unknown
+//	  (UINT32) Unknown
+//	  (UINT32) Unknown
+//
 //	For each battle.net profile (sub type 0x03, encoded with protobuf):
 //	   (UINT8) Player ID
 //	  (STRING) Battletag
@@ -2370,11 +2382,61 @@ func (pkt *MapPartError) Deserialize(buf *protocol.Buffer, enc *Encoding) error
 //	For sub type 0x05 (encoded with protobuf):
 //	   (UINT8) Player ID
 //	  (UINT32) Unknown
+//
+// Data of unknown sub types is stored in Raw as is.
 type PlayerExtra struct {
-	Type     PlayerExtraType
-	Profiles []PlayerDataProfile
-	Skins    []PlayerDataSkins
-	Unknown5 []PlayerData5
+	Type     PlayerExtraType     `json:"Type"`
+	Units    []PlayerDataUnits   `json:"Units"`
+	Profiles []PlayerDataProfile `json:"Profiles"`
+	Skins    []PlayerDataSkins   `json:"Skins"`
+	Unknown5 []PlayerData5       `json:"Unknown5"`
+	Raw      []byte              `json:"Raw"`
+}
+
+// PlayerDataUnits stores the unit counts for a single player.
+//
+// Format (protobuf):
+//
+//	 (UINT8) Player ID
+//	 (UINT8) Race (1=human, 2=orc, 3=undead, 4=nightelf)
+//
+//	For each unit type:
+//	   (UINT32) Unit ID
+//	   (UINT32) Unknown
+//	   (UINT32) Count
+//
+//	(field 5) Unknown
+type PlayerDataUnits struct {
+	PlayerID uint32            `json:"PlayerID"`
+	Race     uint32            `json:"Race"`
+	Units    []PlayerDataUnit  `json:"Units"`
+	Unknown1 PlayerDataUnknown `json:"Unknown1" protobuf:"5"`
+}
+
+// PlayerDataUnit stores the count for a single unit type.
+//
+// Format (protobuf):
+//
+//	(UINT32) Unit ID
+//	(UINT32) Unknown
+//	(UINT32) Count
+type PlayerDataUnit struct {
+	Unit     uint32 `json:"Unit"`
+	Unknown1 uint32 `json:"Unknown1"`
+	Count    uint32 `json:"Count"`
+}
+
+// PlayerDataUnknown stores an unknown struct found in PlayerDataUnits.
+//
+// Format (protobuf):
+//
+//	(UINT32) Unknown
+//	(UINT32) Unknown
+//	(UINT32) Unknown
+type PlayerDataUnknown struct {
+	Unknown1 uint32 `json:"Unknown1"`
+	Unknown2 uint32 `json:"Unknown2"`
+	Unknown3 uint32 `json:"Unknown3"`
 }
 
 // PlayerDataProfile stores the info for a single battle.net player profile.
@@ -2388,12 +2450,12 @@ type PlayerExtra struct {
 //	 (UINT8) Team
 //	(STRING) Unknown
 type PlayerDataProfile struct {
-	PlayerID  uint32
-	BattleTag string
-	Clan      string
-	Portrait  string
-	Realm     ProfileRealm
-	Unknown1  string
+	PlayerID  uint32       `json:"PlayerID"`
+	BattleTag string       `json:"BattleTag"`
+	Clan      string       `json:"Clan"`
+	Portrait  string       `json:"Portrait"`
+	Realm     ProfileRealm `json:"Realm"`
+	Unknown1  string       `json:"Unknown1"`
 }
 
 // PlayerDataSkins stores the in-game skin usage for a single player.
@@ -2407,8 +2469,8 @@ type PlayerDataProfile struct {
 //	   (UINT64) Skin ID
 //	   (STRING) Skin collection
 type PlayerDataSkins struct {
-	PlayerID uint32
-	Skins    []PlayerDataSkin
+	PlayerID uint32           `json:"PlayerID"`
+	Skins    []PlayerDataSkin `json:"Skins"`
 }
 
 // PlayerDataSkin stores in-game skin info.
@@ -2419,9 +2481,9 @@ type PlayerDataSkins struct {
 //	(UINT64) Skin ID
 //	(STRING) Skin collection
 type PlayerDataSkin struct {
-	Unit       uint64
-	Skin       uint64
-	Collection string
+	Unit       uint64 `json:"Unit"`
+	Skin       uint64 `json:"Skin"`
+	Collection string `json:"Collection"`
 }
 
 // PlayerData5 stores the info for a single battle.net player profile.
@@ -2431,8 +2493,8 @@ type PlayerDataSkin struct {
 //	 (UINT8) Player ID
 //	(UINT32) Unknown
 type PlayerData5 struct {
-	PlayerID uint32
-	Unknown1 uint32
+	PlayerID uint32 `json:"PlayerID"`
+	Unknown1 uint32 `json:"Unknown1"`
 }
 
 // Serialize encodes the struct into its binary form.
@@ -2476,18 +2538,11 @@ func (pkt *PlayerExtra) SerializeContent(buf *protocol.Buffer, enc *Encoding) er
 	var raw []byte
 	var err error
 	switch pkt.Type {
-	case PlayerExtra2:
-		// Unknown format:
-		//   0a60080610031a0a08efd09dab07100018011a0a08efc685ab07100018061a0808e7d2e
-		//   9ab0718011a0808e4de85ab0718011a0808ece0b9ab0718011a0808ecde9dab0718011a
-		//   0808f0cacdab0718011a0808edded1ab0718012a060800100018000a4a080710041a0a0
-		//   8f0e6ddab061000180a1a0808ecded1ab0618011a0808ecde9dab0618011a0808e5e885
-		//   ab0618011a0808edde85ab0618011a0808f7deb5ab0618012a06080010001800
-		//
-		//   0a4c080610031a0a08efd09dab07100018011a0a08efc685ab07100018031a0808e7d2e
-		//   9ab0718011a0808e4de85ab0718011a0808ece0b9ab0718011a0808ecde9dab0718012a
-		//   060800100018000a36080710041a0a08f0e6ddab06100018051a0808ecded1ab0618011
-		//   a0808ecde9dab0618011a0808e5e885ab0618012a06080010001800
+	case PlayerUnits:
+		var tmp = struct {
+			Units []PlayerDataUnits
+		}{pkt.Units}
+		raw, err = protobuf.Encode(&tmp)
 	case PlayerProfile:
 		if len(pkt.Profiles) == 1 {
 			raw, err = protobuf.Encode(&pkt.Profiles[0])
@@ -2515,6 +2570,8 @@ func (pkt *PlayerExtra) SerializeContent(buf *protocol.Buffer, enc *Encoding) er
 			}{pkt.Unknown5}
 			raw, err = protobuf.Encode(&tmp)
 		}
+	default:
+		raw = pkt.Raw
 	}
 
 	if err != nil {
@@ -2542,12 +2599,22 @@ func (pkt *PlayerExtra) DeserializeContent(buf *protocol.Buffer, enc *Encoding)
 		return io.ErrShortBuffer
 	}
 
+	pkt.Units = pkt.Units[:0]
 	pkt.Profiles = pkt.Profiles[:0]
 	pkt.Skins = pkt.Skins[:0]
 	pkt.Unknown5 = pkt.Unknown5[:0]
+	pkt.Raw = pkt.Raw[:0]
 
 	var raw = buf.ReadBlob(size)
 	switch pkt.Type {
+	case PlayerUnits:
+		var repeat struct {
+			Units []PlayerDataUnits
+		}
+		if err := protobuf.Decode(raw, &repeat); err != nil {
+			return err
+		}
+		pkt.Units = repeat.Units
 	case PlayerProfile:
 		var single PlayerDataProfile
 		var repeat struct {
@@ -2585,7 +2652,7 @@ func (pkt *PlayerExtra) DeserializeContent(buf *protocol.Buffer, enc *Encoding)
 			return err
 		}
 	default:
-		return ErrUnexpectedConst
+		pkt.Raw = append(pkt.Raw, raw...)
 	}
 
 	return nil
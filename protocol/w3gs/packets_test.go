@@ -5,6 +5,7 @@ package w3gs_test
 
 import (
 	"bytes"
+	"encoding/hex"
 	"net"
 	"reflect"
 	"testing"
@@ -320,6 +321,30 @@ func TestPackets(t *testing.T) {
 		},
 		&w3gs.MapPartError{},
 		&w3gs.PlayerExtra{},
+		&w3gs.PlayerExtra{
+			Type: w3gs.PlayerUnits,
+			Units: []w3gs.PlayerDataUnits{
+				w3gs.PlayerDataUnits{
+					PlayerID: 6,
+					Race:     3,
+					Units: []w3gs.PlayerDataUnit{
+						w3gs.PlayerDataUnit{Unit: 1969711215, Count: 1},
+						w3gs.PlayerDataUnit{Unit: 1969316719, Count: 3},
+					},
+				},
+				w3gs.PlayerDataUnits{
+					PlayerID: 7,
+					Race:     4,
+					Units: []w3gs.PlayerDataUnit{
+						w3gs.PlayerDataUnit{Unit: 1702327152, Count: 5},
+					},
+				},
+			},
+		},
+		&w3gs.PlayerExtra{
+			Type: 0x06,
+			Raw:  []byte{0x08, 0x01, 0x10, 0x02},
+		},
 		&w3gs.PlayerExtra{
 			Type: w3gs.PlayerProfile,
 			Profiles: []w3gs.PlayerDataProfile{
@@ -483,3 +508,25 @@ func BenchmarkCreateAndDeserialize(b *testing.B) {
 		res.Deserialize(&dec, &enc)
 	}
 }
+
+func TestPlayerUnits(t *testing.T) {
+	// Captured when leaving a LAN game
+	var raw, _ = hex.DecodeString("0a4c080610031a0a08efd09dab07100018011a0a08efc685ab07100018031a0808e7d2e9ab0718011a0808e4de85ab0718011a0808ece0b9ab0718011a0808ecde9dab0718012a060800100018000a36080710041a0a08f0e6ddab06100018051a0808ecded1ab0618011a0808ecde9dab0618011a0808e5e885ab0618012a06080010001800")
+
+	var buf = protocol.Buffer{}
+	buf.WriteUInt8(uint8(w3gs.PlayerUnits))
+	buf.WriteUInt32(uint32(len(raw)))
+	buf.WriteBlob(raw)
+
+	var pkt w3gs.PlayerExtra
+	if err := pkt.DeserializeContent(&buf, &w3gs.Encoding{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkt.Units) != 2 || pkt.Units[0].PlayerID != 6 || pkt.Units[1].PlayerID != 7 || pkt.Units[1].Race != 4 {
+		t.Fatalf("Player mismatch: %+v", pkt.Units)
+	}
+	if len(pkt.Units[0].Units) != 6 || pkt.Units[0].Units[1] != (w3gs.PlayerDataUnit{Unit: 0x7561636F, Count: 3}) {
+		t.Fatalf("Unit mismatch: %+v", pkt.Units[0].Units)
+	}
+}